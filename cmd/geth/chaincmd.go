@@ -465,7 +465,7 @@ func dump(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	config := &trie.Config{
+	config := &state.TrieConfig{
 		Preimages: true, // always enable preimage lookup
 	}
 	state, err := state.New(root, state.NewDatabaseWithConfig(db, config), nil)
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -71,12 +72,17 @@ Remove blockchain and state databases`,
 			dbCheckStateContentCmd,
 		},
 	}
+	dbInspectJSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the statistics as JSON instead of a table",
+	}
 	dbInspectCmd = &cli.Command{
 		Action:    inspect,
 		Name:      "inspect",
 		ArgsUsage: "<prefix> <start>",
 		Flags: flags.Merge([]cli.Flag{
 			utils.SyncModeFlag,
+			dbInspectJSONFlag,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Usage:       "Inspect the storage size for each type of data in the database",
 		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.`,
@@ -283,7 +289,20 @@ func inspect(ctx *cli.Context) error {
 	db := utils.MakeChainDatabase(ctx, stack, true)
 	defer db.Close()
 
-	return rawdb.InspectDatabase(db, prefix, start)
+	stats, err := rawdb.InspectDatabaseRange(db, prefix, start)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(dbInspectJSONFlag.Name) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	stats.Print(os.Stdout)
+	if stats.Unaccounted.Size > 0 {
+		log.Error("Database contains unaccounted data", "size", stats.Unaccounted.Size, "count", stats.Unaccounted.Count)
+	}
+	return nil
 }
 
 func checkStateContent(ctx *cli.Context) error {
@@ -268,3 +268,20 @@ func Exp(base, exponent *big.Int) *big.Int {
 	}
 	return result
 }
+
+// FakeExponential approximates factor * e ** (numerator / denominator) using
+// Taylor expansion, as specified by EIP-4844. The arguments are not modified.
+func FakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	var (
+		output = new(big.Int)
+		accum  = new(big.Int).Mul(factor, denominator)
+	)
+	for i := 1; accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+
+		accum.Mul(accum, numerator)
+		accum.Div(accum, denominator)
+		accum.Div(accum, big.NewInt(int64(i)))
+	}
+	return output.Div(output, denominator)
+}
@@ -25,9 +25,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -186,3 +188,105 @@ func BenchmarkDifficultyCalculator(b *testing.B) {
 		}
 	})
 }
+
+// testHeaderChain is a minimal header reader holding a single genesis header.
+type testHeaderChain struct {
+	config  *params.ChainConfig
+	genesis *types.Header
+}
+
+func (c *testHeaderChain) Config() *params.ChainConfig  { return c.config }
+func (c *testHeaderChain) CurrentHeader() *types.Header { return c.genesis }
+func (c *testHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.GetHeaderByHash(hash)
+}
+func (c *testHeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number == 0 {
+		return c.genesis
+	}
+	return nil
+}
+func (c *testHeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if hash == c.genesis.Hash() {
+		return c.genesis
+	}
+	return nil
+}
+func (c *testHeaderChain) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+// makeTestHeaders creates a chain of n valid headers on top of a fresh genesis.
+func makeTestHeaders(n int) (*testHeaderChain, []*types.Header) {
+	config := params.TestChainConfig
+	parent := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(131072),
+		GasLimit:   8_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Time:       1,
+	}
+	chain := &testHeaderChain{config: config, genesis: parent}
+
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			GasUsed:    parent.GasLimit / 2,
+			Time:       parent.Time + 13,
+			BaseFee:    misc.CalcBaseFee(config, parent),
+		}
+		header.Difficulty = CalcDifficulty(config, header.Time, parent)
+		headers[i], parent = header, header
+	}
+	return chain, headers
+}
+
+// Tests that the concurrent batch verification delivers the same results, in
+// the same order, as verifying the headers one by one.
+func TestVerifyHeadersOrdering(t *testing.T) {
+	chain, headers := makeTestHeaders(256)
+
+	// Invalidate a header, which also orphans its descendant
+	headers[100].Difficulty = big.NewInt(1)
+
+	engine := NewFaker()
+	unixNow := time.Now().Unix()
+
+	_, results := engine.VerifyHeaders(chain, headers)
+	for i := range headers {
+		want := engine.verifyHeaderWorker(chain, headers, i, unixNow)
+		if have := <-results; (have == nil) != (want == nil) {
+			t.Fatalf("header %d: result mismatch: have %v, want %v", i, have, want)
+		}
+		if i != 100 && i != 101 && want != nil {
+			t.Fatalf("header %d: unexpected failure: %v", i, want)
+		}
+	}
+}
+
+func BenchmarkVerifyHeaders(b *testing.B) {
+	chain, headers := makeTestHeaders(10000)
+	engine := NewFaker()
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			unixNow := time.Now().Unix()
+			for index := range headers {
+				if err := engine.verifyHeaderWorker(chain, headers, index, unixNow); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, results := engine.VerifyHeaders(chain, headers)
+			for range headers {
+				if err := <-results; err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
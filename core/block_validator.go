@@ -17,8 +17,10 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -90,6 +92,74 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	return nil
 }
 
+var (
+	// errTooManyBlobs is returned if a block references more blobs than allowed.
+	errTooManyBlobs = errors.New("too many blobs")
+
+	// errBlobCountMismatch is returned if the number of sidecars differs from the
+	// number of blobs referenced by the block.
+	errBlobCountMismatch = errors.New("blob sidecar count mismatch")
+
+	// errBlobSidecarMismatch is returned if a sidecar doesn't belong to the block
+	// or is out of order.
+	errBlobSidecarMismatch = errors.New("blob sidecar does not match block")
+
+	// errBlobHashMismatch is returned if a sidecar's commitment doesn't hash to
+	// the versioned hash in the corresponding transaction.
+	errBlobHashMismatch = errors.New("blob versioned hash mismatch")
+
+	// errBlobSize is returned if a sidecar's blob has the wrong length.
+	errBlobSize = errors.New("invalid blob size")
+)
+
+// blobValidationError is returned by validateBlobSidecarHashes, identifying the
+// blob which failed validation.
+type blobValidationError struct {
+	Index int // Index of the offending blob within the block, -1 for block level errors
+	Err   error
+}
+
+func (e *blobValidationError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("invalid blob sidecars: %v", e.Err)
+	}
+	return fmt.Sprintf("invalid blob sidecar %d: %v", e.Index, e.Err)
+}
+
+func (e *blobValidationError) Unwrap() error { return e.Err }
+
+// validateBlobSidecarHashes checks that the given sidecars carry exactly the
+// blobs referenced by the block's transactions, in order, and that each
+// commitment hashes to the versioned hash committed to in the transaction.
+//
+// This is only the structural part of sidecar validation: the KZG proofs are
+// not verified, as there is no EIP-4844 trusted setup to verify them against.
+// It stays unexported until proof verification completes it.
+func (v *BlockValidator) validateBlobSidecarHashes(block *types.Block, sidecars []*types.BlobSidecar) error {
+	var hashes []common.Hash
+	for _, tx := range block.Transactions() {
+		hashes = append(hashes, tx.BlobHashes()...)
+	}
+	if len(hashes) > params.MaxBlobsPerBlock {
+		return &blobValidationError{Index: -1, Err: fmt.Errorf("%w: have %d, max %d", errTooManyBlobs, len(hashes), params.MaxBlobsPerBlock)}
+	}
+	if len(sidecars) != len(hashes) {
+		return &blobValidationError{Index: -1, Err: fmt.Errorf("%w: have %d, want %d", errBlobCountMismatch, len(sidecars), len(hashes))}
+	}
+	for i, sc := range sidecars {
+		if sc.BlockHash != block.Hash() || sc.Index != uint64(i) {
+			return &blobValidationError{Index: i, Err: errBlobSidecarMismatch}
+		}
+		if len(sc.Blob) != params.BlobSize {
+			return &blobValidationError{Index: i, Err: fmt.Errorf("%w: have %d bytes, want %d", errBlobSize, len(sc.Blob), params.BlobSize)}
+		}
+		if hash := sc.VersionedHash(); hash != hashes[i] {
+			return &blobValidationError{Index: i, Err: fmt.Errorf("%w: have %x, want %x", errBlobHashMismatch, hash, hashes[i])}
+		}
+	}
+	return nil
+}
+
 // ValidateState validates the various changes that happen after a state transition,
 // such as amount of used gas, the receipt roots and the state root itself.
 func (v *BlockValidator) ValidateState(block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) error {
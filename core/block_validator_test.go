@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 	"runtime"
 	"testing"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
 )
 
 // Tests that simple header verification works, for both good and bad blocks.
@@ -378,3 +380,52 @@ func TestCalcGasLimit(t *testing.T) {
 		}
 	}
 }
+
+// Tests that blob sidecars are matched against the blobs referenced by a block.
+func TestValidateBlobSidecarHashes(t *testing.T) {
+	newSidecar := func(seed byte) *types.BlobSidecar {
+		sc := &types.BlobSidecar{Blob: make([]byte, params.BlobSize)}
+		sc.Commitment[0] = seed
+		return sc
+	}
+	var (
+		sc1, sc2 = newSidecar(1), newSidecar(2)
+		tx       = types.NewTx(&types.BlobTx{
+			ChainID:    uint256.NewInt(1),
+			BlobHashes: []common.Hash{sc1.VersionedHash(), sc2.VersionedHash()},
+		})
+		block     = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithBody([]*types.Transaction{tx}, nil)
+		validator = new(BlockValidator)
+	)
+	for i, sc := range []*types.BlobSidecar{sc1, sc2} {
+		sc.BlockHash, sc.Index = block.Hash(), uint64(i)
+	}
+	if err := validator.validateBlobSidecarHashes(block, []*types.BlobSidecar{sc1, sc2}); err != nil {
+		t.Fatalf("valid sidecars rejected: %v", err)
+	}
+	check := func(sidecars []*types.BlobSidecar, index int, want error) {
+		t.Helper()
+		err := validator.validateBlobSidecarHashes(block, sidecars)
+		var berr *blobValidationError
+		if !errors.As(err, &berr) || !errors.Is(err, want) {
+			t.Fatalf("error mismatch: have %v, want %v", err, want)
+		}
+		if berr.Index != index {
+			t.Fatalf("blob index mismatch: have %d, want %d", berr.Index, index)
+		}
+	}
+	check([]*types.BlobSidecar{sc1}, -1, errBlobCountMismatch)
+	check([]*types.BlobSidecar{sc2, sc1}, 0, errBlobSidecarMismatch)
+
+	bad := newSidecar(3)
+	bad.BlockHash, bad.Index = block.Hash(), 1
+	check([]*types.BlobSidecar{sc1, bad}, 1, errBlobHashMismatch)
+
+	bad.Blob = bad.Blob[:1]
+	check([]*types.BlobSidecar{sc1, bad}, 1, errBlobSize)
+
+	hashes := make([]common.Hash, params.MaxBlobsPerBlock+1)
+	tx = types.NewTx(&types.BlobTx{ChainID: uint256.NewInt(1), BlobHashes: hashes})
+	block = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithBody([]*types.Transaction{tx}, nil)
+	check(nil, -1, errTooManyBlobs)
+}
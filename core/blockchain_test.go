@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -176,7 +177,7 @@ func testBlockChainImport(chain types.Blocks, blockchain *BlockChain) error {
 		blockchain.chainmu.MustLock()
 		rawdb.WriteTd(blockchain.db, block.Hash(), block.NumberU64(), new(big.Int).Add(block.Difficulty(), blockchain.GetTd(block.ParentHash(), block.NumberU64()-1)))
 		rawdb.WriteBlock(blockchain.db, block)
-		statedb.Commit(false)
+		statedb.MustCommit(false)
 		blockchain.chainmu.Unlock()
 	}
 	return nil
@@ -1706,6 +1707,41 @@ func TestTrieForkGC(t *testing.T) {
 	}
 }
 
+// Tests that the number of recent states kept resolvable can be configured.
+func TestTrieRetention(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		genesis = &Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		config = &CacheConfig{
+			TrieCleanLimit: 256,
+			TrieDirtyLimit: 256,
+			TrieTimeLimit:  5 * time.Minute,
+			TrieRetention:  16,
+		}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(genesis, engine, 48, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), config, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// The states of the last 16 blocks should be retained, older ones not
+	for _, block := range blocks {
+		want := block.NumberU64() > 48-16
+		if have := chain.HasState(block.Root()); have != want {
+			t.Errorf("block %d: state availability mismatch: have %v, want %v", block.NumberU64(), have, want)
+		}
+	}
+}
+
 // Tests that doing large reorgs works even if the state associated with the
 // forking point is not available any more.
 func TestLargeReorgTrieGC(t *testing.T) {
@@ -3052,6 +3088,97 @@ func TestDeleteRecreateSlots(t *testing.T) {
 	}
 }
 
+// TestCreate2Collision tests that a CREATE2 into an address which already holds
+// a contract fails as per EIP-684, but succeeds again once the contract has
+// self-destructed in an earlier block, since its nonce and code are wiped.
+// Block 1: BB creates AA
+// Block 2: BB attempts to create AA again, which collides
+// Block 3: AA is selfdestructed
+// Block 4: BB creates AA again
+func TestCreate2Collision(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+
+		// A sender who makes transactions, has some funds
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000000000)
+		bb      = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		aaCode  = []byte{byte(vm.PC), byte(vm.SELFDESTRUCT)} // Code for AA (simple selfdestruct)
+	)
+	// The initcode just returns the aaCode
+	initCode := []byte{
+		byte(vm.PUSH2), byte(vm.PC), byte(vm.SELFDESTRUCT), // Push code on stack
+		byte(vm.PUSH1), 0x0, // memory start on stack
+		byte(vm.MSTORE),
+		// Code is now in memory.
+		byte(vm.PUSH1), 0x2, // size
+		byte(vm.PUSH1), byte(32 - 2), // offset
+		byte(vm.RETURN),
+	}
+	// The bb-code CREATE2s the aa contract and stores the resulting address
+	// (zero on failure) in the slot of the current block number
+	bbCode := []byte{
+		// Push initcode onto stack
+		byte(vm.PUSH1) + byte(len(initCode)-1)}
+	bbCode = append(bbCode, initCode...)
+	bbCode = append(bbCode, []byte{
+		byte(vm.PUSH1), 0x0, // memory start on stack
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x00, // salt
+		byte(vm.PUSH1), byte(len(initCode)), // size
+		byte(vm.PUSH1), byte(32 - len(initCode)), // offset
+		byte(vm.PUSH1), 0x00, // endowment
+		byte(vm.CREATE2),
+		byte(vm.NUMBER),
+		byte(vm.SSTORE), // Set slot[number] = created address
+	}...)
+
+	initHash := crypto.Keccak256Hash(initCode)
+	aa := crypto.CreateAddress2(bb, [32]byte{}, initHash[:])
+
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			address: {Balance: funds},
+			bb:      {Code: bbCode, Balance: big.NewInt(0)},
+		},
+	}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 4, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		to := bb
+		if i == 2 {
+			to = aa // kill AA in block 3
+		}
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), to,
+			big.NewInt(0), 100000, b.header.BaseFee, nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	statedb, _ := chain.State()
+
+	for number, exp := range map[int64]common.Hash{
+		1: common.BytesToHash(aa.Bytes()),
+		2: {}, // collision
+		4: common.BytesToHash(aa.Bytes()),
+	} {
+		if got := statedb.GetState(bb, common.BigToHash(big.NewInt(number))); got != exp {
+			t.Errorf("block %d: created address mismatch: got %x exp %x", number, got, exp)
+		}
+	}
+	if got := statedb.GetCode(aa); !bytes.Equal(got, aaCode) {
+		t.Errorf("recreated code mismatch: got %x exp %x", got, aaCode)
+	}
+}
+
 // TestDeleteRecreateAccount tests a state-transition that contains deletion of a
 // contract with storage, and a recreate of the same contract via a
 // regular value-transfer
@@ -4338,3 +4465,71 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that value transfers to accounts holding balances near 2^256 are
+// processed like any other transfer, as the opt-in value overflow check of
+// the EVM must not affect regular block processing.
+func TestValueTransferNearMaxBalance(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		bb      = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		cc      = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+		funds   = big.NewInt(1000000000000000)
+		limit   = new(big.Int).Lsh(big.NewInt(1), 256)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address: {Balance: funds},
+				bb:      {Balance: new(big.Int).Sub(limit, big.NewInt(1))},
+				// The address 0xCCCC sends 1 wei to 0xBBBB and stores the call status
+				cc: {
+					Code: []byte{
+						byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+						byte(vm.PUSH1), 1, byte(vm.PUSH2), 0xbb, 0xbb, byte(vm.GAS), byte(vm.CALL),
+						byte(vm.PUSH1), 0, byte(vm.SSTORE),
+					},
+					Balance: big.NewInt(1),
+				},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		fee := big.NewInt(1)
+		if b.header.BaseFee != nil {
+			fee = b.header.BaseFee
+		}
+		b.SetCoinbase(common.Address{1})
+		// Transfer 1 wei to 0xBBBB directly, then through 0xCCCC
+		for nonce, tx := range []*types.LegacyTx{{To: &bb, Value: big.NewInt(1)}, {To: &cc}} {
+			tx.Nonce, tx.GasPrice, tx.Gas = uint64(nonce), new(big.Int).Set(fee), 100000
+			b.AddTx(types.MustSignNewTx(key, signer, tx))
+		}
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert into chain: %v", err)
+	}
+	for i, receipt := range chain.GetReceiptsByHash(blocks[0].Hash()) {
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			t.Errorf("transaction %d failed", i)
+		}
+	}
+	state, err := chain.StateAt(chain.CurrentHeader().Root)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if status := state.GetState(cc, common.Hash{}); status != common.BigToHash(common.Big1) {
+		t.Errorf("inner call status mismatch: have %x, want 1", status)
+	}
+	if balance, want := state.GetBalance(bb), new(big.Int).Add(limit, big.NewInt(1)); balance.Cmp(want) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, want)
+	}
+}
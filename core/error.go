@@ -53,7 +53,7 @@ var (
 
 	// ErrNonceMax is returned if the nonce of a transaction sender account has
 	// maximum allowed value and would become invalid if incremented.
-	ErrNonceMax = errors.New("nonce has max value")
+	ErrNonceMax = types.ErrNonceMax
 
 	// ErrGasLimitReached is returned by the gas pool if the amount of gas required
 	// by a transaction is higher than what's left in the block.
@@ -84,15 +84,15 @@ var (
 
 	// ErrTipAboveFeeCap is a sanity error to ensure no one is able to specify a
 	// transaction with a tip higher than the total fee cap.
-	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
+	ErrTipAboveFeeCap = types.ErrTipAboveFeeCap
 
 	// ErrTipVeryHigh is a sanity error to avoid extremely big numbers specified
 	// in the tip field.
-	ErrTipVeryHigh = errors.New("max priority fee per gas higher than 2^256-1")
+	ErrTipVeryHigh = types.ErrTipVeryHigh
 
 	// ErrFeeCapVeryHigh is a sanity error to avoid extremely big numbers specified
 	// in the fee cap field.
-	ErrFeeCapVeryHigh = errors.New("max fee per gas higher than 2^256-1")
+	ErrFeeCapVeryHigh = types.ErrFeeCapVeryHigh
 
 	// ErrFeeCapTooLow is returned if the transaction fee cap is less than the
 	// base fee of the block.
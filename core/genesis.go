@@ -116,10 +116,10 @@ func (ga *GenesisAlloc) UnmarshalJSON(data []byte) error {
 }
 
 // deriveHash computes the state root according to the genesis specification.
-func (ga *GenesisAlloc) deriveHash() (common.Hash, error) {
+func (ga *GenesisAlloc) deriveHash(config *state.TrieConfig) (common.Hash, error) {
 	// Create an ephemeral in-memory database for computing hash,
 	// all the derived states will be discarded to not pollute disk.
-	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	db := state.NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), config)
 	statedb, err := state.New(common.Hash{}, db, nil)
 	if err != nil {
 		return common.Hash{}, err
@@ -431,7 +431,13 @@ func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 
 // ToBlock returns the genesis block according to genesis specification.
 func (g *Genesis) ToBlock() *types.Block {
-	root, err := g.Alloc.deriveHash()
+	return g.ToBlockWithConfig(nil)
+}
+
+// ToBlockWithConfig is like ToBlock, but derives the genesis state using a trie
+// database configured by config. A nil config uses the defaults.
+func (g *Genesis) ToBlockWithConfig(config *state.TrieConfig) *types.Block {
+	root, err := g.Alloc.deriveHash(config)
 	if err != nil {
 		panic(err)
 	}
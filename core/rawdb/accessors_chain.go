@@ -422,6 +422,52 @@ func deleteHeaderWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 	if err := db.Delete(headerKey(number, hash)); err != nil {
 		log.Crit("Failed to delete header", "err", err)
 	}
+	if err := db.Delete(blockMetadataKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block metadata", "err", err)
+	}
+}
+
+// BlockMetadata is the subset of the block header fields most often needed on
+// their own, stored apart from the header to spare decoding it in full.
+type BlockMetadata struct {
+	Number uint64
+	Time   uint64
+	TD     *big.Int `rlp:"-"` // Total difficulty, stored separately and nil if unknown
+}
+
+// WriteBlockMetadata stores the metadata of a block. The total difficulty is
+// not part of it, it is stored with WriteTd.
+//
+// WriteHeader does not store the metadata, as nothing reads it on a hot path
+// yet. Callers that do need it have to write it explicitly.
+func WriteBlockMetadata(db ethdb.KeyValueWriter, hash common.Hash, meta *BlockMetadata) {
+	data, err := rlp.EncodeToBytes(meta)
+	if err != nil {
+		log.Crit("Failed to RLP encode block metadata", "err", err)
+	}
+	if err := db.Put(blockMetadataKey(meta.Number, hash), data); err != nil {
+		log.Crit("Failed to store block metadata", "err", err)
+	}
+}
+
+// ReadBlockMetadata retrieves the number, timestamp and total difficulty of a
+// block. The metadata of headers moved to the ancient store, or written before
+// the metadata was tracked, is derived from the full header instead.
+func ReadBlockMetadata(db ethdb.Reader, hash common.Hash, number uint64) (*BlockMetadata, error) {
+	meta := new(BlockMetadata)
+	if data, _ := db.Get(blockMetadataKey(number, hash)); len(data) > 0 {
+		if err := rlp.DecodeBytes(data, meta); err != nil {
+			return nil, fmt.Errorf("invalid block metadata RLP: %w", err)
+		}
+	} else {
+		header := ReadHeader(db, hash, number)
+		if header == nil {
+			return nil, fmt.Errorf("block %d (%x) not found", number, hash)
+		}
+		meta.Number, meta.Time = number, header.Time
+	}
+	meta.TD = ReadTd(db, hash, number)
+	return meta, nil
 }
 
 // isCanon is an internal utility method, to check whether the given number/hash
@@ -504,6 +550,74 @@ func ReadBody(db ethdb.Reader, hash common.Hash, number uint64) *types.Body {
 	return body
 }
 
+// ReadAllBodies retrieves the bodies of many blocks at once, returning them in
+// the order of the requested hashes and numbers, with nil entries for missing
+// or invalid ones. Instead of a lookup per body, the bodies in the key-value
+// store are collected in a single ordered iteration, which is much cheaper for
+// runs of consecutive blocks as requested by the downloader.
+func ReadAllBodies(db ethdb.Database, hashes []common.Hash, numbers []uint64) ([]*types.Body, error) {
+	if len(hashes) != len(numbers) {
+		return nil, fmt.Errorf("hash and number count mismatch: %d != %d", len(hashes), len(numbers))
+	}
+	var (
+		blobs   = make([][]byte, len(hashes))
+		pending []int // Indices of the bodies not found in the ancient store
+	)
+	// Canonical bodies may have been moved to the ancient store already
+	db.ReadAncients(func(reader ethdb.AncientReaderOp) error {
+		for i := range hashes {
+			if isCanon(reader, numbers[i], hashes[i]) {
+				blobs[i], _ = reader.Ancient(ChainFreezerBodiesTable, numbers[i])
+			}
+			if len(blobs[i]) == 0 {
+				pending = append(pending, i)
+			}
+		}
+		return nil
+	})
+	// Look up the rest in key order, seeking the iterator only once
+	if len(pending) > 0 {
+		keys := make([][]byte, len(hashes))
+		for _, i := range pending {
+			keys[i] = blockBodyKey(numbers[i], hashes[i])
+		}
+		sort.Slice(pending, func(a, b int) bool {
+			return bytes.Compare(keys[pending[a]], keys[pending[b]]) < 0
+		})
+		it := db.NewIterator(blockBodyPrefix, keys[pending[0]][len(blockBodyPrefix):])
+		defer it.Release()
+
+		valid := it.Next()
+		for _, i := range pending {
+			for valid && bytes.Compare(it.Key(), keys[i]) < 0 {
+				valid = it.Next()
+			}
+			if !valid {
+				break
+			}
+			if bytes.Equal(it.Key(), keys[i]) {
+				blobs[i] = common.CopyBytes(it.Value())
+			}
+		}
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	}
+	bodies := make([]*types.Body, len(hashes))
+	for i, blob := range blobs {
+		if len(blob) == 0 {
+			continue
+		}
+		body := new(types.Body)
+		if err := rlp.DecodeBytes(blob, body); err != nil {
+			log.Error("Invalid block body RLP", "hash", hashes[i], "err", err)
+			continue
+		}
+		bodies[i] = body
+	}
+	return bodies, nil
+}
+
 // WriteBody stores a block body into the database.
 func WriteBody(db ethdb.KeyValueWriter, hash common.Hash, number uint64, body *types.Body) {
 	data, err := rlp.EncodeToBytes(body)
@@ -581,6 +695,10 @@ func HasReceipts(db ethdb.Reader, hash common.Hash, number uint64) bool {
 }
 
 // ReadReceiptsRLP retrieves all the transaction receipts belonging to a block in RLP encoding.
+//
+// Note, the receipts are returned in their storage encoding, which lacks the logs
+// bloom and differs from the consensus encoding used on the wire and for the
+// receipt root. It can not be relayed to peers as is.
 func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	var data []byte
 	db.ReadAncients(func(reader ethdb.AncientReaderOp) error {
@@ -674,6 +792,75 @@ func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadBlobSidecars retrieves the blob sidecars of a block, ordered by blob index.
+func ReadBlobSidecars(db ethdb.KeyValueReader, hash common.Hash, number uint64) []*types.BlobSidecar {
+	data, _ := db.Get(blobSidecarsKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var sidecars []*types.BlobSidecar
+	if err := rlp.DecodeBytes(data, &sidecars); err != nil {
+		log.Error("Invalid blob sidecars RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return sidecars
+}
+
+// WriteBlobSidecars stores the blob sidecars of a block. They are kept apart
+// from the block body, so they can be pruned independently.
+func WriteBlobSidecars(db ethdb.KeyValueWriter, hash common.Hash, number uint64, sidecars []*types.BlobSidecar) {
+	data, err := rlp.EncodeToBytes(sidecars)
+	if err != nil {
+		log.Crit("Failed to RLP encode blob sidecars", "err", err)
+	}
+	if err := db.Put(blobSidecarsKey(number, hash), data); err != nil {
+		log.Crit("Failed to store blob sidecars", "err", err)
+	}
+}
+
+// DeleteBlobSidecars removes the blob sidecars associated with a block hash.
+func DeleteBlobSidecars(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blobSidecarsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete blob sidecars", "err", err)
+	}
+}
+
+// PruneBlobSidecars removes the blob sidecars of all blocks below the given
+// number, both canonical and reorged forks included. It returns the number of
+// blocks whose sidecars were deleted.
+func PruneBlobSidecars(db ethdb.KeyValueStore, limit uint64) int {
+	var (
+		batch  = db.NewBatch()
+		pruned int
+	)
+	it := db.NewIterator(blobSidecarsPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(blobSidecarsPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(blobSidecarsPrefix):]) >= limit {
+			break // Keys are ordered by block number
+		}
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete blob sidecars", "err", err)
+		}
+		pruned++
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune blob sidecars", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune blob sidecars", "err", err)
+	}
+	return pruned
+}
+
 // storedReceiptRLP is the storage encoding of a receipt.
 // Re-definition in core/types/receipt.go.
 // TODO: Re-use the existing definition.
@@ -767,6 +954,13 @@ func WriteBlock(db ethdb.KeyValueWriter, block *types.Block) {
 	WriteHeader(db, block.Header())
 }
 
+// WriteBlockWithSidecars serializes a block into the database together with the
+// sidecars of the blobs it references.
+func WriteBlockWithSidecars(db ethdb.KeyValueWriter, block *types.Block, sidecars []*types.BlobSidecar) {
+	WriteBlock(db, block)
+	WriteBlobSidecars(db, block.Hash(), block.NumberU64(), sidecars)
+}
+
 // WriteAncientBlocks writes entire block data into ancient store and returns the total written size.
 func WriteAncientBlocks(db ethdb.AncientWriter, blocks []*types.Block, receipts []types.Receipts, td *big.Int) (int64, error) {
 	var (
@@ -815,6 +1009,7 @@ func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *type
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteBlobSidecars(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -824,6 +1019,7 @@ func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 // the hash to number mapping.
 func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteBlobSidecars(db, hash, number)
 	deleteHeaderWithoutNumber(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -67,6 +67,46 @@ func TestHeaderStorage(t *testing.T) {
 	}
 }
 
+// Tests block metadata storage and retrieval operations.
+func TestBlockMetadataStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	header := &types.Header{Number: big.NewInt(42), Time: 1234, Extra: []byte("test header")}
+	hash, number := header.Hash(), header.Number.Uint64()
+	if _, err := ReadBlockMetadata(db, hash, number); err == nil {
+		t.Fatal("Non existent block metadata returned")
+	}
+	// Writing the header alone doesn't store the metadata
+	WriteHeader(db, header)
+	if data, _ := db.Get(blockMetadataKey(number, hash)); len(data) != 0 {
+		t.Fatal("Block metadata stored with header")
+	}
+	// Stored metadata is returned, the total difficulty is added once stored
+	WriteBlockMetadata(db, hash, &BlockMetadata{Number: number, Time: header.Time})
+	meta, err := ReadBlockMetadata(db, hash, number)
+	if err != nil {
+		t.Fatalf("Stored block metadata not found: %v", err)
+	}
+	if meta.Number != number || meta.Time != header.Time || meta.TD != nil {
+		t.Fatalf("Retrieved block metadata mismatch: have %+v", meta)
+	}
+	WriteTd(db, hash, number, big.NewInt(100))
+	if meta, _ = ReadBlockMetadata(db, hash, number); meta.TD == nil || meta.TD.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("Retrieved total difficulty mismatch: have %v, want 100", meta.TD)
+	}
+	// Headers without separate metadata fall back to the full header
+	db.Delete(blockMetadataKey(number, hash))
+	if meta, err = ReadBlockMetadata(db, hash, number); err != nil || meta.Time != header.Time {
+		t.Fatalf("Derived block metadata mismatch: have %+v, %v", meta, err)
+	}
+	// Deleting the header deletes the metadata
+	WriteBlockMetadata(db, hash, &BlockMetadata{Number: number, Time: header.Time})
+	DeleteHeader(db, hash, number)
+	if data, _ := db.Get(blockMetadataKey(number, hash)); len(data) != 0 {
+		t.Fatal("Block metadata not deleted with header")
+	}
+}
+
 // Tests block body storage and retrieval operations.
 func TestBodyStorage(t *testing.T) {
 	db := NewMemoryDatabase()
@@ -155,6 +195,48 @@ func TestBlockStorage(t *testing.T) {
 	}
 }
 
+// Tests blob sidecar storage, retrieval and pruning operations.
+func TestBlobSidecarStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	// Store a couple of blocks with sidecars, one of which gets deleted
+	var blocks []*types.Block
+	for i := uint64(0); i < 4; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(i), Extra: []byte("test block")})
+		sidecars := []*types.BlobSidecar{
+			{BlockHash: block.Hash(), Index: 0, Blob: []byte{byte(i)}, Commitment: [48]byte{1}, Proof: [48]byte{2}},
+			{BlockHash: block.Hash(), Index: 1, Blob: []byte{byte(i), 1}, Commitment: [48]byte{3}, Proof: [48]byte{4}},
+		}
+		if entry := ReadBlobSidecars(db, block.Hash(), i); entry != nil {
+			t.Fatalf("Non existent sidecars returned: %v", entry)
+		}
+		WriteBlockWithSidecars(db, block, sidecars)
+		if entry := ReadBlock(db, block.Hash(), i); entry == nil {
+			t.Fatalf("Stored block not found")
+		}
+		if entry := ReadBlobSidecars(db, block.Hash(), i); !reflect.DeepEqual(entry, sidecars) {
+			t.Fatalf("Retrieved sidecars mismatch: have %v, want %v", entry, sidecars)
+		}
+		blocks = append(blocks, block)
+	}
+	DeleteBlock(db, blocks[3].Hash(), 3)
+	if entry := ReadBlobSidecars(db, blocks[3].Hash(), 3); entry != nil {
+		t.Fatalf("Deleted sidecars returned: %v", entry)
+	}
+	// Prune the sidecars of the first two blocks, the blocks themselves remain
+	if pruned := PruneBlobSidecars(db, 2); pruned != 2 {
+		t.Fatalf("Pruned sidecar count mismatch: have %d, want 2", pruned)
+	}
+	for i, block := range blocks[:3] {
+		if entry := ReadBlobSidecars(db, block.Hash(), uint64(i)); (entry == nil) != (i < 2) {
+			t.Errorf("Block %d: sidecar availability mismatch: have %v", i, entry != nil)
+		}
+		if entry := ReadBlock(db, block.Hash(), uint64(i)); entry == nil {
+			t.Errorf("Block %d: pruned along with its sidecars", i)
+		}
+	}
+}
+
 // Tests that partial block contents don't get reassembled into full blocks.
 func TestPartialBlockStorage(t *testing.T) {
 	db := NewMemoryDatabase()
@@ -528,6 +610,35 @@ func TestCanonicalHashIteration(t *testing.T) {
 	}
 }
 
+// Tests that all competing blocks at a height are enumerated, not just the
+// canonical one, and that neighbouring heights don't leak into the result.
+func TestReadAllHashesSiblings(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	want := make(map[common.Hash]bool)
+	for i := 0; i < 3; i++ {
+		header := &types.Header{Number: big.NewInt(100), Extra: []byte{byte(i)}}
+		WriteHeader(db, header)
+		if i == 0 {
+			WriteCanonicalHash(db, header.Hash(), 100)
+		}
+		want[header.Hash()] = true
+	}
+	WriteHeader(db, &types.Header{Number: big.NewInt(99)})
+	WriteHeader(db, &types.Header{Number: big.NewInt(101)})
+
+	hashes := ReadAllHashes(db, 100)
+	if len(hashes) != len(want) {
+		t.Fatalf("hash count mismatch: have %d, want %d", len(hashes), len(want))
+	}
+	for _, hash := range hashes {
+		if !want[hash] {
+			t.Fatalf("unexpected hash %x", hash)
+		}
+		delete(want, hash)
+	}
+}
+
 func TestHashesInRange(t *testing.T) {
 	mkHeader := func(number, seq int) *types.Header {
 		h := types.Header{
@@ -569,6 +680,156 @@ func TestHashesInRange(t *testing.T) {
 	}
 }
 
+func TestAncientRange(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	blocks := makeTestBlocks(100, 1)
+	if _, err := WriteAncientBlocks(db, blocks, makeTestReceipts(100, 1), big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	// Force the range to be gathered over multiple freezer reads
+	defer func(old uint64) { ancientRangeChunk = old }(ancientRangeChunk)
+	ancientRangeChunk = 1024
+
+	items, err := AncientRange(db, ChainFreezerHeaderTable, 10, 80)
+	if err != nil {
+		t.Fatalf("failed to read ancient range: %v", err)
+	}
+	if len(items) != 80 {
+		t.Fatalf("item count mismatch: have %d, want 80", len(items))
+	}
+	for i, item := range items {
+		want, _ := db.Ancient(ChainFreezerHeaderTable, uint64(10+i))
+		if !bytes.Equal(item, want) {
+			t.Fatalf("item %d mismatch: have %x, want %x", 10+i, item, want)
+		}
+	}
+	// Ranges reaching past the head must fail rather than come back short
+	if _, err := AncientRange(db, ChainFreezerHeaderTable, 90, 20); err == nil {
+		t.Fatalf("expected error for range beyond the freezer head")
+	}
+	if _, err := AncientRange(db, ChainFreezerHeaderTable, 100, 1); err == nil {
+		t.Fatalf("expected error for range starting at the freezer head")
+	}
+}
+
+func TestReadAllBodies(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	// Move the first blocks to the ancient store, keep the rest in the
+	// key-value store
+	blocks := makeTestBlocks(20, 1)
+	if _, err := WriteAncientBlocks(db, blocks[:10], makeTestReceipts(10, 1), big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	for _, block := range blocks[10:] {
+		WriteBody(db, block.Hash(), block.NumberU64(), block.Body())
+	}
+	// Request the bodies out of order, along with a missing one
+	var (
+		hashes  = []common.Hash{blocks[15].Hash(), blocks[3].Hash(), {0xde, 0xad}, blocks[19].Hash(), blocks[11].Hash()}
+		numbers = []uint64{15, 3, 12, 19, 11}
+	)
+	bodies, err := ReadAllBodies(db, hashes, numbers)
+	if err != nil {
+		t.Fatalf("failed to read bodies: %v", err)
+	}
+	if len(bodies) != len(hashes) {
+		t.Fatalf("body count mismatch: have %d, want %d", len(bodies), len(hashes))
+	}
+	for i, body := range bodies {
+		want := ReadBody(db, hashes[i], numbers[i])
+		if (body == nil) != (want == nil) {
+			t.Fatalf("body %d: presence mismatch: have %v, want %v", i, body != nil, want != nil)
+		}
+		if body != nil && types.DeriveSha(types.Transactions(body.Transactions), newHasher()) != types.DeriveSha(types.Transactions(want.Transactions), newHasher()) {
+			t.Errorf("body %d: transactions mismatch", i)
+		}
+	}
+	if bodies[2] != nil {
+		t.Errorf("unexpected body for missing block")
+	}
+	if _, err := ReadAllBodies(db, hashes, numbers[1:]); err == nil {
+		t.Error("expected error for mismatching request lengths")
+	}
+}
+
+// This compares reading many block bodies in one pass against reading them
+// one by one.
+func BenchmarkReadAllBodies(b *testing.B) {
+	for _, count := range []int{100, 1000, 10000} {
+		var (
+			db      = NewMemoryDatabase()
+			blocks  = makeTestBlocks(count, 1)
+			hashes  = make([]common.Hash, count)
+			numbers = make([]uint64, count)
+		)
+		for i, block := range blocks {
+			WriteBody(db, block.Hash(), block.NumberU64(), block.Body())
+			hashes[i], numbers[i] = block.Hash(), block.NumberU64()
+		}
+		b.Run(fmt.Sprintf("%d/single", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for n := range hashes {
+					if ReadBody(db, hashes[n], numbers[n]) == nil {
+						b.Fatal("body not found")
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("%d/batch", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ReadAllBodies(db, hashes, numbers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// This compares reading a range of ancient headers in one go against reading
+// them one by one.
+func BenchmarkAncientRange(b *testing.B) {
+	const count = 1000
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), b.TempDir(), "", false)
+	if err != nil {
+		b.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	if _, err := WriteAncientBlocks(db, makeTestBlocks(count, 1), makeTestReceipts(count, 1), big.NewInt(100)); err != nil {
+		b.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	// Hashes are stored raw, headers are snappy compressed per item
+	for _, kind := range []string{ChainFreezerHashTable, ChainFreezerHeaderTable} {
+		b.Run(kind+"/single", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for n := uint64(0); n < count; n++ {
+					if _, err := db.Ancient(kind, n); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run(kind+"/range", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := AncientRange(db, kind, 0, count); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 // This measures the write speed of the WriteAncientBlocks operation.
 func BenchmarkWriteAncientBlocks(b *testing.B) {
 	// Open freezer database.
@@ -179,3 +179,44 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// BloomRangeSize is the number of consecutive blocks aggregated into a single
+// range bloom.
+const BloomRangeSize = 1000
+
+// ReadBloomRange retrieves the aggregated bloom filter of the given block range.
+// The second return value is false if the range has not been indexed.
+func ReadBloomRange(db ethdb.KeyValueReader, section uint64) (types.Bloom, bool) {
+	data, _ := db.Get(bloomRangeKey(section))
+	if len(data) != types.BloomByteLength {
+		return types.Bloom{}, false
+	}
+	return types.BytesToBloom(data), true
+}
+
+// WriteBloomRange stores the aggregated bloom filter of the given block range.
+func WriteBloomRange(db ethdb.KeyValueWriter, section uint64, bloom types.Bloom) {
+	if err := db.Put(bloomRangeKey(section), bloom.Bytes()); err != nil {
+		log.Crit("Failed to store range bloom", "err", err)
+	}
+}
+
+// UpdateBloomRange merges the bloom of the given block into the aggregate of
+// its range. A range is only started from its first block, so an existing entry
+// always covers every block of the range written since; blocks of a range that
+// was never started are ignored. Side chain blocks are merged too, which only
+// makes the aggregate more permissive.
+//
+// The current aggregate is read from db and the updated one is written into w,
+// allowing it to be committed atomically along with the block.
+func UpdateBloomRange(db ethdb.KeyValueReader, w ethdb.KeyValueWriter, number uint64, bloom types.Bloom) {
+	section := number / BloomRangeSize
+	agg, ok := ReadBloomRange(db, section)
+	if !ok && number%BloomRangeSize != 0 {
+		return
+	}
+	for i := range agg {
+		agg[i] |= bloom[i]
+	}
+	WriteBloomRange(w, section, agg)
+}
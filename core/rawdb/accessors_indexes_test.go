@@ -178,3 +178,33 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+// Tests that range blooms are only started at range boundaries and aggregate
+// every block merged afterwards.
+func TestUpdateBloomRange(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var b1, b2 types.Bloom
+	b1.Add([]byte("first"))
+	b2.Add([]byte("second"))
+
+	// Blocks in the middle of an unstarted range must not create an entry
+	UpdateBloomRange(db, db, BloomRangeSize+1, b1)
+	if _, ok := ReadBloomRange(db, 1); ok {
+		t.Fatalf("range started from a non-boundary block")
+	}
+	// Starting the range at its boundary enables aggregation
+	UpdateBloomRange(db, db, BloomRangeSize, b1)
+	UpdateBloomRange(db, db, BloomRangeSize+1, b2)
+
+	agg, ok := ReadBloomRange(db, 1)
+	if !ok {
+		t.Fatalf("range bloom missing")
+	}
+	if !agg.Test([]byte("first")) || !agg.Test([]byte("second")) {
+		t.Fatalf("range bloom does not contain merged blocks")
+	}
+	if _, ok := ReadBloomRange(db, 0); ok {
+		t.Fatalf("unexpected bloom for untouched range")
+	}
+}
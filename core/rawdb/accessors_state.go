@@ -17,11 +17,19 @@
 package rawdb
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 )
 
+// ErrCodeTooLarge is returned by WriteCodeWithSize if the contract code exceeds
+// the maximum code size.
+var ErrCodeTooLarge = errors.New("contract code too large")
+
 // ReadPreimage retrieves a single preimage of the provided hash.
 func ReadPreimage(db ethdb.KeyValueReader, hash common.Hash) []byte {
 	data, _ := db.Get(preimageKey(hash))
@@ -86,6 +94,19 @@ func WriteCode(db ethdb.KeyValueWriter, hash common.Hash, code []byte) {
 	}
 }
 
+// WriteCodeWithSize writes the provided contract code into the database, like
+// WriteCode, but refuses code larger than params.MaxCodeSize and reports write
+// failures instead of crashing.
+//
+// Contracts deployed before EIP-170 are not subject to the size limit, so code
+// synced or replayed from the chain must keep going through WriteCode.
+func WriteCodeWithSize(db ethdb.KeyValueWriter, hash common.Hash, code []byte) error {
+	if len(code) > params.MaxCodeSize {
+		return fmt.Errorf("%w: %x has %d bytes, limit %d", ErrCodeTooLarge, hash, len(code), params.MaxCodeSize)
+	}
+	return db.Put(codeKey(hash), code)
+}
+
 // DeleteCode deletes the specified contract code from the database.
 func DeleteCode(db ethdb.KeyValueWriter, hash common.Hash) {
 	if err := db.Delete(codeKey(hash)); err != nil {
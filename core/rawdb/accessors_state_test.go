@@ -0,0 +1,49 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestWriteCodeWithSize(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	// Code up to the limit is stored
+	code := bytes.Repeat([]byte{0x5b}, params.MaxCodeSize)
+	hash := crypto.Keccak256Hash(code)
+	if err := WriteCodeWithSize(db, hash, code); err != nil {
+		t.Fatalf("failed to write code at the size limit: %v", err)
+	}
+	if have := ReadCode(db, hash); !bytes.Equal(have, code) {
+		t.Fatalf("stored code mismatch: have %d bytes, want %d", len(have), len(code))
+	}
+	// Larger code is refused and not stored
+	code = bytes.Repeat([]byte{0x5b}, 25*1024)
+	hash = crypto.Keccak256Hash(code)
+	if err := WriteCodeWithSize(db, hash, code); !errors.Is(err, ErrCodeTooLarge) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCodeTooLarge)
+	}
+	if HasCode(db, hash) {
+		t.Fatal("oversized code stored")
+	}
+}
@@ -119,3 +119,28 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 	table.dumpIndexStdout(start, end)
 	return nil
 }
+
+// ancientRangeChunk is the byte budget of a single freezer read issued by
+// AncientRange. The freezer preallocates a buffer of this size per request.
+// It's a variable so tests can exercise reads spanning several chunks.
+var ancientRangeChunk uint64 = 4 * 1024 * 1024
+
+// AncientRange retrieves 'count' consecutive items of the given ancient table,
+// starting at 'from'. Unlike the AncientRange method of the ancient store, it is
+// not bounded by size: the items are read sequentially in chunks until all of
+// them are gathered. An error is returned if any item of the range is missing.
+func AncientRange(db ethdb.Database, kind string, from, count uint64) ([][]byte, error) {
+	items := make([][]byte, 0, count)
+	for uint64(len(items)) < count {
+		next := from + uint64(len(items))
+		batch, err := db.AncientRange(kind, next, count-uint64(len(items)), ancientRangeChunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ancient %s #%d: %w", kind, next, err)
+		}
+		if len(batch) == 0 {
+			return nil, fmt.Errorf("missing ancient %s #%d", kind, next)
+		}
+		items = append(items, batch...)
+	}
+	return items, nil
+}
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"fmt"
 	"runtime"
 	"sync/atomic"
 	"time"
@@ -170,6 +171,56 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 	return hashesCh
 }
 
+// headerPrefetchLimit is the maximum number of headers read ahead of the
+// callback when iterating over the canonical chain.
+const headerPrefetchLimit = 256
+
+// IterateCanonicalHeaders calls fn with each canonical header in the range
+// [from, to) in ascending order, stopping early if fn returns false. Headers are
+// read ahead on a separate goroutine to hide the database latency. An error is
+// returned if a header in the range is missing.
+func IterateCanonicalHeaders(db ethdb.Database, from, to uint64, fn func(*types.Header) bool) error {
+	if from >= to {
+		return nil
+	}
+	var (
+		headers   = make(chan *types.Header, headerPrefetchLimit)
+		interrupt = make(chan struct{})
+	)
+	defer close(interrupt)
+
+	go func() {
+		defer close(headers)
+		for n := from; n < to; n++ {
+			var header *types.Header
+			if hash := ReadCanonicalHash(db, n); hash != (common.Hash{}) {
+				header = ReadHeader(db, hash, n)
+			}
+			// Feed the header to the iterator, or abort on interrupt. A
+			// missing header is forwarded too, terminating the iteration.
+			select {
+			case headers <- header:
+			case <-interrupt:
+				return
+			}
+			if header == nil {
+				return
+			}
+		}
+	}()
+	number := from
+	for header := range headers {
+		if header == nil {
+			return fmt.Errorf("canonical header #%d missing", number)
+		}
+		if !fn(header) {
+			return nil
+		}
+		number++
+	}
+	return nil
+}
+
 // indexTransactions creates txlookup indices of the specified block range.
 //
 // This function iterates canonical chain in reverse order, it has one main advantage:
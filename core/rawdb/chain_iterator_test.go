@@ -206,3 +206,73 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestIterateCanonicalHeaders(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	// Construct a chain of headers with varying gas usage
+	var parent common.Hash
+	for i := uint64(0); i < 10000; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(i),
+			GasLimit:   30_000_000,
+			GasUsed:    (i * 7919) % 30_000_000,
+		}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), i)
+		parent = header.Hash()
+	}
+	// Sum up the gas used by reading the headers one by one
+	var want uint64
+	for i := uint64(0); i < 10000; i++ {
+		want += ReadHeader(db, ReadCanonicalHash(db, i), i).GasUsed
+	}
+	// Iterate over the entire chain and ensure the same result is produced
+	var (
+		have   uint64
+		number uint64
+	)
+	err := IterateCanonicalHeaders(db, 0, 10000, func(header *types.Header) bool {
+		if header.Number.Uint64() != number {
+			t.Fatalf("header number mismatch: have %d, want %d", header.Number, number)
+		}
+		have += header.GasUsed
+		number++
+		return true
+	})
+	if err != nil {
+		t.Fatalf("failed to iterate headers: %v", err)
+	}
+	if number != 10000 {
+		t.Fatalf("iterated header count mismatch: have %d, want %d", number, 10000)
+	}
+	if have != want {
+		t.Fatalf("gas used mismatch: have %d, want %d", have, want)
+	}
+	// Ensure the iteration stops when requested
+	var visited int
+	err = IterateCanonicalHeaders(db, 100, 10000, func(header *types.Header) bool {
+		visited++
+		return header.Number.Uint64() < 109
+	})
+	if err != nil {
+		t.Fatalf("failed to iterate headers: %v", err)
+	}
+	if visited != 10 {
+		t.Fatalf("visited header count mismatch: have %d, want %d", visited, 10)
+	}
+	// Ensure a gap in the canonical chain is reported
+	DeleteCanonicalHash(db, 5000)
+	visited = 0
+	err = IterateCanonicalHeaders(db, 4990, 10000, func(header *types.Header) bool {
+		visited++
+		return true
+	})
+	if err == nil {
+		t.Fatal("expected missing header to be reported")
+	}
+	if visited != 10 {
+		t.Fatalf("visited header count mismatch: have %d, want %d", visited, 10)
+	}
+}
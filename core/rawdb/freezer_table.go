@@ -753,8 +753,8 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		count = items - start
 	}
 	var (
-		output     = make([]byte, maxBytes) // Buffer to read data into
-		outputSize int                      // Used size of that buffer
+		output     []byte // Buffer to read data into
+		outputSize int    // Used size of that buffer
 	)
 	// readData is a helper method to read a single data item from disk.
 	readData := func(fileId, start uint32, length int) error {
@@ -778,6 +778,17 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	if err != nil {
 		return nil, nil, err
 	}
+	// Size the read buffer by the data actually requested, so large byte limits
+	// don't cost large allocations for small items.
+	var requested uint64
+	for i := 0; i < len(indices)-1 && requested < maxBytes; i++ {
+		offset1, offset2, _ := indices[i].bounds(indices[i+1])
+		requested += uint64(offset2 - offset1)
+	}
+	if requested > maxBytes {
+		requested = maxBytes
+	}
+	output = make([]byte, requested)
 	var (
 		sizes      []int               // The sizes for each element
 		totalSize  = 0                 // The total size of all data read so far
@@ -0,0 +1,256 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/olekukonko/tablewriter"
+)
+
+// CategoryStats holds the number of database entries belonging to a category
+// and their total size, keys included.
+type CategoryStats struct {
+	Size  common.StorageSize `json:"size"`
+	Count uint64             `json:"count"`
+}
+
+// add accounts a single entry of the given size to the category.
+func (s *CategoryStats) add(size common.StorageSize) {
+	s.Size += size
+	s.Count++
+}
+
+// AncientStats holds the number of items stored in a freezer and the storage
+// size of each of its tables.
+type AncientStats struct {
+	Name   string                        `json:"name"`
+	Items  uint64                        `json:"items"`
+	Tables map[string]common.StorageSize `json:"tables"`
+}
+
+// DatabaseStats is the storage breakdown of a database by data category.
+type DatabaseStats struct {
+	// Key-value store statistics
+	Headers         CategoryStats `json:"headers"`
+	Bodies          CategoryStats `json:"bodies"`
+	Receipts        CategoryStats `json:"receipts"`
+	BlobSidecars    CategoryStats `json:"blobSidecars"`
+	BlockMetadata   CategoryStats `json:"blockMetadata"`
+	Difficulties    CategoryStats `json:"difficulties"`
+	NumberToHash    CategoryStats `json:"numberToHash"`
+	HashToNumber    CategoryStats `json:"hashToNumber"`
+	TxLookups       CategoryStats `json:"txLookups"`
+	BloomBits       CategoryStats `json:"bloomBits"`
+	Codes           CategoryStats `json:"codes"`
+	TrieNodes       CategoryStats `json:"trieNodes"`
+	Preimages       CategoryStats `json:"preimages"`
+	AccountSnapshot CategoryStats `json:"accountSnapshot"`
+	StorageSnapshot CategoryStats `json:"storageSnapshot"`
+	BeaconHeaders   CategoryStats `json:"beaconHeaders"`
+	CliqueSnapshots CategoryStats `json:"cliqueSnapshots"`
+	Metadata        CategoryStats `json:"metadata"`
+	ChtTrieNodes    CategoryStats `json:"chtTrieNodes"`
+	BloomTrieNodes  CategoryStats `json:"bloomTrieNodes"`
+	Unaccounted     CategoryStats `json:"unaccounted"`
+
+	// Append-only file store statistics
+	Ancients []AncientStats `json:"ancients"`
+
+	Total common.StorageSize `json:"total"`
+}
+
+// InspectDatabase traverses the entire database and returns the size of all
+// different categories of data.
+func InspectDatabase(db ethdb.Database) (*DatabaseStats, error) {
+	return InspectDatabaseRange(db, nil, nil)
+}
+
+// InspectDatabaseRange is like InspectDatabase, but only the key-value entries
+// with the given prefix, starting at keyStart, are traversed. The ancient stores
+// are always inspected in full.
+func InspectDatabaseRange(db ethdb.Database, keyPrefix, keyStart []byte) (*DatabaseStats, error) {
+	it := db.NewIterator(keyPrefix, keyStart)
+	defer it.Release()
+
+	var (
+		stats  = new(DatabaseStats)
+		count  int64
+		start  = time.Now()
+		logged = time.Now()
+	)
+	// Inspect key-value database first.
+	for it.Next() {
+		var (
+			key  = it.Key()
+			size = common.StorageSize(len(key) + len(it.Value()))
+		)
+		stats.Total += size
+		switch {
+		case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength):
+			stats.Headers.add(size)
+		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
+			stats.Bodies.add(size)
+		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
+			stats.Receipts.add(size)
+		case bytes.HasPrefix(key, blobSidecarsPrefix) && len(key) == (len(blobSidecarsPrefix)+8+common.HashLength):
+			stats.BlobSidecars.add(size)
+		case bytes.HasPrefix(key, blockMetadataPrefix) && len(key) == (len(blockMetadataPrefix)+8+common.HashLength):
+			stats.BlockMetadata.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
+			stats.Difficulties.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
+			stats.NumberToHash.add(size)
+		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
+			stats.HashToNumber.add(size)
+		case len(key) == common.HashLength:
+			stats.TrieNodes.add(size)
+		case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
+			stats.Codes.add(size)
+		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
+			stats.TxLookups.add(size)
+		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
+			stats.AccountSnapshot.add(size)
+		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
+			stats.StorageSnapshot.add(size)
+		case bytes.HasPrefix(key, PreimagePrefix) && len(key) == (len(PreimagePrefix)+common.HashLength):
+			stats.Preimages.add(size)
+		case bytes.HasPrefix(key, configPrefix) && len(key) == (len(configPrefix)+common.HashLength):
+			stats.Metadata.add(size)
+		case bytes.HasPrefix(key, genesisPrefix) && len(key) == (len(genesisPrefix)+common.HashLength):
+			stats.Metadata.add(size)
+		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
+			stats.BloomBits.add(size)
+		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
+			stats.BloomBits.add(size)
+		case bytes.HasPrefix(key, bloomRangePrefix) && len(key) == (len(bloomRangePrefix)+8):
+			stats.BloomBits.add(size)
+		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
+			stats.BeaconHeaders.add(size)
+		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
+			stats.CliqueSnapshots.add(size)
+		case bytes.HasPrefix(key, ChtTablePrefix) ||
+			bytes.HasPrefix(key, ChtIndexTablePrefix) ||
+			bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
+			stats.ChtTrieNodes.add(size)
+		case bytes.HasPrefix(key, BloomTrieTablePrefix) ||
+			bytes.HasPrefix(key, BloomTrieIndexPrefix) ||
+			bytes.HasPrefix(key, BloomTriePrefix): // Bloomtrie sub
+			stats.BloomTrieNodes.add(size)
+		default:
+			var accounted bool
+			for _, meta := range [][]byte{
+				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
+				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
+				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
+				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+			} {
+				if bytes.Equal(key, meta) {
+					stats.Metadata.add(size)
+					accounted = true
+					break
+				}
+			}
+			if !accounted {
+				stats.Unaccounted.add(size)
+			}
+		}
+		count++
+		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	// Inspect all registered append-only file store then.
+	ancients, err := inspectFreezers(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, ancient := range ancients {
+		tables := make(map[string]common.StorageSize, len(ancient.sizes))
+		for _, table := range ancient.sizes {
+			tables[table.name] = table.size
+		}
+		stats.Ancients = append(stats.Ancients, AncientStats{
+			Name:   ancient.name,
+			Items:  ancient.count(),
+			Tables: tables,
+		})
+		stats.Total += ancient.size()
+	}
+	return stats, nil
+}
+
+// Print writes the statistics as a table to w.
+func (s *DatabaseStats) Print(w io.Writer) {
+	row := func(db, category string, stat CategoryStats) []string {
+		return []string{db, category, stat.Size.String(), fmt.Sprintf("%d", stat.Count)}
+	}
+	stats := [][]string{
+		row("Key-Value store", "Headers", s.Headers),
+		row("Key-Value store", "Bodies", s.Bodies),
+		row("Key-Value store", "Receipt lists", s.Receipts),
+		row("Key-Value store", "Blob sidecars", s.BlobSidecars),
+		row("Key-Value store", "Block metadata", s.BlockMetadata),
+		row("Key-Value store", "Difficulties", s.Difficulties),
+		row("Key-Value store", "Block number->hash", s.NumberToHash),
+		row("Key-Value store", "Block hash->number", s.HashToNumber),
+		row("Key-Value store", "Transaction index", s.TxLookups),
+		row("Key-Value store", "Bloombit index", s.BloomBits),
+		row("Key-Value store", "Contract codes", s.Codes),
+		row("Key-Value store", "Trie nodes", s.TrieNodes),
+		row("Key-Value store", "Trie preimages", s.Preimages),
+		row("Key-Value store", "Account snapshot", s.AccountSnapshot),
+		row("Key-Value store", "Storage snapshot", s.StorageSnapshot),
+		row("Key-Value store", "Beacon sync headers", s.BeaconHeaders),
+		row("Key-Value store", "Clique snapshots", s.CliqueSnapshots),
+		row("Key-Value store", "Singleton metadata", s.Metadata),
+		row("Light client", "CHT trie nodes", s.ChtTrieNodes),
+		row("Light client", "Bloom trie nodes", s.BloomTrieNodes),
+	}
+	for _, ancient := range s.Ancients {
+		names := make([]string, 0, len(ancient.Tables))
+		for name := range ancient.Tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			stats = append(stats, []string{
+				fmt.Sprintf("Ancient store (%s)", strings.Title(ancient.Name)),
+				strings.Title(name),
+				ancient.Tables[name].String(),
+				fmt.Sprintf("%d", ancient.Items),
+			})
+		}
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Database", "Category", "Size", "Items"})
+	table.SetFooter([]string{"", "Total", s.Total.String(), " "})
+	table.AppendBulk(stats)
+	table.Render()
+}
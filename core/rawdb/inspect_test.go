@@ -0,0 +1,66 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the database inspection classifies entries by their key schema.
+func TestInspectDatabase(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		hash = common.Hash{0x01}
+		code = []byte{0x60, 0x00}
+		node = []byte{0xc0}
+	)
+	WriteCode(db, hash, code)
+	db.Put(hash[:], node)
+	db.Put([]byte("unknown"), []byte{0x01})
+
+	stats, err := InspectDatabase(db)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	if want := common.StorageSize(len(CodePrefix) + common.HashLength + len(code)); stats.Codes.Size != want || stats.Codes.Count != 1 {
+		t.Errorf("code stats mismatch: have %v, want %v in 1 entry", stats.Codes, want)
+	}
+	if want := common.StorageSize(common.HashLength + len(node)); stats.TrieNodes.Size != want || stats.TrieNodes.Count != 1 {
+		t.Errorf("trie node stats mismatch: have %v, want %v in 1 entry", stats.TrieNodes, want)
+	}
+	if stats.Unaccounted.Count != 1 {
+		t.Errorf("unaccounted entry count mismatch: have %d, want 1", stats.Unaccounted.Count)
+	}
+	if len(stats.Ancients) != 1 || stats.Ancients[0].Name != chainFreezerName {
+		t.Errorf("unexpected ancient stores: %v", stats.Ancients)
+	}
+	var total common.StorageSize
+	for _, size := range stats.Ancients[0].Tables {
+		total += size
+	}
+	total += stats.Codes.Size + stats.TrieNodes.Size + stats.Metadata.Size + stats.Unaccounted.Size
+	if stats.Total != total {
+		t.Errorf("total size mismatch: have %v, want %v", stats.Total, total)
+	}
+}
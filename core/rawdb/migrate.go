@@ -0,0 +1,128 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// migrateBatchItems is the number of entries written to the destination
+	// database in one batch during a migration.
+	migrateBatchItems = 10000
+
+	// migrateVerifyInterval is the stride at which migrated entries are read
+	// back for verification, i.e. one in every 100 entries (1%) is checked.
+	migrateVerifyInterval = 100
+)
+
+// MigrateFromLevelDB copies all key-value entries of src into dst, e.g. to move
+// a node from LevelDB to Pebble without resyncing. Nothing in it is specific to
+// LevelDB though, any two key-value stores work. The entries are written in
+// batches, and after each one the optional progress callback is invoked with
+// the number of entries copied so far. Once done, a sample of the entries is
+// read back from dst and compared against src.
+//
+// The ancient store is not touched, its flat files are migrated separately
+// with MigrateAncients.
+func MigrateFromLevelDB(src, dst ethdb.KeyValueStore, progress func(copied uint64)) error {
+	it := src.NewIterator(nil, nil)
+	defer it.Release()
+
+	var (
+		batch  = dst.NewBatch()
+		copied uint64
+	)
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		copied++
+		if copied%migrateBatchItems == 0 {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+			if progress != nil {
+				progress(copied)
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if copied%migrateBatchItems != 0 {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(copied)
+		}
+	}
+	return verifyMigration(src, dst)
+}
+
+// verifyMigration reads back every migrateVerifyInterval-th entry of src from
+// dst, checking that it was copied unmodified.
+func verifyMigration(src, dst ethdb.KeyValueStore) error {
+	it := src.NewIterator(nil, nil)
+	defer it.Release()
+
+	for i := 0; it.Next(); i++ {
+		if i%migrateVerifyInterval != 0 {
+			continue
+		}
+		value, err := dst.Get(it.Key())
+		if err != nil {
+			return fmt.Errorf("migrated entry %x missing: %w", it.Key(), err)
+		}
+		if !bytes.Equal(value, it.Value()) {
+			return fmt.Errorf("migrated entry %x mismatch: have %x, want %x", it.Key(), value, it.Value())
+		}
+	}
+	return it.Error()
+}
+
+// MigrateAncients copies the flat files of the ancient store in srcDir over to
+// dstDir, preserving the directory layout. Both ancient stores must be closed
+// while copying.
+func MigrateAncients(srcDir, dstDir string) error {
+	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dstDir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		// The lock file belongs to the instance holding the source store
+		if d.Name() == "FLOCK" {
+			return nil
+		}
+		return copyFrom(path, dest, 0, nil)
+	})
+}
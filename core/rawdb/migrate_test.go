@@ -0,0 +1,98 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func TestMigrateFromLevelDB(t *testing.T) {
+	var (
+		src   = memorydb.New()
+		dst   = memorydb.New()
+		items = 2*migrateBatchItems + 1
+	)
+	for i := 0; i < items; i++ {
+		key := binary.BigEndian.AppendUint32(nil, uint32(i))
+		src.Put(key, append([]byte("value"), key...))
+	}
+	var reports []uint64
+	if err := MigrateFromLevelDB(src, dst, func(copied uint64) { reports = append(reports, copied) }); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	want := []uint64{migrateBatchItems, 2 * migrateBatchItems, uint64(items)}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("progress mismatch: have %v, want %v", reports, want)
+	}
+	if dst.Len() != items {
+		t.Fatalf("entry count mismatch: have %d, want %d", dst.Len(), items)
+	}
+	it := src.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if value, _ := dst.Get(it.Key()); !bytes.Equal(value, it.Value()) {
+			t.Fatalf("entry %x mismatch: have %x, want %x", it.Key(), value, it.Value())
+		}
+	}
+	// A corrupted destination must fail the verification
+	dst.Put([]byte{0, 0, 0, 0}, []byte("corrupt"))
+	if err := verifyMigration(src, dst); err == nil {
+		t.Error("corrupted migration verified")
+	}
+}
+
+func TestMigrateAncients(t *testing.T) {
+	var (
+		srcDir = t.TempDir()
+		dstDir = filepath.Join(t.TempDir(), "ancient")
+		files  = map[string]string{
+			"chain/headers.0000.cdat": "headers",
+			"chain/headers.cidx":      "index",
+			"chain/hashes.meta":       "meta",
+		}
+	)
+	os.MkdirAll(filepath.Join(srcDir, "chain"), 0755)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(srcDir, "chain", "FLOCK"), nil, 0644)
+
+	if err := MigrateAncients(srcDir, dstDir); err != nil {
+		t.Fatalf("failed to migrate ancients: %v", err)
+	}
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(dstDir, name))
+		if err != nil {
+			t.Fatalf("file %s not migrated: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("file %s mismatch: have %q, want %q", name, data, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "chain", "FLOCK")); !os.IsNotExist(err) {
+		t.Error("lock file migrated")
+	}
+}
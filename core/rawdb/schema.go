@@ -92,9 +92,12 @@ var (
 
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
+	blobSidecarsPrefix  = []byte("x") // blobSidecarsPrefix + num (uint64 big endian) + hash -> blob sidecars
+	blockMetadataPrefix = []byte("m") // blockMetadataPrefix + num (uint64 big endian) + hash -> block metadata
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	bloomRangePrefix      = []byte("R") // bloomRangePrefix + range (uint64 big endian) -> aggregated bloom of the range
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
@@ -175,6 +178,16 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blobSidecarsKey = blobSidecarsPrefix + num (uint64 big endian) + hash
+func blobSidecarsKey(number uint64, hash common.Hash) []byte {
+	return append(append(blobSidecarsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// blockMetadataKey = blockMetadataPrefix + num (uint64 big endian) + hash
+func blockMetadataKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockMetadataPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -205,6 +218,11 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// bloomRangeKey = bloomRangePrefix + range (uint64 big endian)
+func bloomRangeKey(section uint64) []byte {
+	return append(bloomRangePrefix, encodeBlockNumber(section)...)
+}
+
 // skeletonHeaderKey = skeletonHeaderPrefix + num (uint64 big endian)
 func skeletonHeaderKey(number uint64) []byte {
 	return append(skeletonHeaderPrefix, encodeBlockNumber(number)...)
@@ -17,6 +17,9 @@
 package state
 
 import (
+	"bytes"
+	"sort"
+
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -71,6 +74,37 @@ func (a *accessList) Copy() *accessList {
 	return cp
 }
 
+// StorageKeys returns the slots of the given address in the access list, in
+// ascending order.
+func (al *accessList) StorageKeys(address common.Address) []common.Hash {
+	idx, ok := al.addresses[address]
+	if !ok || idx == -1 {
+		return []common.Hash{}
+	}
+	keys := make([]common.Hash, 0, len(al.slots[idx]))
+	for slot := range al.slots[idx] {
+		keys = append(keys, slot)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+	return keys
+}
+
+// NumAddresses returns the number of addresses in the access list.
+func (al *accessList) NumAddresses() int {
+	return len(al.addresses)
+}
+
+// NumSlots returns the number of slots in the access list, across all addresses.
+func (al *accessList) NumSlots() int {
+	var n int
+	for _, slots := range al.slots {
+		n += len(slots)
+	}
+	return n
+}
+
 // AddAddress adds an address to the access list, and returns 'true' if the operation
 // caused a change (addr was not previously in the list).
 func (al *accessList) AddAddress(address common.Address) bool {
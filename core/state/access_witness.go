@@ -0,0 +1,110 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AccessWitness is the set of state locations accessed while executing a block,
+// i.e. the accounts and storage slots that a stateless verifier needs in order
+// to re-execute it. Every account maps to the Verkle tree stem holding its
+// header fields and every slot to the leaf holding its value, so the witness
+// determines the Verkle keys to be proven.
+type AccessWitness struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+// NewAccessWitness creates an empty access witness.
+func NewAccessWitness() *AccessWitness {
+	return &AccessWitness{
+		accounts: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// AddAccount marks the account at addr as accessed.
+func (aw *AccessWitness) AddAccount(addr common.Address) {
+	if _, ok := aw.accounts[addr]; !ok {
+		aw.accounts[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// AddSlot marks the storage slot of the account at addr as accessed. The
+// account itself is marked too.
+func (aw *AccessWitness) AddSlot(addr common.Address, slot common.Hash) {
+	aw.AddAccount(addr)
+	aw.accounts[addr][slot] = struct{}{}
+}
+
+// AddAccessList marks all accounts and slots in the list as accessed.
+func (aw *AccessWitness) AddAccessList(list types.AccessList) {
+	for _, tuple := range list {
+		aw.AddAccount(tuple.Address)
+		for _, slot := range tuple.StorageKeys {
+			aw.AddSlot(tuple.Address, slot)
+		}
+	}
+}
+
+// Merge adds all accesses of other into the witness.
+func (aw *AccessWitness) Merge(other *AccessWitness) {
+	for addr, slots := range other.accounts {
+		aw.AddAccount(addr)
+		for slot := range slots {
+			aw.accounts[addr][slot] = struct{}{}
+		}
+	}
+}
+
+// Accounts returns the accessed accounts in ascending order.
+func (aw *AccessWitness) Accounts() []common.Address {
+	addrs := make([]common.Address, 0, len(aw.accounts))
+	for addr := range aw.accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+// Slots returns the accessed storage slots of the account at addr in ascending
+// order.
+func (aw *AccessWitness) Slots(addr common.Address) []common.Hash {
+	slots := make([]common.Hash, 0, len(aw.accounts[addr]))
+	for slot := range aw.accounts[addr] {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return bytes.Compare(slots[i][:], slots[j][:]) < 0
+	})
+	return slots
+}
+
+// Len returns the number of accessed locations, counting every account and
+// every storage slot once.
+func (aw *AccessWitness) Len() int {
+	n := len(aw.accounts)
+	for _, slots := range aw.accounts {
+		n += len(slots)
+	}
+	return n
+}
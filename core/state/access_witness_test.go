@@ -0,0 +1,86 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestAccessWitnessMerge(t *testing.T) {
+	var (
+		a = common.Address{0xaa}
+		b = common.Address{0xbb}
+	)
+	w1 := NewAccessWitness()
+	w1.AddAccessList(types.AccessList{
+		{Address: b, StorageKeys: []common.Hash{{0x02}, {0x01}}},
+	})
+	w2 := NewAccessWitness()
+	w2.AddAccount(a)
+	w2.AddSlot(b, common.Hash{0x01})
+	w2.AddSlot(b, common.Hash{0x03})
+
+	w1.Merge(w2)
+	if have, want := w1.Accounts(), []common.Address{a, b}; !reflect.DeepEqual(have, want) {
+		t.Errorf("accounts mismatch: have %x, want %x", have, want)
+	}
+	if have, want := w1.Slots(b), []common.Hash{{0x01}, {0x02}, {0x03}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("slots mismatch: have %x, want %x", have, want)
+	}
+	if have := w1.Slots(a); len(have) != 0 {
+		t.Errorf("unexpected slots for %x: %x", a, have)
+	}
+	if have, want := w1.Len(), 5; have != want {
+		t.Errorf("length mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that the state records the accounts and slots read or written into the
+// witness, keeping the accesses of reverted changes.
+func TestAccessWitnessRecording(t *testing.T) {
+	var (
+		a = common.Address{0xaa}
+		b = common.Address{0xbb}
+		c = common.Address{0xcc}
+	)
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+	state.GetBalance(c) // not recorded, the witness isn't set yet
+
+	witness := NewAccessWitness()
+	state.SetAccessWitness(witness)
+
+	snap := state.Snapshot()
+	state.GetBalance(a)
+	state.SetState(b, common.Hash{0x02}, common.Hash{0x01})
+	state.GetCommittedState(b, common.Hash{0x01})
+	state.RevertToSnapshot(snap)
+
+	state.SetAccessWitness(nil)
+	state.GetState(c, common.Hash{0x03})
+
+	if have, want := witness.Accounts(), []common.Address{a, b}; !reflect.DeepEqual(have, want) {
+		t.Errorf("accounts mismatch: have %x, want %x", have, want)
+	}
+	if have, want := witness.Slots(b), []common.Hash{{0x01}, {0x02}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("slots mismatch: have %x, want %x", have, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AccountCache is an LRU cache of account data that outlives a single StateDB,
+// letting consecutive blocks reuse the accounts loaded by their predecessors.
+//
+// The cached accounts are only valid for one state root at a time. A StateDB
+// only consults the cache if it was opened at that root, and advances it when
+// committing, evicting the accounts it modified. Opening a StateDB at any other
+// root, e.g. on a reorg, simply misses the cache.
+type AccountCache struct {
+	root     common.Hash
+	accounts lru.BasicLRU[common.Address, types.StateAccount]
+	lock     sync.Mutex
+}
+
+// NewAccountCache creates an account cache holding at most size accounts.
+func NewAccountCache(size int) *AccountCache {
+	return &AccountCache{
+		accounts: lru.NewBasicLRU[common.Address, types.StateAccount](size),
+	}
+}
+
+// copyAccount returns a copy of the account not sharing the mutable balance.
+func copyAccount(acc *types.StateAccount) types.StateAccount {
+	cpy := *acc
+	if acc.Balance != nil {
+		cpy.Balance = new(big.Int).Set(acc.Balance)
+	}
+	return cpy
+}
+
+// get retrieves the account at addr if the cache tracks the given state root.
+func (c *AccountCache) get(root common.Hash, addr common.Address) (*types.StateAccount, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.root != root {
+		return nil, false
+	}
+	acc, ok := c.accounts.Get(addr)
+	if !ok {
+		return nil, false
+	}
+	cpy := copyAccount(&acc)
+	return &cpy, true
+}
+
+// add inserts an account loaded from the given state root into the cache. If
+// the cache doesn't track the root yet and is empty, it starts doing so.
+func (c *AccountCache) add(root common.Hash, addr common.Address, acc *types.StateAccount) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.root != root {
+		if c.accounts.Len() > 0 {
+			return
+		}
+		c.root = root
+	}
+	c.accounts.Add(addr, copyAccount(acc))
+}
+
+// commit moves the cache from the parent state root to root, evicting the
+// accounts modified in between. If the cache tracked a different root, it is
+// flushed instead as nothing in it is known to be valid for root.
+func (c *AccountCache) commit(parent, root common.Hash, dirty []common.Address) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.root != parent {
+		c.accounts.Purge()
+	}
+	for _, addr := range dirty {
+		c.accounts.Remove(addr)
+	}
+	c.root = root
+}
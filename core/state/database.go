@@ -132,15 +132,34 @@ func NewDatabase(db ethdb.Database) Database {
 	return NewDatabaseWithConfig(db, nil)
 }
 
+// TrieConfig defines the tunables of the trie database backing a state database.
+type TrieConfig struct {
+	Preimages bool   // Flag whether the preimage of trie key is recorded
+	Cache     int    // Memory allowance (MB) to use for caching clean trie nodes
+	Journal   string // Journal of clean cache to survive node restarts
+}
+
+// trieConfig converts the state level configuration into the trie database one.
+func (c *TrieConfig) trieConfig() *trie.Config {
+	if c == nil {
+		return nil
+	}
+	return &trie.Config{
+		Cache:     c.Cache,
+		Journal:   c.Journal,
+		Preimages: c.Preimages,
+	}
+}
+
 // NewDatabaseWithConfig creates a backing store for state. The returned database
 // is safe for concurrent use and retains a lot of collapsed RLP trie nodes in a
 // large memory cache.
-func NewDatabaseWithConfig(db ethdb.Database, config *trie.Config) Database {
+func NewDatabaseWithConfig(db ethdb.Database, config *TrieConfig) Database {
 	return &cachingDB{
 		disk:          db,
 		codeSizeCache: lru.NewCache[common.Hash, int](codeSizeCacheSize),
 		codeCache:     lru.NewSizeConstrainedCache[common.Hash, []byte](codeCacheSize),
-		triedb:        trie.NewDatabaseWithConfig(db, config),
+		triedb:        trie.NewDatabaseWithConfig(db, config.trieConfig()),
 	}
 }
 
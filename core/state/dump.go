@@ -167,21 +167,12 @@ func (s *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) (nextKey []
 			account.Code = obj.Code(s.db)
 		}
 		if !conf.SkipStorage {
-			account.Storage = make(map[common.Hash]string)
-			tr, err := obj.getTrie(s.db)
+			storage, err := s.dumpStorage(obj)
 			if err != nil {
 				log.Error("Failed to load storage trie", "err", err)
 				continue
 			}
-			storageIt := trie.NewIterator(tr.NodeIterator(nil))
-			for storageIt.Next() {
-				_, content, _, err := rlp.Split(storageIt.Value)
-				if err != nil {
-					log.Error("Failed to decode the value returned by iterator", "error", err)
-					continue
-				}
-				account.Storage[common.BytesToHash(s.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(content)
-			}
+			account.Storage = storage
 		}
 		c.OnAccount(addr, account)
 		accounts++
@@ -206,6 +197,60 @@ func (s *StateDB) DumpToCollector(c DumpCollector, conf *DumpConfig) (nextKey []
 	return nextKey
 }
 
+// dumpStorage iterates the storage trie of the given account, returning all the
+// slots in it.
+func (s *StateDB) dumpStorage(obj *stateObject) (map[common.Hash]string, error) {
+	tr, err := obj.getTrie(s.db)
+	if err != nil {
+		return nil, err
+	}
+	storage := make(map[common.Hash]string)
+	storageIt := trie.NewIterator(tr.NodeIterator(nil))
+	for storageIt.Next() {
+		_, content, _, err := rlp.Split(storageIt.Value)
+		if err != nil {
+			log.Error("Failed to decode the value returned by iterator", "error", err)
+			continue
+		}
+		storage[common.BytesToHash(s.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(content)
+	}
+	return storage, storageIt.Err
+}
+
+// AccountDump returns a single account in the same format as the full dump,
+// including its entire storage. It returns nil if the account doesn't exist.
+func (s *StateDB) AccountDump(addr common.Address) (*DumpAccount, error) {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		return nil, s.Error()
+	}
+	storage, err := s.dumpStorage(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &DumpAccount{
+		Balance:  obj.Balance().String(),
+		Nonce:    obj.Nonce(),
+		Root:     obj.data.Root[:],
+		CodeHash: obj.CodeHash(),
+		Code:     obj.Code(s.db),
+		Storage:  storage,
+	}, nil
+}
+
+// StorageDump returns the values of the given storage slots of the account at
+// addr, without iterating its storage trie.
+func (s *StateDB) StorageDump(addr common.Address, keys []common.Hash) (map[common.Hash]common.Hash, error) {
+	storage := make(map[common.Hash]common.Hash, len(keys))
+	for _, key := range keys {
+		storage[key] = s.GetState(addr, key)
+	}
+	if err := s.Error(); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
 // RawDump returns the entire state an a single large object
 func (s *StateDB) RawDump(opts *DumpConfig) Dump {
 	dump := &Dump{
@@ -0,0 +1,88 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+)
+
+// RootResolver maps a block number to the state root of that block.
+type RootResolver func(number uint64) (common.Hash, error)
+
+// HistoricalStateReader answers account queries against the state of arbitrary
+// past blocks. Unlike opening a StateDB per block, it keeps the account tries of
+// recently queried state roots open, so that time-series queries over a range
+// of blocks only pay the trie-open cost once per root.
+type HistoricalStateReader struct {
+	db      Database
+	resolve RootResolver
+	tries   lru.BasicLRU[common.Hash, Trie]
+	lock    sync.Mutex // Protects the cache and the tries, which aren't thread safe
+}
+
+// NewHistoricalStateReader creates a reader of the historical states in db,
+// keeping the tries of at most size state roots open. The resolver is used to
+// look up the state root of a block.
+func NewHistoricalStateReader(db Database, resolve RootResolver, size int) *HistoricalStateReader {
+	return &HistoricalStateReader{
+		db:      db,
+		resolve: resolve,
+		tries:   lru.NewBasicLRU[common.Hash, Trie](size),
+	}
+}
+
+// openTrie returns the account trie of the given state root, opening it if it
+// is not cached yet. The caller must hold the lock.
+func (r *HistoricalStateReader) openTrie(root common.Hash) (Trie, error) {
+	if tr, ok := r.tries.Get(root); ok {
+		return tr, nil
+	}
+	tr, err := r.db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	r.tries.Add(root, tr)
+	return tr, nil
+}
+
+// BalanceAt returns the balance of the account at addr in the state of the given
+// block. The balance of a non-existent account is zero.
+func (r *HistoricalStateReader) BalanceAt(addr common.Address, number uint64) (*big.Int, error) {
+	root, err := r.resolve(number)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	tr, err := r.openTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := tr.GetAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil {
+		return new(big.Int), nil
+	}
+	return new(big.Int).Set(acc.Balance), nil
+}
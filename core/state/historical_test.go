@@ -0,0 +1,74 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestHistoricalStateReaderBalanceAt(t *testing.T) {
+	var (
+		db      = NewDatabase(rawdb.NewMemoryDatabase())
+		addr    = common.Address{0x01}
+		missing = common.Address{0x02}
+		roots   []common.Hash
+	)
+	// Create a chain of states, crediting the account once per block
+	state := NewEmptyStateDB(db)
+	for i := 0; i < 4; i++ {
+		state.AddBalance(addr, big.NewInt(10))
+		root, err := state.Commit(false)
+		if err != nil {
+			t.Fatalf("block %d: failed to commit state: %v", i, err)
+		}
+		roots = append(roots, root)
+		state, _ = New(root, db, nil)
+	}
+	resolve := func(number uint64) (common.Hash, error) {
+		if number >= uint64(len(roots)) {
+			return common.Hash{}, fmt.Errorf("unknown block %d", number)
+		}
+		return roots[number], nil
+	}
+	reader := NewHistoricalStateReader(db, resolve, 2)
+
+	for round := 0; round < 2; round++ {
+		for number := range roots {
+			balance, err := reader.BalanceAt(addr, uint64(number))
+			if err != nil {
+				t.Fatalf("block %d: failed to read balance: %v", number, err)
+			}
+			if want := big.NewInt(int64(number+1) * 10); balance.Cmp(want) != 0 {
+				t.Errorf("block %d: balance mismatch: have %v, want %v", number, balance, want)
+			}
+			if balance, _ := reader.BalanceAt(missing, uint64(number)); balance.Sign() != 0 {
+				t.Errorf("block %d: non-zero balance of missing account: %v", number, balance)
+			}
+		}
+	}
+	if n := reader.tries.Len(); n != 2 {
+		t.Errorf("cached trie count mismatch: have %d, want %d", n, 2)
+	}
+	if _, err := reader.BalanceAt(addr, uint64(len(roots))); err == nil {
+		t.Error("expected error for unknown block")
+	}
+}
@@ -84,6 +84,22 @@ func (j *journal) length() int {
 	return len(j.entries)
 }
 
+// CountEntries returns the current number of entries in the journal. It is meant
+// for tests and debugging, the state itself uses length.
+func (j *journal) CountEntries() int {
+	return len(j.entries)
+}
+
+// CountEntriesSince returns the number of entries appended after the journal
+// had the given length, as recorded for a revision. Like revert, it takes a
+// journal index and not a StateDB snapshot id.
+func (j *journal) CountEntriesSince(snapshot int) int {
+	if snapshot >= len(j.entries) {
+		return 0
+	}
+	return len(j.entries) - snapshot
+}
+
 type (
 	// Changes to the account trie.
 	createObjectChange struct {
@@ -0,0 +1,150 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StorageProof is the Merkle proof of a single storage slot of an account.
+type StorageProof struct {
+	Key   common.Hash
+	Value common.Hash // Value of the slot, zero if the slot is empty
+	Proof [][]byte
+}
+
+// MerkleProof is the EIP-1186 Merkle proof of an account and some of its
+// storage slots.
+type MerkleProof struct {
+	Address       common.Address
+	AccountProof  [][]byte
+	StorageProofs []StorageProof
+}
+
+// GenerateMerkleProof returns the Merkle proof of the account at addr in the
+// state trie, along with the proofs of the given storage slots in the storage
+// trie of the account. The storage proofs are empty if the account does not
+// exist.
+//
+// The account proof is generated from the state trie as of the last computed
+// root, see IntermediateRoot.
+func (s *StateDB) GenerateMerkleProof(addr common.Address, keys []common.Hash) (*MerkleProof, error) {
+	accountProof, err := s.GetProof(addr)
+	if err != nil {
+		return nil, err
+	}
+	storageTrie, err := s.StorageTrie(addr)
+	if err != nil {
+		return nil, err
+	}
+	proof := &MerkleProof{
+		Address:       addr,
+		AccountProof:  accountProof,
+		StorageProofs: make([]StorageProof, len(keys)),
+	}
+	for i, key := range keys {
+		proof.StorageProofs[i].Key = key
+		if storageTrie == nil {
+			continue
+		}
+		enc, err := storageTrie.GetStorage(addr, key.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if proof.StorageProofs[i].Value, err = decodeStorageValue(enc); err != nil {
+			return nil, err
+		}
+		var list proofList
+		if err := storageTrie.Prove(crypto.Keccak256(key.Bytes()), 0, &list); err != nil {
+			return nil, err
+		}
+		proof.StorageProofs[i].Proof = list
+	}
+	return proof, nil
+}
+
+// Verify checks that the account proof is valid for stateRoot and that the
+// storage proofs are valid for the storage root of the proven account and
+// prove the claimed slot values.
+func (p *MerkleProof) Verify(stateRoot common.Hash) error {
+	blob, err := verifyProof(stateRoot, crypto.Keccak256(p.Address.Bytes()), p.AccountProof)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %w", err)
+	}
+	storageRoot := types.EmptyRootHash
+	if blob != nil {
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return fmt.Errorf("invalid account: %w", err)
+		}
+		storageRoot = account.Root
+	}
+	for _, storage := range p.StorageProofs {
+		// An empty storage trie has no nodes to prove anything with
+		if storageRoot == types.EmptyRootHash {
+			if len(storage.Proof) != 0 {
+				return fmt.Errorf("storage proof of slot %x: unexpected nodes for empty storage", storage.Key)
+			}
+			if storage.Value != (common.Hash{}) {
+				return fmt.Errorf("storage proof of slot %x: value %x in empty storage", storage.Key, storage.Value)
+			}
+			continue
+		}
+		enc, err := verifyProof(storageRoot, crypto.Keccak256(storage.Key.Bytes()), storage.Proof)
+		if err != nil {
+			return fmt.Errorf("invalid storage proof of slot %x: %w", storage.Key, err)
+		}
+		value, err := decodeStorageValue(enc)
+		if err != nil {
+			return fmt.Errorf("invalid storage value of slot %x: %w", storage.Key, err)
+		}
+		if value != storage.Value {
+			return fmt.Errorf("storage proof of slot %x: value mismatch: have %x, proven %x", storage.Key, storage.Value, value)
+		}
+	}
+	return nil
+}
+
+// verifyProof checks a Merkle proof given as a list of trie nodes, returning the
+// proven value, or nil if the proof shows the key to be absent.
+func verifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	db := memorydb.New()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return trie.VerifyProof(root, key, db)
+}
+
+// decodeStorageValue decodes a storage slot value as stored in the trie, with
+// a nil leaf denoting an empty slot.
+func decodeStorageValue(enc []byte) (common.Hash, error) {
+	if len(enc) == 0 {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(enc)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestGenerateMerkleProof(t *testing.T) {
+	var (
+		db      = NewDatabase(rawdb.NewMemoryDatabase())
+		addr    = common.Address{0x01}
+		empty   = common.Address{0x02}
+		missing = common.Address{0x03}
+		keys    = []common.Hash{{0x01}, {0x02}}
+	)
+	state := NewEmptyStateDB(db)
+	for i := byte(0); i < 16; i++ {
+		state.SetBalance(common.Address{0x10 + i}, big.NewInt(int64(i)+1))
+	}
+	state.SetBalance(addr, big.NewInt(1))
+	state.SetState(addr, keys[0], common.Hash{0xff})
+	state.SetBalance(empty, big.NewInt(1))
+
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	state, _ = New(root, db, nil)
+
+	for _, a := range []common.Address{addr, empty, missing} {
+		proof, err := state.GenerateMerkleProof(a, keys)
+		if err != nil {
+			t.Fatalf("account %x: failed to generate proof: %v", a, err)
+		}
+		if len(proof.StorageProofs) != len(keys) {
+			t.Fatalf("account %x: storage proof count mismatch: have %d, want %d", a, len(proof.StorageProofs), len(keys))
+		}
+		if err := proof.Verify(root); err != nil {
+			t.Errorf("account %x: valid proof rejected: %v", a, err)
+		}
+		for _, storage := range proof.StorageProofs {
+			if want := state.GetState(a, storage.Key); storage.Value != want {
+				t.Errorf("account %x: slot %x value mismatch: have %x, want %x", a, storage.Key, storage.Value, want)
+			}
+		}
+		if err := proof.Verify(common.Hash{0xde, 0xad}); err == nil {
+			t.Errorf("account %x: proof accepted for wrong root", a)
+		}
+	}
+	// Claiming a different value than the one proven must be rejected, both
+	// for a present and an absent slot
+	for i := range keys {
+		proof, _ := state.GenerateMerkleProof(addr, keys)
+		proof.StorageProofs[i].Value = common.Hash{0xee}
+		if err := proof.Verify(root); err == nil {
+			t.Errorf("slot %x: forged value accepted", keys[i])
+		}
+	}
+	proof, _ := state.GenerateMerkleProof(empty, keys)
+	proof.StorageProofs[0].Value = common.Hash{0xee}
+	if err := proof.Verify(root); err == nil {
+		t.Error("forged value in empty storage accepted")
+	}
+	// Tampering with any node must invalidate the proof
+	proof, _ = state.GenerateMerkleProof(addr, keys)
+	proof.StorageProofs[0].Proof[0][len(proof.StorageProofs[0].Proof[0])-1] ^= 0xff
+	if err := proof.Verify(root); err == nil {
+		t.Error("tampered storage proof accepted")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// DeleteStateRange deletes the trie nodes of the state at fromRoot which are not
+// part of the state at toRoot, leaving the latter intact. It is meant for manual
+// pruning of a specific historical state, once a newer one is available.
+//
+// As a safety measure, the nodes of the head and genesis states are never
+// deleted, and deleting the head state itself is refused. Other states sharing
+// nodes with fromRoot may however become incomplete. Contract codes are left
+// untouched, as they are shared by content across all states.
+//
+// The reachable nodes of the states are tracked in memory, so this is only
+// suitable for states of moderate size. It only supports the hash-based trie
+// node scheme.
+func DeleteStateRange(db ethdb.Database, fromRoot, toRoot common.Hash) error {
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("failed to load head block")
+	}
+	if fromRoot == head.Root() {
+		return errors.New("refusing to delete the head state")
+	}
+	if fromRoot == toRoot {
+		return nil
+	}
+	start := time.Now()
+
+	// Collect the nodes of the states which must be retained
+	keep := make(map[common.Hash]struct{})
+	protected := []common.Hash{toRoot, head.Root()}
+	if genesis := rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, 0), 0); genesis != nil {
+		protected = append(protected, genesis.Root())
+	}
+	for _, root := range protected {
+		if err := iterateStateNodes(db, root, func(hash common.Hash) { keep[hash] = struct{}{} }); err != nil {
+			return err
+		}
+	}
+	// Delete the nodes only reachable from the stale state. The deletion is
+	// deferred until the iteration is done, as the iterator still needs them.
+	var stale []common.Hash
+	err := iterateStateNodes(db, fromRoot, func(hash common.Hash) {
+		if _, ok := keep[hash]; !ok {
+			keep[hash] = struct{}{} // Avoid deleting shared subtries twice
+			stale = append(stale, hash)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	batch := db.NewBatch()
+	for _, hash := range stale {
+		rawdb.DeleteLegacyTrieNode(batch, hash)
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Deleted stale state", "root", fromRoot, "retained", toRoot, "nodes", len(stale), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// iterateStateNodes calls fn with the hash of every trie node of the state at
+// root, including the nodes of all its storage tries. Embedded nodes are not
+// reported, as they are not stored on their own.
+func iterateStateNodes(db ethdb.Database, root common.Hash, fn func(hash common.Hash)) error {
+	if root == types.EmptyRootHash {
+		return nil
+	}
+	triedb := trie.NewDatabase(db)
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
+	if err != nil {
+		return err
+	}
+	accIter := t.NodeIterator(nil)
+	for accIter.Next(true) {
+		if hash := accIter.Hash(); hash != (common.Hash{}) {
+			fn(hash)
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		id := trie.StorageTrieID(root, common.BytesToHash(accIter.LeafKey()), acc.Root)
+		storageTrie, err := trie.NewStateTrie(id, triedb)
+		if err != nil {
+			return err
+		}
+		storageIter := storageTrie.NodeIterator(nil)
+		for storageIter.Next(true) {
+			if hash := storageIter.Hash(); hash != (common.Hash{}) {
+				fn(hash)
+			}
+		}
+		if storageIter.Error() != nil {
+			return storageIter.Error()
+		}
+	}
+	return accIter.Error()
+}
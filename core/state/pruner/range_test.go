@@ -0,0 +1,87 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that deleting a state range removes the stale nodes only, keeping the
+// retained state fully resolvable.
+func TestDeleteStateRange(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		sdb    = state.NewDatabase(db)
+		roots  []common.Hash
+		shared = common.Address{0x01}
+		stale  = common.Address{0x02}
+	)
+	// Create two consecutive states, sharing the storage of one account
+	for i := 0; i < 2; i++ {
+		parent := types.EmptyRootHash
+		if i > 0 {
+			parent = roots[i-1]
+		}
+		statedb, _ := state.New(parent, sdb, nil)
+		if i == 0 {
+			for j := byte(0); j < 16; j++ {
+				statedb.SetState(shared, common.Hash{j}, common.Hash{j + 1})
+			}
+		}
+		for j := byte(0); j < 16; j++ {
+			statedb.SetState(stale, common.Hash{j}, common.Hash{byte(i + 1), j + 1})
+		}
+		statedb.AddBalance(stale, big.NewInt(1))
+
+		root, err := statedb.Commit(false)
+		if err != nil {
+			t.Fatalf("failed to commit state %d: %v", i, err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("failed to flush state %d: %v", i, err)
+		}
+		roots = append(roots, root)
+	}
+	head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Root: roots[1]})
+	rawdb.WriteBlock(db, head)
+	rawdb.WriteCanonicalHash(db, head.Hash(), 1)
+	rawdb.WriteHeadBlockHash(db, head.Hash())
+
+	if err := DeleteStateRange(db, roots[1], roots[0]); err == nil {
+		t.Fatal("deleting the head state not refused")
+	}
+	if err := DeleteStateRange(db, roots[0], roots[1]); err != nil {
+		t.Fatalf("failed to delete state range: %v", err)
+	}
+	if rawdb.HasLegacyTrieNode(db, roots[0]) {
+		t.Fatal("stale state root not deleted")
+	}
+	// The retained state must be intact, including the shared storage
+	if err := iterateStateNodes(db, roots[1], func(common.Hash) {}); err != nil {
+		t.Fatalf("retained state damaged: %v", err)
+	}
+	statedb, _ := state.New(roots[1], state.NewDatabase(db), nil)
+	if have := statedb.GetState(shared, common.Hash{3}); have != (common.Hash{4}) {
+		t.Fatalf("shared storage mismatch: have %x, want %x", have, common.Hash{4})
+	}
+}
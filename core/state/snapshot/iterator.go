@@ -226,6 +226,26 @@ func (it *diskAccountIterator) Release() {
 	}
 }
 
+// rangeAccountIterator is an account iterator that stops at an upper bound of
+// the account hashes, exclusive.
+type rangeAccountIterator struct {
+	AccountIterator
+	limit common.Hash
+	done  bool
+}
+
+// Next steps the iterator forward one element, returning false if exhausted or
+// if the next account is beyond the limit.
+func (it *rangeAccountIterator) Next() bool {
+	if it.done || !it.AccountIterator.Next() {
+		return false
+	}
+	if it.limit != (common.Hash{}) && bytes.Compare(it.AccountIterator.Hash().Bytes(), it.limit.Bytes()) >= 0 {
+		it.done = true
+	}
+	return !it.done
+}
+
 // diffStorageIterator is a storage iterator that steps over the specific storage
 // (both live and deleted) contained within a single diff layer. Higher order
 // iterators will use the deleted slot to skip deeper iterators.
@@ -649,6 +649,65 @@ func TestAccountIteratorSeek(t *testing.T) {
 	verifyIterator(t, 0, it, verifyAccount) // expected: nothing
 }
 
+// TestAccountIteratorRange tests that splitting the account space into ranges
+// and iterating them one after the other yields the same accounts as a single
+// full iteration.
+func TestAccountIteratorRange(t *testing.T) {
+	base := &diskLayer{
+		diskdb: rawdb.NewMemoryDatabase(),
+		root:   common.HexToHash("0x01"),
+		cache:  fastcache.New(1024 * 500),
+	}
+	snaps := &Tree{
+		layers: map[common.Hash]snapshot{
+			base.root: base,
+		},
+	}
+	for i := 1; i < 4; i++ {
+		accounts := make(map[common.Hash][]byte)
+		for j := 0; j < 256; j++ {
+			accounts[randomHash()] = randomAccount()
+		}
+		snaps.Update(common.HexToHash(fmt.Sprintf("0x%02x", i+1)), common.HexToHash(fmt.Sprintf("0x%02x", i)), nil, accounts, nil)
+	}
+	root := common.HexToHash("0x04")
+
+	var full []common.Hash
+	it, _ := snaps.AccountIterator(root, common.Hash{})
+	for it.Next() {
+		full = append(full, it.Hash())
+	}
+	it.Release()
+
+	// Split the account space into 8 ranges, leaving the last one open ended
+	var ranged []common.Hash
+	for i := 0; i < 8; i++ {
+		from, to := common.Hash{byte(i * 32)}, common.Hash{}
+		if i < 7 {
+			to = common.Hash{byte((i + 1) * 32)}
+		}
+		it, err := snaps.AccountIteratorRange(root, from, to)
+		if err != nil {
+			t.Fatalf("range %d: failed to create iterator: %v", i, err)
+		}
+		for it.Next() {
+			if hash := it.Hash(); bytes.Compare(hash[:], from[:]) < 0 || (i < 7 && bytes.Compare(hash[:], to[:]) >= 0) {
+				t.Errorf("range %d: account %x out of range [%x, %x)", i, hash, from, to)
+			}
+			ranged = append(ranged, it.Hash())
+		}
+		it.Release()
+	}
+	if len(ranged) != len(full) {
+		t.Fatalf("account count mismatch: have %d, want %d", len(ranged), len(full))
+	}
+	for i := range full {
+		if ranged[i] != full[i] {
+			t.Fatalf("account %d mismatch: have %x, want %x", i, ranged[i], full[i])
+		}
+	}
+}
+
 func TestStorageIteratorSeek(t *testing.T) {
 	// Create a snapshot stack with some initial data
 	base := &diskLayer{
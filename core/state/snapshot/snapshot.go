@@ -753,6 +753,17 @@ func (t *Tree) AccountIterator(root common.Hash, seek common.Hash) (AccountItera
 	return newFastAccountIterator(t, root, seek)
 }
 
+// AccountIteratorRange creates a new account iterator for the specified root hash,
+// yielding only the accounts with hashes in the range [from, to). A zero to hash
+// leaves the range open ended, so that the range can cover the last account.
+func (t *Tree) AccountIteratorRange(root common.Hash, from, to common.Hash) (AccountIterator, error) {
+	it, err := t.AccountIterator(root, from)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeAccountIterator{AccountIterator: it, limit: to}, nil
+}
+
 // StorageIterator creates a new storage iterator for the specified root hash and
 // account. The iterator will be move to the specific start position.
 func (t *Tree) StorageIterator(root common.Hash, account common.Hash, seek common.Hash) (StorageIterator, error) {
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
 )
 
 type Code []byte
@@ -391,6 +392,56 @@ func (s *stateObject) SubBalance(amount *big.Int) {
 	s.SetBalance(new(big.Int).Sub(s.Balance(), amount))
 }
 
+// addBalanceU256 is the uint256 counterpart of AddBalance, allocating only the
+// resulting balance.
+func (s *stateObject) addBalanceU256(amount *uint256.Int) {
+	if amount.IsZero() {
+		if s.empty() {
+			s.touch()
+		}
+		return
+	}
+	var balance uint256.Int
+	if s.data.Balance.Sign() < 0 || balance.SetFromBig(s.data.Balance) {
+		s.AddBalance(amount.ToBig()) // Unrepresentable balance, unreachable in practice
+		return
+	}
+	if _, overflow := balance.AddOverflow(&balance, amount); overflow {
+		s.AddBalance(amount.ToBig())
+		return
+	}
+	s.replaceBalance(balance.ToBig())
+}
+
+// subBalanceU256 is the uint256 counterpart of SubBalance, allocating only the
+// resulting balance. It returns false without modifying the balance if it is
+// lower than amount.
+func (s *stateObject) subBalanceU256(amount *uint256.Int) bool {
+	if amount.IsZero() {
+		return true
+	}
+	var balance uint256.Int
+	if s.data.Balance.Sign() < 0 || balance.SetFromBig(s.data.Balance) {
+		return false // Unrepresentable balance, unreachable in practice
+	}
+	if balance.Lt(amount) {
+		return false
+	}
+	s.replaceBalance(balance.Sub(&balance, amount).ToBig())
+	return true
+}
+
+// replaceBalance sets the balance like SetBalance, but journals the previous
+// balance without copying it. This is safe as balances are always replaced,
+// never modified in place.
+func (s *stateObject) replaceBalance(amount *big.Int) {
+	s.db.journal.append(balanceChange{
+		account: &s.address,
+		prev:    s.data.Balance,
+	})
+	s.setBalance(amount)
+}
+
 func (s *stateObject) SetBalance(amount *big.Int) {
 	s.db.journal.append(balanceChange{
 		account: &s.address,
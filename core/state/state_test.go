@@ -19,13 +19,13 @@ package state
 import (
 	"bytes"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
-	"github.com/ethereum/go-ethereum/trie"
 )
 
 type stateTest struct {
@@ -35,13 +35,13 @@ type stateTest struct {
 
 func newStateTest() *stateTest {
 	db := rawdb.NewMemoryDatabase()
-	sdb, _ := New(common.Hash{}, NewDatabase(db), nil)
+	sdb := NewEmptyStateDB(NewDatabase(db))
 	return &stateTest{db: db, state: sdb}
 }
 
 func TestDump(t *testing.T) {
 	db := rawdb.NewMemoryDatabase()
-	sdb, _ := New(common.Hash{}, NewDatabaseWithConfig(db, &trie.Config{Preimages: true}), nil)
+	sdb := NewEmptyStateDB(NewDatabaseWithConfig(db, &TrieConfig{Preimages: true}))
 	s := &stateTest{db: db, state: sdb}
 
 	// generate a few entries
@@ -55,7 +55,7 @@ func TestDump(t *testing.T) {
 	// write some of them to the trie
 	s.state.updateStateObject(obj1)
 	s.state.updateStateObject(obj2)
-	s.state.Commit(false)
+	s.state.MustCommit(false)
 
 	// check that DumpToCollector contains the state objects that are in trie
 	got := string(s.state.Dump(nil))
@@ -91,6 +91,42 @@ func TestDump(t *testing.T) {
 	}
 }
 
+func TestAccountDump(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	state := NewEmptyStateDB(NewDatabaseWithConfig(db, &TrieConfig{Preimages: true}))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state.SetBalance(addr, big.NewInt(22))
+	state.SetNonce(addr, 3)
+	state.SetCode(addr, []byte{3, 3, 3})
+	state.SetState(addr, common.Hash{0x01}, common.BigToHash(big.NewInt(2)))
+	state.SetState(addr, common.Hash{0x03}, common.BigToHash(big.NewInt(4)))
+	root := state.MustCommit(false)
+
+	state, _ = New(root, state.db, nil)
+	dump, err := state.AccountDump(addr)
+	if err != nil {
+		t.Fatalf("failed to dump account: %v", err)
+	}
+	if dump.Balance != "22" || dump.Nonce != 3 || !bytes.Equal(dump.Code, []byte{3, 3, 3}) {
+		t.Fatalf("account mismatch: have %+v", dump)
+	}
+	if len(dump.Storage) != 2 || dump.Storage[common.Hash{0x01}] != "02" || dump.Storage[common.Hash{0x03}] != "04" {
+		t.Fatalf("storage mismatch: have %v", dump.Storage)
+	}
+	if dump, err := state.AccountDump(common.BytesToAddress([]byte{0x02})); dump != nil || err != nil {
+		t.Fatalf("missing account dumped: %+v, %v", dump, err)
+	}
+	storage, err := state.StorageDump(addr, []common.Hash{{0x01}, {0x05}})
+	if err != nil {
+		t.Fatalf("failed to dump storage: %v", err)
+	}
+	want := map[common.Hash]common.Hash{{0x01}: common.BigToHash(big.NewInt(2)), {0x05}: {}}
+	if !reflect.DeepEqual(storage, want) {
+		t.Fatalf("storage mismatch: have %v, want %v", storage, want)
+	}
+}
+
 func TestNull(t *testing.T) {
 	s := newStateTest()
 	address := common.HexToAddress("0x823140710bf13990e4500136726d8b55")
@@ -99,7 +135,7 @@ func TestNull(t *testing.T) {
 	var value common.Hash
 
 	s.state.SetState(address, common.Hash{}, value)
-	s.state.Commit(false)
+	s.state.MustCommit(false)
 
 	if value := s.state.GetState(address, common.Hash{}); value != (common.Hash{}) {
 		t.Errorf("expected empty current value, got %x", value)
@@ -150,7 +186,7 @@ func TestSnapshotEmpty(t *testing.T) {
 }
 
 func TestSnapshot2(t *testing.T) {
-	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 
 	stateobjaddr0 := common.BytesToAddress([]byte("so0"))
 	stateobjaddr1 := common.BytesToAddress([]byte("so1"))
@@ -171,7 +207,7 @@ func TestSnapshot2(t *testing.T) {
 	so0.deleted = false
 	state.setStateObject(so0)
 
-	root, _ := state.Commit(false)
+	root := state.MustCommit(false)
 	state, _ = New(root, state.db, state.snaps)
 
 	// and one with deleted == true
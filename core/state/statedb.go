@@ -18,10 +18,13 @@
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
+	"runtime"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -34,8 +37,13 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
 )
 
+// ErrInsufficientBalance is returned by SubBalanceU256 if the balance of the
+// account is lower than the amount to subtract.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
 type revision struct {
 	id           int
 	journalIndex int
@@ -69,6 +77,7 @@ type StateDB struct {
 
 	snaps        *snapshot.Tree
 	snap         snapshot.Snapshot
+	accountCache *AccountCache // Optional account cache shared across blocks
 	snapAccounts map[common.Hash][]byte
 	snapStorage  map[common.Hash]map[common.Hash][]byte
 
@@ -97,6 +106,14 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// Optional callback invoked with the keccak256 preimages of the addresses
+	// and storage keys written to, used by tracers. Not copied.
+	preimageHook func(hash common.Hash, preimage []byte)
+
+	// Optional recorder of every account and storage slot accessed. It is kept
+	// outside the journal, so reverted accesses stay recorded. Not copied.
+	witness *AccessWitness
+
 	// Per-transaction access list
 	accessList *accessList
 
@@ -160,6 +177,29 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 	return sdb, nil
 }
 
+// NewEmptyStateDB creates a new state rooted at the empty trie, without any
+// snapshot backing it.
+func NewEmptyStateDB(db Database) *StateDB {
+	sdb, err := New(types.EmptyRootHash, db, nil)
+	if err != nil {
+		// The empty trie is never resolved from the database
+		panic(fmt.Sprintf("failed to open empty trie: %v", err))
+	}
+	return sdb
+}
+
+// NewStateDBWithCache creates a new state from a given trie, reusing the
+// accounts in the given cache which were loaded by the ancestors of this state.
+// The cache is consulted before the snapshot tree, if any.
+func NewStateDBWithCache(root common.Hash, db Database, snaps *snapshot.Tree, cache *AccountCache) (*StateDB, error) {
+	sdb, err := New(root, db, snaps)
+	if err != nil {
+		return nil, err
+	}
+	sdb.accountCache = cache
+	return sdb, nil
+}
+
 // StartPrefetcher initializes a new trie prefetcher to pull in nodes from the
 // state trie concurrently while the state is mutated so that when we reach the
 // commit phase, most of the needed data is already hot.
@@ -233,6 +273,21 @@ func (s *StateDB) AddPreimage(hash common.Hash, preimage []byte) {
 	}
 }
 
+// SetPreimageHook sets a callback to be invoked with the hash and the preimage
+// of every storage key written and every account created, as hashed for the
+// secure tries. The same preimage may be reported multiple times. Passing nil
+// removes the callback.
+func (s *StateDB) SetPreimageHook(hook func(hash common.Hash, preimage []byte)) {
+	s.preimageHook = hook
+}
+
+// SetAccessWitness sets the witness recording every account and storage slot
+// read or written from now on, including the accesses of reverted executions.
+// Passing nil stops the recording.
+func (s *StateDB) SetAccessWitness(witness *AccessWitness) {
+	s.witness = witness
+}
+
 // Preimages returns a list of SHA3 preimages that have been submitted.
 func (s *StateDB) Preimages() map[common.Hash][]byte {
 	return s.preimages
@@ -316,6 +371,9 @@ func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
 
 // GetState retrieves a value from the given account's storage trie.
 func (s *StateDB) GetState(addr common.Address, hash common.Hash) common.Hash {
+	if s.witness != nil {
+		s.witness.AddSlot(addr, hash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(s.db, hash)
@@ -354,6 +412,9 @@ func (s *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte,
 
 // GetCommittedState retrieves a value from the given account's committed storage trie.
 func (s *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
+	if s.witness != nil {
+		s.witness.AddSlot(addr, hash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetCommittedState(s.db, hash)
@@ -409,6 +470,31 @@ func (s *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 	}
 }
 
+// AddBalanceU256 adds amount to the account associated with addr. It is the
+// allocation-saving counterpart of AddBalance for callers doing their balance
+// arithmetic on uint256.
+func (s *StateDB) AddBalanceU256(addr common.Address, amount *uint256.Int) {
+	stateObject := s.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.addBalanceU256(amount)
+	}
+}
+
+// SubBalanceU256 subtracts amount from the account associated with addr. It is
+// the allocation-saving counterpart of SubBalance for callers doing their balance
+// arithmetic on uint256. Unlike SubBalance, it leaves the balance untouched and
+// returns ErrInsufficientBalance if the balance is lower than amount.
+func (s *StateDB) SubBalanceU256(addr common.Address, amount *uint256.Int) error {
+	if amount.IsZero() {
+		return nil
+	}
+	stateObject := s.getStateObject(addr)
+	if stateObject == nil || !stateObject.subBalanceU256(amount) {
+		return fmt.Errorf("%w: address %v, amount %v", ErrInsufficientBalance, addr, amount)
+	}
+	return nil
+}
+
 func (s *StateDB) SetBalance(addr common.Address, amount *big.Int) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
@@ -430,13 +516,64 @@ func (s *StateDB) SetCode(addr common.Address, code []byte) {
 	}
 }
 
+// CodeStore is a source of contract code keyed by code hash, allowing accounts
+// to share a single copy of identical code.
+type CodeStore interface {
+	GetCode(hash common.Hash) []byte
+}
+
+// SetCodeFromHash sets the code of the account to the one with the given hash,
+// retrieved from store. If store is nil, the code is looked up among the codes
+// already known to the state database. An error is returned if the code can't
+// be found or doesn't match the hash, in which case the account is unchanged.
+func (s *StateDB) SetCodeFromHash(addr common.Address, codeHash common.Hash, store CodeStore) error {
+	var code []byte
+	if codeHash != types.EmptyCodeHash {
+		if store != nil {
+			code = store.GetCode(codeHash)
+		} else {
+			code, _ = s.db.ContractCode(crypto.Keccak256Hash(addr.Bytes()), codeHash)
+		}
+		if len(code) == 0 {
+			return fmt.Errorf("code %x not found", codeHash)
+		}
+		if hash := crypto.Keccak256Hash(code); hash != codeHash {
+			return fmt.Errorf("code hash mismatch: have %x, want %x", hash, codeHash)
+		}
+	}
+	stateObject := s.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetCode(codeHash, code)
+	}
+	return nil
+}
+
 func (s *StateDB) SetState(addr common.Address, key, value common.Hash) {
+	if s.witness != nil {
+		s.witness.AddSlot(addr, key)
+	}
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetState(s.db, key, value)
+		if s.preimageHook != nil {
+			s.preimageHook(crypto.Keccak256Hash(key[:]), common.CopyBytes(key[:]))
+		}
 	}
 }
 
+// SetStateIfZero sets the storage slot of the account at addr to value if the
+// slot is currently zero, reporting whether the write happened. The slot is
+// added to the access list either way, so that the read is journalled even if
+// the write is skipped.
+func (s *StateDB) SetStateIfZero(addr common.Address, key, value common.Hash) bool {
+	s.AddSlotToAccessList(addr, key)
+	if s.GetState(addr, key) != (common.Hash{}) {
+		return false
+	}
+	s.SetState(addr, key, value)
+	return true
+}
+
 // SetStorage replaces the entire storage for the specified account with given
 // storage. This function should only be used for debugging.
 func (s *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
@@ -542,6 +679,9 @@ func (s *StateDB) deleteStateObject(obj *stateObject) {
 // the object is not found or was deleted in this execution context. If you need
 // to differentiate between non-existent/just-deleted, use getDeletedStateObject.
 func (s *StateDB) getStateObject(addr common.Address) *stateObject {
+	if s.witness != nil {
+		s.witness.AddAccount(addr)
+	}
 	if obj := s.getDeletedStateObject(addr); obj != nil && !obj.deleted {
 		return obj
 	}
@@ -557,9 +697,15 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
 	}
-	// If no live objects are available, attempt to use snapshots
+	// If no live objects are available, attempt to use the account cache
 	var data *types.StateAccount
-	if s.snap != nil {
+	if s.accountCache != nil {
+		if acc, ok := s.accountCache.get(s.originalRoot, addr); ok {
+			data = acc
+		}
+	}
+	// If not cached, attempt to use snapshots
+	if data == nil && s.snap != nil {
 		start := time.Now()
 		acc, err := s.snap.Account(crypto.HashData(s.hasher, addr.Bytes()))
 		if metrics.EnabledExpensive {
@@ -569,18 +715,7 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 			if acc == nil {
 				return nil
 			}
-			data = &types.StateAccount{
-				Nonce:    acc.Nonce,
-				Balance:  acc.Balance,
-				CodeHash: acc.CodeHash,
-				Root:     common.BytesToHash(acc.Root),
-			}
-			if len(data.CodeHash) == 0 {
-				data.CodeHash = types.EmptyCodeHash.Bytes()
-			}
-			if data.Root == (common.Hash{}) {
-				data.Root = types.EmptyRootHash
-			}
+			data = fullAccount(acc)
 		}
 	}
 	// If snapshot unavailable or reading from it failed, load from the database
@@ -599,12 +734,120 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 			return nil
 		}
 	}
+	if s.accountCache != nil {
+		s.accountCache.add(s.originalRoot, addr, data)
+	}
 	// Insert into the live set
 	obj := newObject(s, addr, *data)
 	s.setStateObject(obj)
 	return obj
 }
 
+// fullAccount converts a slim snapshot account into its consensus form.
+func fullAccount(acc *snapshot.Account) *types.StateAccount {
+	data := &types.StateAccount{
+		Nonce:    acc.Nonce,
+		Balance:  acc.Balance,
+		CodeHash: acc.CodeHash,
+		Root:     common.BytesToHash(acc.Root),
+	}
+	if len(data.CodeHash) == 0 {
+		data.CodeHash = types.EmptyCodeHash.Bytes()
+	}
+	if data.Root == (common.Hash{}) {
+		data.Root = types.EmptyRootHash
+	}
+	return data
+}
+
+// Preload concurrently loads the accounts of the given addresses into the live
+// object set, so that subsequent accesses don't hit the database one by one.
+// Accounts that are already live or don't exist are skipped. Since the trie is
+// not safe for concurrent use, every worker reads through its own instance of
+// the pre-state trie, which is fine as modified accounts are always live.
+//
+// Preloading is a best-effort optimization: read failures are ignored and left
+// to surface on the regular access path.
+func (s *StateDB) Preload(addrs []common.Address) {
+	var (
+		pending = make([]common.Address, 0, len(addrs))
+		seen    = make(map[common.Address]struct{}, len(addrs))
+	)
+	for _, addr := range addrs {
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		if _, ok := s.stateObjects[addr]; ok {
+			continue
+		}
+		if s.accountCache != nil {
+			if data, ok := s.accountCache.get(s.originalRoot, addr); ok {
+				s.setStateObject(newObject(s, addr, *data))
+				continue
+			}
+		}
+		pending = append(pending, addr)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	workers := runtime.NumCPU()
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+	var (
+		accounts = make([]*types.StateAccount, len(pending))
+		tasks    = make(chan int, len(pending))
+		wg       sync.WaitGroup
+	)
+	for i := range pending {
+		tasks <- i
+	}
+	close(tasks)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var (
+				hasher = crypto.NewKeccakState()
+				tr     Trie
+			)
+			for i := range tasks {
+				addr := pending[i]
+				if s.snap != nil {
+					acc, err := s.snap.Account(crypto.HashData(hasher, addr.Bytes()))
+					if err == nil {
+						if acc != nil {
+							accounts[i] = fullAccount(acc)
+						}
+						continue
+					}
+				}
+				if tr == nil {
+					var err error
+					if tr, err = s.db.OpenTrie(s.originalRoot); err != nil {
+						return
+					}
+				}
+				accounts[i], _ = tr.GetAccount(addr)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, data := range accounts {
+		if data != nil {
+			if s.accountCache != nil {
+				s.accountCache.add(s.originalRoot, pending[i], data)
+			}
+			s.setStateObject(newObject(s, pending[i], *data))
+		}
+	}
+}
+
 func (s *StateDB) setStateObject(object *stateObject) {
 	s.stateObjects[object.Address()] = object
 }
@@ -658,6 +901,9 @@ func (s *StateDB) CreateAccount(addr common.Address) {
 	if prev != nil {
 		newObj.setBalance(prev.data.Balance)
 	}
+	if s.preimageHook != nil {
+		s.preimageHook(newObj.addrHash, common.CopyBytes(addr[:]))
+	}
 }
 
 func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) error {
@@ -693,6 +939,94 @@ func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common
 	return nil
 }
 
+// AccountsByBalance returns the addresses of all accounts in the committed
+// state whose balance lies within [min, max], sorted in ascending address
+// order. A nil bound leaves that side of the range open.
+//
+// The snapshot is iterated if one is available for the state root, otherwise
+// the account trie is walked. Either way, every account in the state is
+// visited, making this an O(accounts) operation that is only suitable for
+// offline use. Since both sources are keyed by address hash, accounts whose
+// preimage is not known to the database are skipped.
+func (s *StateDB) AccountsByBalance(min, max *big.Int) []common.Address {
+	var (
+		addrs   []common.Address
+		missing int
+	)
+	inRange := func(balance *big.Int) bool {
+		if min != nil && balance.Cmp(min) < 0 {
+			return false
+		}
+		return max == nil || balance.Cmp(max) <= 0
+	}
+	collect := func(hash []byte) {
+		preimage := s.trie.GetKey(hash)
+		if preimage == nil {
+			missing++
+			return
+		}
+		addrs = append(addrs, common.BytesToAddress(preimage))
+	}
+	if err := s.accountsByBalanceSnap(inRange, collect); err != nil {
+		addrs, missing = addrs[:0], 0
+
+		tr, err := s.db.OpenTrie(s.originalRoot)
+		if err != nil {
+			s.setError(err)
+			return nil
+		}
+		it := trie.NewIterator(tr.NodeIterator(nil))
+		for it.Next() {
+			var data types.StateAccount
+			if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+				s.setError(err)
+				return nil
+			}
+			if inRange(data.Balance) {
+				collect(it.Key)
+			}
+		}
+		if it.Err != nil {
+			s.setError(it.Err)
+			return nil
+		}
+	}
+	if missing > 0 {
+		log.Warn("Balance query incomplete due to missing preimages", "missing", missing)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+// accountsByBalanceSnap feeds the hashes of all snapshot accounts accepted by
+// the filter into collect. An error is returned if no snapshot is available
+// or it cannot be iterated, in which case the caller should fall back to the
+// trie.
+func (s *StateDB) accountsByBalanceSnap(filter func(*big.Int) bool, collect func([]byte)) error {
+	if s.snaps == nil {
+		return errors.New("snapshot unavailable")
+	}
+	it, err := s.snaps.AccountIterator(s.originalRoot, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		acc, err := snapshot.FullAccount(it.Account())
+		if err != nil {
+			return err
+		}
+		if filter(acc.Balance) {
+			hash := it.Hash()
+			collect(hash[:])
+		}
+	}
+	return it.Error()
+}
+
 // Copy creates a deep, independent copy of the state.
 // Snapshots of the copied state cannot be applied to the copy.
 func (s *StateDB) Copy() *StateDB {
@@ -701,6 +1035,7 @@ func (s *StateDB) Copy() *StateDB {
 		db:                   s.db,
 		trie:                 s.db.CopyTrie(s.trie),
 		originalRoot:         s.originalRoot,
+		accountCache:         s.accountCache,
 		stateObjects:         make(map[common.Address]*stateObject, len(s.journal.dirties)),
 		stateObjectsPending:  make(map[common.Address]struct{}, len(s.stateObjectsPending)),
 		stateObjectsDirty:    make(map[common.Address]struct{}, len(s.journal.dirties)),
@@ -823,6 +1158,25 @@ func (s *StateDB) RevertToSnapshot(revid int) {
 	s.validRevisions = s.validRevisions[:idx]
 }
 
+// Revert undoes all changes made since the state was created, or finalised for
+// the last time, and invalidates all snapshots.
+func (s *StateDB) Revert() {
+	s.journal.revert(s, 0)
+	s.validRevisions = s.validRevisions[:0]
+}
+
+// WithState applies the changes made by fn to the state as a single unit: if fn
+// fails, all its changes are reverted and the error is returned. Otherwise the
+// changes are finalised and the resulting intermediate root is returned.
+func (s *StateDB) WithState(deleteEmptyObjects bool, fn func(*StateDB) error) (common.Hash, error) {
+	snapshot := s.Snapshot()
+	if err := fn(s); err != nil {
+		s.RevertToSnapshot(snapshot)
+		return common.Hash{}, err
+	}
+	return s.IntermediateRoot(deleteEmptyObjects), nil
+}
+
 // GetRefund returns the current value of the refund counter.
 func (s *StateDB) GetRefund() uint64 {
 	return s.refund
@@ -881,6 +1235,12 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 // It is called in between transactions to get the root hash that
 // goes into transaction receipts.
 func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
+	return s.intermediateRoot(deleteEmptyObjects, 1)
+}
+
+// intermediateRoot is the implementation of IntermediateRoot, hashing the storage
+// tries with the given number of concurrent workers.
+func (s *StateDB) intermediateRoot(deleteEmptyObjects bool, workers int) common.Hash {
 	// Finalise all the dirty storage states and write them into the tries
 	s.Finalise(deleteEmptyObjects)
 
@@ -903,9 +1263,13 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 	// the account prefetcher. Instead, let's process all the storage updates
 	// first, giving the account prefetches just a few more milliseconds of time
 	// to pull useful data from disk.
-	for addr := range s.stateObjectsPending {
-		if obj := s.stateObjects[addr]; !obj.deleted {
-			obj.updateRoot(s.db)
+	if workers > 1 {
+		s.updateRootsParallel(workers)
+	} else {
+		for addr := range s.stateObjectsPending {
+			if obj := s.stateObjects[addr]; !obj.deleted {
+				obj.updateRoot(s.db)
+			}
 		}
 	}
 	// Now we're about to start to write changes to the trie. The trie is so far
@@ -958,33 +1322,73 @@ func (s *StateDB) clearJournalAndRefund() {
 
 // Commit writes the state to the underlying in-memory trie database.
 func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
+	return s.commit(deleteEmptyObjects, 1)
+}
+
+// MustCommit is like Commit, but panics if the state can't be committed. It is
+// intended for tests, where commit failures would otherwise go unnoticed.
+func (s *StateDB) MustCommit(deleteEmptyObjects bool) common.Hash {
+	root, err := s.Commit(deleteEmptyObjects)
+	if err != nil {
+		panic(fmt.Sprintf("failed to commit state: %v", err))
+	}
+	return root
+}
+
+// CommitParallel writes the state to the underlying in-memory trie database
+// like Commit, but hashes and commits the storage tries of the dirty accounts
+// on the given number of concurrent workers. The resulting root is identical.
+//
+// Only the storage tries are processed concurrently, as they are independent
+// of each other. The account trie is still updated and committed by a single
+// goroutine after all of them are done.
+func (s *StateDB) CommitParallel(deleteEmptyObjects bool, workers int) (common.Hash, error) {
+	return s.commit(deleteEmptyObjects, workers)
+}
+
+// commit is the implementation of Commit and CommitParallel.
+func (s *StateDB) commit(deleteEmptyObjects bool, workers int) (common.Hash, error) {
 	// Short circuit in case any database failure occurred earlier.
 	if s.dbErr != nil {
 		return common.Hash{}, fmt.Errorf("commit aborted due to earlier error: %v", s.dbErr)
 	}
 	// Finalize any pending changes and merge everything into the tries
-	s.IntermediateRoot(deleteEmptyObjects)
+	s.intermediateRoot(deleteEmptyObjects, workers)
 
 	// Commit objects to the trie, measuring the elapsed time
 	var (
+		parent                  = s.originalRoot
+		dirty                   = make([]common.Address, 0, len(s.stateObjectsDirty))
 		accountTrieNodesUpdated int
 		accountTrieNodesDeleted int
 		storageTrieNodesUpdated int
 		storageTrieNodesDeleted int
 		nodes                   = trie.NewMergedNodeSet()
 		codeWriter              = s.db.DiskDB().NewBatch()
+		sets                    map[common.Address]*trie.NodeSet
 	)
+	if workers > 1 {
+		var err error
+		if sets, err = s.commitTriesParallel(workers); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	for addr := range s.stateObjectsDirty {
+		dirty = append(dirty, addr)
 		if obj := s.stateObjects[addr]; !obj.deleted {
 			// Write any contract code associated with the state object
 			if obj.code != nil && obj.dirtyCode {
 				rawdb.WriteCode(codeWriter, common.BytesToHash(obj.CodeHash()), obj.code)
 				obj.dirtyCode = false
 			}
-			// Write any storage changes in the state object to its storage trie
-			set, err := obj.commitTrie(s.db)
-			if err != nil {
-				return common.Hash{}, err
+			// Write any storage changes in the state object to its storage trie,
+			// unless already done concurrently
+			set, done := sets[addr]
+			if !done {
+				var err error
+				if set, err = obj.commitTrie(s.db); err != nil {
+					return common.Hash{}, err
+				}
 			}
 			// Merge the dirty nodes of storage trie into global set
 			if set != nil {
@@ -1079,9 +1483,92 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 			s.TrieDBCommits += time.Since(start)
 		}
 	}
+	if s.accountCache != nil {
+		s.accountCache.commit(parent, s.originalRoot, dirty)
+	}
 	return root, nil
 }
 
+// updateRootsParallel updates the storage roots of all the pending objects like
+// calling updateRoot on each, hashing the storage tries on the given number of
+// concurrent workers. The storage changes themselves are flushed into the tries
+// beforehand by the calling goroutine, as that touches state shared across the
+// objects, such as the hasher, the snapshot maps and the metrics.
+func (s *StateDB) updateRootsParallel(workers int) {
+	var objs []*stateObject
+	for addr := range s.stateObjectsPending {
+		if obj := s.stateObjects[addr]; !obj.deleted {
+			if tr, err := obj.updateTrie(s.db); err == nil && tr != nil {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	if metrics.EnabledExpensive {
+		defer func(start time.Time) { s.StorageHashes += time.Since(start) }(time.Now())
+	}
+	forEachParallel(len(objs), workers, func(i int) {
+		objs[i].data.Root = objs[i].trie.Hash()
+	})
+}
+
+// commitTriesParallel commits the storage tries of all the dirty objects on the
+// given number of concurrent workers, returning the collected trie changes of
+// each. The storage changes must have been flushed into the tries beforehand.
+func (s *StateDB) commitTriesParallel(workers int) (map[common.Address]*trie.NodeSet, error) {
+	var objs []*stateObject
+	for addr := range s.stateObjectsDirty {
+		if obj := s.stateObjects[addr]; !obj.deleted {
+			if _, err := obj.updateTrie(s.db); err != nil {
+				return nil, err
+			}
+			objs = append(objs, obj)
+		}
+	}
+	if metrics.EnabledExpensive {
+		defer func(start time.Time) { s.StorageCommits += time.Since(start) }(time.Now())
+	}
+	sets := make([]*trie.NodeSet, len(objs))
+	forEachParallel(len(objs), workers, func(i int) {
+		// If nothing changed, don't bother with committing anything
+		if objs[i].trie == nil {
+			return
+		}
+		objs[i].data.Root, sets[i] = objs[i].trie.Commit(false)
+	})
+	result := make(map[common.Address]*trie.NodeSet, len(objs))
+	for i, obj := range objs {
+		result[obj.address] = sets[i]
+	}
+	return result, nil
+}
+
+// forEachParallel calls fn for all the indices in [0, n), spread across the
+// given number of goroutines.
+func forEachParallel(n int, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	var (
+		tasks = make(chan int, n)
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		tasks <- i
+	}
+	close(tasks)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // Prepare handles the preparatory steps for executing a state transition with.
 // This method must be invoked before state transition.
 //
@@ -1158,6 +1645,19 @@ func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addre
 	return s.accessList.Contains(addr, slot)
 }
 
+// TouchedAddresses returns the addresses accessed by the current transaction, as
+// tracked by EIP-2929, in ascending order.
+func (s *StateDB) TouchedAddresses() []common.Address {
+	addrs := make([]common.Address, 0, len(s.accessList.addresses))
+	for addr := range s.accessList.addresses {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
 // convertAccountSet converts a provided account set from address keyed to hash keyed.
 func (s *StateDB) convertAccountSet(set map[common.Address]struct{}) map[common.Hash]struct{} {
 	ret := make(map[common.Hash]struct{})
@@ -19,6 +19,7 @@ package state
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
@@ -31,7 +32,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -39,7 +43,7 @@ import (
 func TestUpdateLeaks(t *testing.T) {
 	// Create an empty state database
 	db := rawdb.NewMemoryDatabase()
-	state, _ := New(common.Hash{}, NewDatabase(db), nil)
+	state := NewEmptyStateDB(NewDatabase(db))
 
 	// Update it with some accounts
 	for i := byte(0); i < 255; i++ {
@@ -73,8 +77,8 @@ func TestIntermediateLeaks(t *testing.T) {
 	// Create two state databases, one transitioning to the final state, the other final from the beginning
 	transDb := rawdb.NewMemoryDatabase()
 	finalDb := rawdb.NewMemoryDatabase()
-	transState, _ := New(common.Hash{}, NewDatabase(transDb), nil)
-	finalState, _ := New(common.Hash{}, NewDatabase(finalDb), nil)
+	transState := NewEmptyStateDB(NewDatabase(transDb))
+	finalState := NewEmptyStateDB(NewDatabase(finalDb))
 
 	modify := func(state *StateDB, addr common.Address, i, tweak byte) {
 		state.SetBalance(addr, big.NewInt(int64(11*i)+int64(tweak)))
@@ -149,7 +153,7 @@ func TestIntermediateLeaks(t *testing.T) {
 // https://github.com/ethereum/go-ethereum/pull/15549.
 func TestCopy(t *testing.T) {
 	// Create a random state test to copy and modify "independently"
-	orig, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	orig := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 
 	for i := byte(0); i < 255; i++ {
 		obj := orig.GetOrNewStateObject(common.BytesToAddress([]byte{i}))
@@ -409,7 +413,7 @@ func (test *snapshotTest) String() string {
 func (test *snapshotTest) run() bool {
 	// Run all actions and create snapshots.
 	var (
-		state, _     = New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		state        = NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 		snapshotRevs = make([]int, len(test.snapshots))
 		sindex       = 0
 	)
@@ -423,7 +427,7 @@ func (test *snapshotTest) run() bool {
 	// Revert all snapshots in reverse order. Each revert must yield a state
 	// that is equivalent to fresh state with all actions up the snapshot applied.
 	for sindex--; sindex >= 0; sindex-- {
-		checkstate, _ := New(common.Hash{}, state.Database(), nil)
+		checkstate := NewEmptyStateDB(state.Database())
 		for _, action := range test.actions[:test.snapshots[sindex]] {
 			action.fn(action, checkstate)
 		}
@@ -483,7 +487,7 @@ func (test *snapshotTest) checkEqual(state, checkstate *StateDB) error {
 func TestTouchDelete(t *testing.T) {
 	s := newStateTest()
 	s.state.GetOrNewStateObject(common.Address{})
-	root, _ := s.state.Commit(false)
+	root := s.state.MustCommit(false)
 	s.state, _ = New(root, s.state.db, s.state.snaps)
 
 	snapshot := s.state.Snapshot()
@@ -501,7 +505,7 @@ func TestTouchDelete(t *testing.T) {
 // TestCopyOfCopy tests that modified objects are carried over to the copy, and the copy of the copy.
 // See https://github.com/ethereum/go-ethereum/pull/15225#issuecomment-380191512
 func TestCopyOfCopy(t *testing.T) {
-	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 	addr := common.HexToAddress("aaaa")
 	state.SetBalance(addr, big.NewInt(42))
 
@@ -518,7 +522,7 @@ func TestCopyOfCopy(t *testing.T) {
 //
 // See https://github.com/ethereum/go-ethereum/issues/20106.
 func TestCopyCommitCopy(t *testing.T) {
-	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 
 	// Create an account and check if the retrieved balance is correct
 	addr := common.HexToAddress("0xaffeaffeaffeaffeaffeaffeaffeaffeaffeaffe")
@@ -556,7 +560,7 @@ func TestCopyCommitCopy(t *testing.T) {
 		t.Fatalf("first copy pre-commit committed storage slot mismatch: have %x, want %x", val, common.Hash{})
 	}
 
-	copyOne.Commit(false)
+	copyOne.MustCommit(false)
 	if balance := copyOne.GetBalance(addr); balance.Cmp(big.NewInt(42)) != 0 {
 		t.Fatalf("first copy post-commit balance mismatch: have %v, want %v", balance, 42)
 	}
@@ -590,7 +594,7 @@ func TestCopyCommitCopy(t *testing.T) {
 //
 // See https://github.com/ethereum/go-ethereum/issues/20106.
 func TestCopyCopyCommitCopy(t *testing.T) {
-	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 
 	// Create an account and check if the retrieved balance is correct
 	addr := common.HexToAddress("0xaffeaffeaffeaffeaffeaffeaffeaffeaffeaffe")
@@ -641,7 +645,7 @@ func TestCopyCopyCommitCopy(t *testing.T) {
 	if val := copyTwo.GetCommittedState(addr, skey); val != (common.Hash{}) {
 		t.Fatalf("second copy pre-commit committed storage slot mismatch: have %x, want %x", val, common.Hash{})
 	}
-	copyTwo.Commit(false)
+	copyTwo.MustCommit(false)
 	if balance := copyTwo.GetBalance(addr); balance.Cmp(big.NewInt(42)) != 0 {
 		t.Fatalf("second copy post-commit balance mismatch: have %v, want %v", balance, 42)
 	}
@@ -680,12 +684,12 @@ func TestCopyCopyCommitCopy(t *testing.T) {
 // first, but the journal wiped the entire state object on create-revert.
 func TestDeleteCreateRevert(t *testing.T) {
 	// Create an initial state with a single contract
-	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
 
 	addr := common.BytesToAddress([]byte("so"))
 	state.SetBalance(addr, big.NewInt(1))
 
-	root, _ := state.Commit(false)
+	root := state.MustCommit(false)
 	state, _ = New(root, state.db, state.snaps)
 
 	// Simulate self-destructing in one transaction, then create-reverting in another
@@ -697,7 +701,7 @@ func TestDeleteCreateRevert(t *testing.T) {
 	state.RevertToSnapshot(id)
 
 	// Commit the entire state and make sure we don't crash and have the correct state
-	root, _ = state.Commit(true)
+	root = state.MustCommit(true)
 	state, _ = New(root, state.db, state.snaps)
 
 	if state.getStateObject(addr) != nil {
@@ -713,7 +717,7 @@ func TestMissingTrieNodes(t *testing.T) {
 	memDb := rawdb.NewMemoryDatabase()
 	db := NewDatabase(memDb)
 	var root common.Hash
-	state, _ := New(common.Hash{}, db, nil)
+	state := NewEmptyStateDB(db)
 	addr := common.BytesToAddress([]byte("so"))
 	{
 		state.SetBalance(addr, big.NewInt(1))
@@ -721,7 +725,7 @@ func TestMissingTrieNodes(t *testing.T) {
 		a2 := common.BytesToAddress([]byte("another"))
 		state.SetBalance(a2, big.NewInt(100))
 		state.SetCode(a2, []byte{1, 2, 4})
-		root, _ = state.Commit(false)
+		root = state.MustCommit(false)
 		t.Logf("root: %x", root)
 		// force-flush
 		state.Database().TrieDB().Cap(0)
@@ -762,7 +766,7 @@ func TestStateDBAccessList(t *testing.T) {
 
 	memDb := rawdb.NewMemoryDatabase()
 	db := NewDatabase(memDb)
-	state, _ := New(common.Hash{}, db, nil)
+	state := NewEmptyStateDB(db)
 	state.accessList = newAccessList()
 
 	verifyAddrs := func(astrings ...string) {
@@ -930,9 +934,9 @@ func TestStateDBAccessList(t *testing.T) {
 func TestFlushOrderDataLoss(t *testing.T) {
 	// Create a state trie with many accounts and slots
 	var (
-		memdb    = rawdb.NewMemoryDatabase()
-		statedb  = NewDatabase(memdb)
-		state, _ = New(common.Hash{}, statedb, nil)
+		memdb   = rawdb.NewMemoryDatabase()
+		statedb = NewDatabase(memdb)
+		state   = NewEmptyStateDB(statedb)
 	)
 	for a := byte(0); a < 10; a++ {
 		state.CreateAccount(common.Address{a})
@@ -968,7 +972,7 @@ func TestFlushOrderDataLoss(t *testing.T) {
 func TestStateDBTransientStorage(t *testing.T) {
 	memDb := rawdb.NewMemoryDatabase()
 	db := NewDatabase(memDb)
-	state, _ := New(common.Hash{}, db, nil)
+	state := NewEmptyStateDB(db)
 
 	key := common.Hash{0x01}
 	value := common.Hash{0x02}
@@ -998,3 +1002,715 @@ func TestStateDBTransientStorage(t *testing.T) {
 		t.Fatalf("transient storage mismatch: have %x, want %x", got, value)
 	}
 }
+
+func TestStateDBSetStateIfZero(t *testing.T) {
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+
+	var (
+		addr = common.Address{0x01}
+		key  = common.Hash{0x01}
+	)
+	// Two claims racing for the same slot, only the first one may succeed
+	var writes int
+	for _, value := range []common.Hash{{0x01}, {0x02}} {
+		if state.SetStateIfZero(addr, key, value) {
+			writes++
+		}
+	}
+	if writes != 1 {
+		t.Fatalf("write count mismatch: have %d, want 1", writes)
+	}
+	if got, exp := state.GetState(addr, key), (common.Hash{0x01}); got != exp {
+		t.Fatalf("storage mismatch: have %x, want %x", got, exp)
+	}
+	// A skipped write still marks the slot as accessed, revertibly
+	other := common.Hash{0x02}
+	state.SetState(addr, other, common.Hash{0x03})
+	snapshot := state.Snapshot()
+	if state.SetStateIfZero(addr, other, common.Hash{0x04}) {
+		t.Fatalf("non-zero slot overwritten")
+	}
+	if _, ok := state.SlotInAccessList(addr, other); !ok {
+		t.Fatalf("skipped write not recorded in the access list")
+	}
+	state.RevertToSnapshot(snapshot)
+	if _, ok := state.SlotInAccessList(addr, other); ok {
+		t.Fatalf("reverted read still in the access list")
+	}
+}
+
+func TestStateDBApplyDiff(t *testing.T) {
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+
+	var (
+		addr1 = common.Address{0x01}
+		addr2 = common.Address{0x02}
+		nonce = uint64(7)
+	)
+	state.SetBalance(addr1, big.NewInt(100))
+	state.SetState(addr2, common.Hash{0x01}, common.Hash{0xaa})
+
+	// An invalid diff must be rejected without touching the state
+	err := state.ApplyDiff(StateDiff{
+		addr1: {Balance: big.NewInt(1)},
+		addr2: {Balance: big.NewInt(-1)},
+	})
+	if err == nil {
+		t.Fatal("expected negative balance to be rejected")
+	}
+	if have := state.GetBalance(addr1); have.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("balance modified by rejected diff: have %v, want 100", have)
+	}
+	// A valid diff should overwrite only the fields it carries
+	snap := state.Snapshot()
+	err = state.ApplyDiff(StateDiff{
+		addr1: {Balance: big.NewInt(50), Nonce: &nonce},
+		addr2: {Code: []byte{0x60, 0x00}, Storage: map[common.Hash]common.Hash{{0x02}: {0xbb}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to apply diff: %v", err)
+	}
+	if have := state.GetBalance(addr1); have.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want 50", have)
+	}
+	if have := state.GetNonce(addr1); have != nonce {
+		t.Fatalf("nonce mismatch: have %d, want %d", have, nonce)
+	}
+	if have := state.GetCode(addr2); !bytes.Equal(have, []byte{0x60, 0x00}) {
+		t.Fatalf("code mismatch: have %x", have)
+	}
+	if have := state.GetState(addr2, common.Hash{0x01}); have != (common.Hash{0xaa}) {
+		t.Fatalf("untouched slot modified: have %x", have)
+	}
+	if have := state.GetState(addr2, common.Hash{0x02}); have != (common.Hash{0xbb}) {
+		t.Fatalf("slot mismatch: have %x, want %x", have, common.Hash{0xbb})
+	}
+	// The diff is journaled and can be reverted as a whole
+	state.RevertToSnapshot(snap)
+	if have := state.GetBalance(addr1); have.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("balance not reverted: have %v, want 100", have)
+	}
+	if have := state.GetCode(addr2); len(have) != 0 {
+		t.Fatalf("code not reverted: have %x", have)
+	}
+}
+
+func TestStateDBRevert(t *testing.T) {
+	var (
+		db    = NewDatabase(rawdb.NewMemoryDatabase())
+		addr1 = common.Address{0x01}
+		addr2 = common.Address{0x02}
+		addr3 = common.Address{0x03}
+	)
+	state := NewEmptyStateDB(db)
+	state.SetBalance(addr1, big.NewInt(100))
+	state.SetCode(addr1, []byte{0x60, 0x00})
+	state.SetState(addr1, common.Hash{0x01}, common.Hash{0xaa})
+	state.SetBalance(addr2, big.NewInt(200))
+	root := state.MustCommit(false)
+
+	state, _ = New(root, db, nil)
+	state.Snapshot()
+	state.SetBalance(addr1, big.NewInt(1))
+	state.SetCode(addr1, []byte{0x60, 0x01})
+	state.SetState(addr1, common.Hash{0x01}, common.Hash{0xbb})
+	state.SetState(addr1, common.Hash{0x02}, common.Hash{0xcc})
+	state.Suicide(addr2)
+	state.SetBalance(addr3, big.NewInt(300))
+	state.AddRefund(10)
+	state.AddLog(&types.Log{Address: addr1})
+
+	state.Revert()
+	if have := state.IntermediateRoot(false); have != root {
+		t.Fatalf("root mismatch after revert: have %x, want %x", have, root)
+	}
+	if have := state.GetBalance(addr1); have.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("balance not reverted: have %v, want 100", have)
+	}
+	if have := state.GetCode(addr1); !bytes.Equal(have, []byte{0x60, 0x00}) {
+		t.Fatalf("code not reverted: have %x", have)
+	}
+	if have := state.GetState(addr1, common.Hash{0x01}); have != (common.Hash{0xaa}) {
+		t.Fatalf("storage not reverted: have %x", have)
+	}
+	if have := state.GetState(addr1, common.Hash{0x02}); have != (common.Hash{}) {
+		t.Fatalf("storage not reverted: have %x", have)
+	}
+	if state.HasSuicided(addr2) {
+		t.Fatal("suicide not reverted")
+	}
+	if state.Exist(addr3) {
+		t.Fatal("account creation not reverted")
+	}
+	if have := state.GetRefund(); have != 0 {
+		t.Fatalf("refund not reverted: have %d", have)
+	}
+	if have := len(state.Logs()); have != 0 {
+		t.Fatalf("logs not reverted: have %d", have)
+	}
+	if have := len(state.validRevisions); have != 0 {
+		t.Fatalf("snapshots not invalidated: have %d", have)
+	}
+}
+
+// journalEntriesSince returns the number of journal entries added since the
+// given snapshot was taken.
+func journalEntriesSince(s *StateDB, revid int) int {
+	for _, rev := range s.validRevisions {
+		if rev.id == revid {
+			return s.journal.CountEntriesSince(rev.journalIndex)
+		}
+	}
+	panic(fmt.Errorf("revision id %v cannot be reverted", revid))
+}
+
+// Tests the number of journal entries created by the individual state
+// modifications, including the ones that turn out to be no-ops.
+func TestJournalEntryCounts(t *testing.T) {
+	state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+
+	var (
+		addr  = common.Address{0x01}
+		other = common.Address{0x02}
+	)
+	tests := []struct {
+		name string
+		op   func()
+		want int
+	}{
+		{"balance of new account", func() { state.SetBalance(addr, big.NewInt(1)) }, 2},
+		{"balance of existing account", func() { state.AddBalance(addr, big.NewInt(1)) }, 1},
+		{"zero balance change", func() { state.AddBalance(addr, new(big.Int)) }, 0},
+		{"nonce", func() { state.SetNonce(addr, 1) }, 1},
+		{"code", func() { state.SetCode(addr, []byte{0x60, 0x00}) }, 1},
+		{"new storage value", func() { state.SetState(addr, common.Hash{0x01}, common.Hash{0x01}) }, 1},
+		{"same storage value", func() { state.SetState(addr, common.Hash{0x01}, common.Hash{0x01}) }, 0},
+		{"transient storage", func() { state.SetTransientState(addr, common.Hash{0x01}, common.Hash{0x01}) }, 1},
+		{"same transient storage", func() { state.SetTransientState(addr, common.Hash{0x01}, common.Hash{0x01}) }, 0},
+		{"refund", func() { state.AddRefund(1) }, 1},
+		{"log", func() { state.AddLog(&types.Log{Address: addr}) }, 1},
+		{"access list address", func() { state.AddAddressToAccessList(other) }, 1},
+		{"access list slot", func() { state.AddSlotToAccessList(other, common.Hash{0x01}) }, 1},
+		{"access list slot and address", func() { state.AddSlotToAccessList(addr, common.Hash{0x01}) }, 2},
+		{"create new account", func() { state.CreateAccount(other) }, 1},
+		{"touch empty account", func() { state.AddBalance(other, new(big.Int)) }, 1},
+		{"recreate existing account", func() { state.CreateAccount(addr) }, 1},
+		{"suicide", func() { state.Suicide(addr) }, 1},
+		{"suicide missing account", func() { state.Suicide(common.Address{0x03}) }, 0},
+	}
+	total := 0
+	for _, tt := range tests {
+		snap := state.Snapshot()
+		tt.op()
+		if have := journalEntriesSince(state, snap); have != tt.want {
+			t.Errorf("%s: journal entry count mismatch: have %d, want %d", tt.name, have, tt.want)
+		}
+		total += tt.want
+	}
+	if have := state.journal.CountEntries(); have != total {
+		t.Errorf("total journal entry count mismatch: have %d, want %d", have, total)
+	}
+	// Reverting must drop the entries counted since the snapshot
+	snap := state.Snapshot()
+	state.SetNonce(other, 5)
+	state.RevertToSnapshot(snap)
+	if have := state.journal.CountEntries(); have != total {
+		t.Errorf("journal entry count after revert mismatch: have %d, want %d", have, total)
+	}
+}
+
+// mapCodeStore is a CodeStore backed by a plain map.
+type mapCodeStore map[common.Hash][]byte
+
+func (s mapCodeStore) GetCode(hash common.Hash) []byte { return s[hash] }
+
+func TestStateDBSetCodeFromHash(t *testing.T) {
+	var (
+		db       = NewDatabase(rawdb.NewMemoryDatabase())
+		code     = []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+		codeHash = crypto.Keccak256Hash(code)
+		store    = mapCodeStore{codeHash: code, {0x01}: {0x00}}
+		addr1    = common.Address{0x01}
+		addr2    = common.Address{0x02}
+	)
+	state := NewEmptyStateDB(db)
+
+	// Code unknown to both the store and the database must be rejected
+	if err := state.SetCodeFromHash(addr1, common.Hash{0x02}, store); err == nil {
+		t.Fatal("expected missing code to be rejected")
+	}
+	if err := state.SetCodeFromHash(addr1, codeHash, nil); err == nil {
+		t.Fatal("expected code missing from the database to be rejected")
+	}
+	// Code not matching the requested hash must be rejected
+	if err := state.SetCodeFromHash(addr1, common.Hash{0x01}, store); err == nil {
+		t.Fatal("expected mismatching code to be rejected")
+	}
+	if state.Exist(addr1) {
+		t.Fatal("account created by rejected code")
+	}
+	// Code present in the store is resolved from it
+	if err := state.SetCodeFromHash(addr1, codeHash, store); err != nil {
+		t.Fatalf("failed to set code from store: %v", err)
+	}
+	if have := state.GetCode(addr1); !bytes.Equal(have, code) {
+		t.Fatalf("code mismatch: have %x, want %x", have, code)
+	}
+	root := state.MustCommit(false)
+
+	// Once committed, the code can be shared without a store
+	state, _ = New(root, db, nil)
+	if err := state.SetCodeFromHash(addr2, codeHash, nil); err != nil {
+		t.Fatalf("failed to set code from database: %v", err)
+	}
+	if have := state.GetCode(addr2); !bytes.Equal(have, code) {
+		t.Fatalf("code mismatch: have %x, want %x", have, code)
+	}
+	if have := state.GetCodeHash(addr2); have != codeHash {
+		t.Fatalf("code hash mismatch: have %x, want %x", have, codeHash)
+	}
+	// The empty code hash clears the code
+	if err := state.SetCodeFromHash(addr2, types.EmptyCodeHash, nil); err != nil {
+		t.Fatalf("failed to clear code: %v", err)
+	}
+	if have := state.GetCodeSize(addr2); have != 0 {
+		t.Fatalf("code not cleared: have %d bytes", have)
+	}
+}
+
+func TestStateDBPreload(t *testing.T) {
+	var (
+		db    = NewDatabase(rawdb.NewMemoryDatabase())
+		state = newStateTest()
+		addrs []common.Address
+	)
+	state.state = NewEmptyStateDB(db)
+	for i := byte(1); i <= 64; i++ {
+		addr := common.Address{i}
+		state.state.SetBalance(addr, big.NewInt(int64(i)))
+		state.state.SetNonce(addr, uint64(i))
+		addrs = append(addrs, addr)
+	}
+	root := state.state.MustCommit(false)
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	statedb, _ := New(root, db, nil)
+
+	// Modify a live account, it must not be overwritten by the preload
+	statedb.SetBalance(addrs[0], big.NewInt(1000))
+
+	missing := common.Address{0xff}
+	statedb.Preload(append(addrs, addrs[0], missing))
+
+	for i, addr := range addrs {
+		if _, ok := statedb.stateObjects[addr]; !ok {
+			t.Fatalf("account %x not preloaded", addr)
+		}
+		want := big.NewInt(int64(i + 1))
+		if i == 0 {
+			want = big.NewInt(1000)
+		}
+		if have := statedb.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Fatalf("account %x balance mismatch: have %v, want %v", addr, have, want)
+		}
+		if have := statedb.GetNonce(addr); have != uint64(i+1) {
+			t.Fatalf("account %x nonce mismatch: have %d, want %d", addr, have, i+1)
+		}
+	}
+	if _, ok := statedb.stateObjects[missing]; ok {
+		t.Fatalf("non-existent account %x preloaded", missing)
+	}
+	if err := statedb.Error(); err != nil {
+		t.Fatalf("unexpected state error: %v", err)
+	}
+}
+
+// This measures loading many distinct accounts from a state persisted to disk,
+// with and without warming them up concurrently first.
+func BenchmarkStateDBPreload(b *testing.B) {
+	const accounts = 2000
+
+	diskdb, err := rawdb.NewLevelDBDatabase(b.TempDir(), 16, 16, "", false)
+	if err != nil {
+		b.Fatalf("failed to create database: %v", err)
+	}
+	defer diskdb.Close()
+
+	var (
+		state = NewEmptyStateDB(NewDatabase(diskdb))
+		addrs = make([]common.Address, accounts)
+	)
+	for i := range addrs {
+		binary.BigEndian.PutUint64(addrs[i][:], uint64(i)+1)
+		state.SetBalance(addrs[i], big.NewInt(int64(i)+1))
+	}
+	root := state.MustCommit(false)
+	if err := state.Database().TrieDB().Commit(root, false); err != nil {
+		b.Fatalf("failed to commit trie: %v", err)
+	}
+	for _, preload := range []bool{false, true} {
+		b.Run(fmt.Sprintf("preload=%v", preload), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// Use a fresh database to start out with cold trie caches
+				statedb, _ := New(root, NewDatabase(diskdb), nil)
+				if preload {
+					statedb.Preload(addrs)
+				}
+				for _, addr := range addrs {
+					statedb.GetBalance(addr)
+				}
+			}
+		})
+	}
+}
+
+func TestStateDBAccountCache(t *testing.T) {
+	var (
+		db    = NewDatabase(rawdb.NewMemoryDatabase())
+		cache = NewAccountCache(16)
+		addrs []common.Address
+	)
+	state := NewEmptyStateDB(db)
+	for i := byte(1); i <= 4; i++ {
+		addr := common.Address{i}
+		state.SetBalance(addr, big.NewInt(int64(i)))
+		addrs = append(addrs, addr)
+	}
+	root0 := state.MustCommit(false)
+
+	// Load all accounts through the cache, then modify one of them
+	state, _ = NewStateDBWithCache(root0, db, nil, cache)
+	for _, addr := range addrs {
+		state.GetBalance(addr)
+	}
+	state.AddBalance(addrs[0], big.NewInt(100))
+	root1 := state.MustCommit(false)
+
+	if cache.root != root1 {
+		t.Fatalf("cache root mismatch: have %x, want %x", cache.root, root1)
+	}
+	if cache.accounts.Contains(addrs[0]) {
+		t.Fatalf("modified account %x not evicted", addrs[0])
+	}
+	for _, addr := range addrs[1:] {
+		if !cache.accounts.Contains(addr) {
+			t.Fatalf("unmodified account %x not cached", addr)
+		}
+	}
+	// The child state must see the modification and the cached accounts
+	state, _ = NewStateDBWithCache(root1, db, nil, cache)
+	if have := state.GetBalance(addrs[0]); have.Cmp(big.NewInt(101)) != 0 {
+		t.Fatalf("modified balance mismatch: have %v, want 101", have)
+	}
+	if have := state.GetBalance(addrs[1]); have.Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("cached balance mismatch: have %v, want 2", have)
+	}
+	// Mutating a live object must not leak into the cache
+	state.AddBalance(addrs[1], big.NewInt(1))
+	if acc, _ := cache.get(root1, addrs[1]); acc.Balance.Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("cached balance modified: have %v, want 2", acc.Balance)
+	}
+	// A state at a different root, e.g. after a reorg, must not use the cache
+	state, _ = NewStateDBWithCache(root0, db, nil, cache)
+	if have := state.GetBalance(addrs[0]); have.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("reorged balance mismatch: have %v, want 1", have)
+	}
+	if cache.root != root1 {
+		t.Fatalf("cache root changed by a foreign state: have %x, want %x", cache.root, root1)
+	}
+}
+
+// This measures processing consecutive blocks which all touch the same few hot
+// accounts, with and without an account cache carried across the blocks.
+func BenchmarkStateDBAccountCache(b *testing.B) {
+	const (
+		blocks   = 100
+		hot      = 10
+		accounts = 10000
+	)
+	diskdb, err := rawdb.NewLevelDBDatabase(b.TempDir(), 16, 16, "", false)
+	if err != nil {
+		b.Fatalf("failed to create database: %v", err)
+	}
+	defer diskdb.Close()
+
+	var (
+		db    = NewDatabase(diskdb)
+		state = NewEmptyStateDB(db)
+		addrs = make([]common.Address, accounts)
+	)
+	for i := range addrs {
+		binary.BigEndian.PutUint64(addrs[i][:], uint64(i)+1)
+		state.SetBalance(addrs[i], big.NewInt(int64(i)+1))
+	}
+	root := state.MustCommit(false)
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		b.Fatalf("failed to commit trie: %v", err)
+	}
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cache=%v", cached), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var (
+					cache  *AccountCache
+					parent = root
+				)
+				if cached {
+					cache = NewAccountCache(1024)
+				}
+				for n := 0; n < blocks; n++ {
+					state, _ := NewStateDBWithCache(parent, db, nil, cache)
+					for _, addr := range addrs[:hot] {
+						state.GetBalance(addr)
+					}
+					// Every block pays the same fee recipient
+					state.AddBalance(addrs[0], common.Big1)
+					parent = state.MustCommit(false)
+				}
+			}
+		})
+	}
+}
+
+// Tests that committing the storage tries concurrently yields the same state as
+// the sequential commit.
+func TestCommitParallel(t *testing.T) {
+	var (
+		seqdb = NewDatabase(rawdb.NewMemoryDatabase())
+		pardb = NewDatabase(rawdb.NewMemoryDatabase())
+		seq   = common.Hash{}
+		par   = common.Hash{}
+	)
+	// mutate applies the same set of changes in every round, touching the
+	// storage of most accounts and deleting some of them from the second round
+	mutate := func(state *StateDB, round int) {
+		for i := 0; i < 100; i++ {
+			addr := common.BytesToAddress([]byte{byte(i), 0xaa})
+			if round > 0 && i%10 == 0 {
+				state.Suicide(addr)
+				continue
+			}
+			state.AddBalance(addr, big.NewInt(int64(i+1)))
+			for j := 0; j < i%7; j++ {
+				key := common.BytesToHash([]byte{byte(j)})
+				state.SetState(addr, key, common.BytesToHash([]byte{byte(i), byte(j), byte(round + 1)}))
+			}
+			if i%5 == 0 {
+				state.SetCode(addr, []byte{byte(i), byte(round)})
+			}
+		}
+	}
+	for round := 0; round < 3; round++ {
+		seqstate, _ := New(seq, seqdb, nil)
+		parstate, _ := New(par, pardb, nil)
+
+		mutate(seqstate, round)
+		mutate(parstate, round)
+
+		var err error
+		if seq, err = seqstate.Commit(true); err != nil {
+			t.Fatalf("round %d: failed to commit sequentially: %v", round, err)
+		}
+		if par, err = parstate.CommitParallel(true, 4); err != nil {
+			t.Fatalf("round %d: failed to commit in parallel: %v", round, err)
+		}
+		if seq != par {
+			t.Fatalf("round %d: root mismatch: sequential %x, parallel %x", round, seq, par)
+		}
+	}
+	// The committed state must be resolvable from the trie database
+	state, err := New(par, pardb, nil)
+	if err != nil {
+		t.Fatalf("failed to open committed state: %v", err)
+	}
+	addr := common.BytesToAddress([]byte{6, 0xaa})
+	if have, want := state.GetState(addr, common.BytesToHash([]byte{5})), common.BytesToHash([]byte{6, 5, 3}); have != want {
+		t.Fatalf("storage mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestAccountsByBalance(t *testing.T) {
+	var (
+		diskdb = rawdb.NewMemoryDatabase()
+		db     = NewDatabaseWithConfig(diskdb, &TrieConfig{Preimages: true})
+	)
+	sdb := NewEmptyStateDB(db)
+	for i := 0; i < 20; i++ {
+		sdb.AddBalance(common.BytesToAddress([]byte{byte(19 - i)}), big.NewInt(int64(i*10)))
+	}
+	root, err := sdb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	snaps, err := snapshot.New(snapshot.Config{CacheSize: 16}, diskdb, db.TrieDB(), root)
+	if err != nil {
+		t.Fatalf("failed to generate snapshot: %v", err)
+	}
+	tests := []struct {
+		min, max *big.Int
+		want     []byte
+	}{
+		{big.NewInt(50), big.NewInt(80), []byte{11, 12, 13, 14}},
+		{nil, big.NewInt(20), []byte{17, 18, 19}},
+		{big.NewInt(175), nil, []byte{0, 1}},
+		{big.NewInt(1000), nil, nil},
+	}
+	for _, snapTree := range []*snapshot.Tree{nil, snaps} {
+		state, err := New(root, db, snapTree)
+		if err != nil {
+			t.Fatalf("failed to open state: %v", err)
+		}
+		for i, tt := range tests {
+			var want []common.Address
+			for _, b := range tt.want {
+				want = append(want, common.BytesToAddress([]byte{b}))
+			}
+			if have := state.AccountsByBalance(tt.min, tt.max); !reflect.DeepEqual(have, want) {
+				t.Errorf("test %d (snapshot %v): accounts mismatch: have %v, want %v", i, snapTree != nil, have, want)
+			}
+		}
+		if err := state.Error(); err != nil {
+			t.Fatalf("state error: %v", err)
+		}
+	}
+}
+
+func TestAccessListStorageKeys(t *testing.T) {
+	var (
+		al    = newAccessList()
+		addr  = common.Address{0x01}
+		empty = common.Address{0x02}
+	)
+	al.AddSlot(addr, common.Hash{0x03})
+	al.AddSlot(addr, common.Hash{0x01})
+	al.AddSlot(addr, common.Hash{0x02})
+	al.AddAddress(empty)
+
+	if have, want := al.StorageKeys(addr), []common.Hash{{0x01}, {0x02}, {0x03}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("storage keys mismatch: have %x, want %x", have, want)
+	}
+	if keys := al.StorageKeys(empty); len(keys) != 0 {
+		t.Errorf("unexpected storage keys of slotless address: %x", keys)
+	}
+	if keys := al.StorageKeys(common.Address{0x03}); len(keys) != 0 {
+		t.Errorf("unexpected storage keys of missing address: %x", keys)
+	}
+	if n := al.NumAddresses(); n != 2 {
+		t.Errorf("address count mismatch: have %d, want 2", n)
+	}
+	if n := al.NumSlots(); n != 3 {
+		t.Errorf("slot count mismatch: have %d, want 3", n)
+	}
+}
+
+func TestStateDBWithState(t *testing.T) {
+	var (
+		state = NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+		addr  = common.Address{0x01}
+		fail  = errors.New("failed")
+	)
+	state.SetBalance(addr, big.NewInt(100))
+	root := state.IntermediateRoot(false)
+
+	// A failing modification must leave no trace
+	_, err := state.WithState(false, func(s *StateDB) error {
+		s.SetBalance(addr, big.NewInt(1))
+		s.SetState(addr, common.Hash{0x01}, common.Hash{0x02})
+		return fail
+	})
+	if err != fail {
+		t.Fatalf("error mismatch: have %v, want %v", err, fail)
+	}
+	if have := state.IntermediateRoot(false); have != root {
+		t.Fatalf("root mismatch after failure: have %x, want %x", have, root)
+	}
+	// A successful one must be applied and hashed
+	have, err := state.WithState(false, func(s *StateDB) error {
+		s.SetBalance(addr, big.NewInt(1))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("modification failed: %v", err)
+	}
+	if want := state.IntermediateRoot(false); have != want || have == root {
+		t.Fatalf("root mismatch after success: have %x, want %x", have, want)
+	}
+	if balance := state.GetBalance(addr); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want 1", balance)
+	}
+}
+
+func TestBalanceU256(t *testing.T) {
+	var (
+		state = NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+		addr  = common.Address{0x01}
+	)
+	state.AddBalanceU256(addr, uint256.NewInt(100))
+	if err := state.SubBalanceU256(addr, uint256.NewInt(30)); err != nil {
+		t.Fatalf("failed to subtract balance: %v", err)
+	}
+	if balance := state.GetBalance(addr); balance.Cmp(big.NewInt(70)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want 70", balance)
+	}
+	// Overdrawing must fail without modifying the balance
+	if err := state.SubBalanceU256(addr, uint256.NewInt(71)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInsufficientBalance)
+	}
+	if err := state.SubBalanceU256(common.Address{0x02}, uint256.NewInt(1)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("error mismatch for missing account: have %v, want %v", err, ErrInsufficientBalance)
+	}
+	if balance := state.GetBalance(addr); balance.Cmp(big.NewInt(70)) != 0 {
+		t.Fatalf("balance modified by failed subtraction: %v", balance)
+	}
+	// The changes must be journalled like the big.Int ones
+	snapshot := state.Snapshot()
+	state.AddBalanceU256(addr, uint256.NewInt(5))
+	state.SubBalanceU256(addr, uint256.NewInt(50))
+	state.RevertToSnapshot(snapshot)
+	if balance := state.GetBalance(addr); balance.Cmp(big.NewInt(70)) != 0 {
+		t.Fatalf("balance mismatch after revert: have %v, want 70", balance)
+	}
+}
+
+// This measures a block of simple transfers, with the balance arithmetic done
+// on big.Int and on uint256.
+func BenchmarkBalanceTransfers(b *testing.B) {
+	const (
+		transfers = 10000
+		accounts  = 100
+	)
+	addrs := make([]common.Address, accounts)
+	for i := range addrs {
+		binary.BigEndian.PutUint64(addrs[i][:], uint64(i)+1)
+	}
+	run := func(b *testing.B, transfer func(state *StateDB, from, to common.Address)) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			state := NewEmptyStateDB(NewDatabase(rawdb.NewMemoryDatabase()))
+			for _, addr := range addrs {
+				state.SetBalance(addr, big.NewInt(1_000_000_000))
+			}
+			for n := 0; n < transfers; n++ {
+				transfer(state, addrs[n%accounts], addrs[(n+1)%accounts])
+			}
+			state.MustCommit(false)
+		}
+	}
+	b.Run("big", func(b *testing.B) {
+		amount := big.NewInt(1000)
+		run(b, func(state *StateDB, from, to common.Address) {
+			state.SubBalance(from, amount)
+			state.AddBalance(to, amount)
+		})
+	})
+	b.Run("uint256", func(b *testing.B) {
+		amount := uint256.NewInt(1000)
+		run(b, func(state *StateDB, from, to common.Address) {
+			state.SubBalanceU256(from, amount)
+			state.AddBalanceU256(to, amount)
+		})
+	})
+}
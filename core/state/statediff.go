@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AccountDiff holds the post-state values of a single account that differ from
+// some base state. Nil fields are left untouched when the diff is applied.
+type AccountDiff struct {
+	Balance *big.Int
+	Nonce   *uint64
+	Code    []byte
+	Storage map[common.Hash]common.Hash
+}
+
+// StateDiff is a set of account changes keyed by address, e.g. the outcome of
+// a speculatively executed block.
+type StateDiff map[common.Address]*AccountDiff
+
+// sortedAddresses returns the addresses touched by the diff in ascending order.
+func (diff StateDiff) sortedAddresses() []common.Address {
+	addrs := make([]common.Address, 0, len(diff))
+	for addr := range diff {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+// ApplyDiff writes all changes contained in diff into the state, visiting the
+// accounts in address order. The whole diff is checked before anything is
+// modified, so an invalid diff leaves the state untouched. The changes are
+// journaled as usual and can be reverted through a prior snapshot.
+func (s *StateDB) ApplyDiff(diff StateDiff) error {
+	addrs := diff.sortedAddresses()
+	for _, addr := range addrs {
+		account := diff[addr]
+		if account == nil {
+			return fmt.Errorf("account %x: missing diff", addr)
+		}
+		if account.Balance != nil && account.Balance.Sign() < 0 {
+			return fmt.Errorf("account %x: negative balance %v", addr, account.Balance)
+		}
+	}
+	for _, addr := range addrs {
+		account := diff[addr]
+		if account.Balance != nil {
+			s.SetBalance(addr, account.Balance)
+		}
+		if account.Nonce != nil {
+			s.SetNonce(addr, *account.Nonce)
+		}
+		if account.Code != nil {
+			s.SetCode(addr, account.Code)
+		}
+		// Apply the storage slots in a deterministic order as well
+		keys := make([]common.Hash, 0, len(account.Storage))
+		for key := range account.Storage {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return bytes.Compare(keys[i][:], keys[j][:]) < 0
+		})
+		for _, key := range keys {
+			s.SetState(addr, key, account.Storage[key])
+		}
+	}
+	return nil
+}
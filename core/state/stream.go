@@ -0,0 +1,156 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// dirtyStateVersion is the version of the dirty state encoding produced by
+// WriteTo, bumped on every incompatible change.
+const dirtyStateVersion = 1
+
+// dirtyState is the RLP encoding of the state changes of a StateDB.
+type dirtyState struct {
+	Version  uint
+	Accounts []dirtyAccount // Sorted by address
+}
+
+// dirtyAccount is a single modified account in the dirty state encoding.
+type dirtyAccount struct {
+	Address    common.Address
+	Destructed bool // Whether the account was destructed before its current incarnation
+	Deleted    bool // Whether the account doesn't exist anymore, in which case the fields below are unset
+	Nonce      uint64
+	Balance    *big.Int
+	Code       []byte         // Deployed code, empty if unmodified
+	Storage    []dirtyStorage // Sorted by key
+}
+
+// dirtyStorage is a single modified storage slot in the dirty state encoding.
+type dirtyStorage struct {
+	Key   common.Hash
+	Value common.Hash
+}
+
+// WriteTo writes the state changes made since the StateDB was opened to w in a
+// deterministic encoding, which can be replayed into another StateDB at the same
+// state root via ApplyFrom. The accounts are written in ascending address order,
+// each with its storage slots in ascending key order.
+//
+// The storage changes are only retained until the state is hashed, so WriteTo
+// must be called before IntermediateRoot or Commit.
+func (s *StateDB) WriteTo(w io.Writer) (int64, error) {
+	dirties := make(map[common.Address]struct{}, len(s.stateObjectsDirty)+len(s.journal.dirties))
+	for addr := range s.stateObjectsDirty {
+		dirties[addr] = struct{}{}
+	}
+	for addr := range s.journal.dirties {
+		dirties[addr] = struct{}{}
+	}
+	addrs := make([]common.Address, 0, len(dirties))
+	for addr := range dirties {
+		// Accounts only touched by a reverted change may not be loaded, see
+		// the ripeMD exception in Finalise
+		if _, ok := s.stateObjects[addr]; ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	state := dirtyState{
+		Version:  dirtyStateVersion,
+		Accounts: make([]dirtyAccount, len(addrs)),
+	}
+	for i, addr := range addrs {
+		obj := s.stateObjects[addr]
+		_, destructed := s.stateObjectsDestruct[addr]
+
+		account := dirtyAccount{
+			Address:    addr,
+			Destructed: destructed,
+			Deleted:    obj.deleted || obj.suicided,
+		}
+		if !account.Deleted {
+			account.Nonce = obj.data.Nonce
+			account.Balance = obj.data.Balance
+			if obj.dirtyCode {
+				account.Code = obj.code
+			}
+			// Merge the slots of the finalised and the running transactions,
+			// the latter taking precedence
+			slots := obj.pendingStorage.Copy()
+			for key, value := range obj.dirtyStorage {
+				slots[key] = value
+			}
+			account.Storage = make([]dirtyStorage, 0, len(slots))
+			for key, value := range slots {
+				account.Storage = append(account.Storage, dirtyStorage{Key: key, Value: value})
+			}
+			sort.Slice(account.Storage, func(i, j int) bool {
+				return bytes.Compare(account.Storage[i].Key[:], account.Storage[j].Key[:]) < 0
+			})
+		}
+		state.Accounts[i] = account
+	}
+	blob, err := rlp.EncodeToBytes(&state)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(blob)
+	return int64(n), err
+}
+
+// ApplyFrom reads state changes written by WriteTo from r and applies them to
+// the StateDB, which is expected to be at the state root the changes were made
+// on. The changes are applied like regular modifications, so they need to be
+// finalised before being committed.
+func (s *StateDB) ApplyFrom(r io.Reader) error {
+	var state dirtyState
+	if err := rlp.Decode(r, &state); err != nil {
+		return err
+	}
+	if state.Version != dirtyStateVersion {
+		return fmt.Errorf("unsupported dirty state version %d", state.Version)
+	}
+	for _, account := range state.Accounts {
+		if account.Deleted {
+			s.Suicide(account.Address)
+			continue
+		}
+		if account.Destructed {
+			s.CreateAccount(account.Address)
+		}
+		s.SetNonce(account.Address, account.Nonce)
+		s.SetBalance(account.Address, account.Balance)
+		if len(account.Code) > 0 {
+			s.SetCode(account.Address, account.Code)
+		}
+		for _, slot := range account.Storage {
+			s.SetState(account.Address, slot.Key, slot.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestWriteToApplyFrom(t *testing.T) {
+	// Create a base state shared by both ends of the transfer
+	db := NewDatabase(rawdb.NewMemoryDatabase())
+	base := NewEmptyStateDB(db)
+	for i := byte(0); i < 4; i++ {
+		addr := common.Address{i}
+		base.SetBalance(addr, big.NewInt(int64(i)+1))
+		base.SetState(addr, common.Hash{0x01}, common.Hash{i + 1})
+	}
+	root, err := base.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit base state: %v", err)
+	}
+	// Modify the state over two transactions
+	src, _ := New(root, db, nil)
+	src.AddBalance(common.Address{0}, big.NewInt(100))
+	src.SetState(common.Address{1}, common.Hash{0x01}, common.Hash{})
+	src.SetState(common.Address{1}, common.Hash{0x02}, common.Hash{0xff})
+	src.Suicide(common.Address{2})
+	src.Finalise(true)
+
+	src.SetNonce(common.Address{0xaa}, 1)
+	src.SetCode(common.Address{0xaa}, []byte{0x60, 0x00})
+	src.SetState(common.Address{0xaa}, common.Hash{0x03}, common.Hash{0x04})
+	src.CreateAccount(common.Address{3})
+	src.SetState(common.Address{3}, common.Hash{0x05}, common.Hash{0x06})
+
+	var stream bytes.Buffer
+	n, err := src.WriteTo(&stream)
+	if err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	if n != int64(stream.Len()) {
+		t.Errorf("written size mismatch: have %d, want %d", n, stream.Len())
+	}
+	// The encoding must be deterministic
+	var again bytes.Buffer
+	src.WriteTo(&again)
+	if !bytes.Equal(stream.Bytes(), again.Bytes()) {
+		t.Fatal("state encoding is not deterministic")
+	}
+	// Replaying the changes must yield the same state
+	dst, _ := New(root, db, nil)
+	if err := dst.ApplyFrom(&stream); err != nil {
+		t.Fatalf("failed to apply state: %v", err)
+	}
+	if have, want := dst.IntermediateRoot(true), src.IntermediateRoot(true); have != want {
+		t.Fatalf("state root mismatch: have %x, want %x", have, want)
+	}
+}
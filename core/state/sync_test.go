@@ -43,7 +43,7 @@ func makeTestState() (ethdb.Database, Database, common.Hash, []*testAccount) {
 	// Create an empty state
 	db := rawdb.NewMemoryDatabase()
 	sdb := NewDatabase(db)
-	state, _ := New(common.Hash{}, sdb, nil)
+	state := NewEmptyStateDB(sdb)
 
 	// Fill it with some arbitrary data
 	var accounts []*testAccount
@@ -70,7 +70,7 @@ func makeTestState() (ethdb.Database, Database, common.Hash, []*testAccount) {
 		state.updateStateObject(obj)
 		accounts = append(accounts, acc)
 	}
-	root, _ := state.Commit(false)
+	root := state.MustCommit(false)
 
 	// Return the generated state
 	return db, sdb, root, accounts
@@ -57,6 +57,32 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	return p.process(block, statedb, cfg)
+}
+
+// ProcessWithAccessWitness processes the block just like Process. If witness
+// collection is enabled in cfg, it additionally returns the accounts and storage
+// slots accessed while processing the block, including those of reverted calls
+// and the ones touched by the block finalization, like the coinbase and uncle
+// rewards and the withdrawals. Otherwise the returned witness is nil.
+func (p *StateProcessor) ProcessWithAccessWitness(block *types.Block, statedb *state.StateDB, cfg vm.Config) (receipts types.Receipts, logs []*types.Log, gasUsed uint64, witness *state.AccessWitness, err error) {
+	if cfg.WitnessCollection {
+		witness = state.NewAccessWitness()
+		statedb.SetAccessWitness(witness)
+		defer statedb.SetAccessWitness(nil)
+	}
+	receipts, logs, gasUsed, err = p.process(block, statedb, cfg)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return receipts, logs, gasUsed, witness, nil
+}
+
+// process implements Process and ProcessWithAccessWitness.
+func (p *StateProcessor) process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	// The return data cap is not part of the consensus rules
+	cfg.MaxReturnDataSize = 0
+
 	var (
 		receipts    types.Receipts
 		usedGas     = new(uint64)
@@ -75,8 +101,13 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		vmenv   = vm.NewEVM(context, vm.TxContext{}, statedb, p.config, cfg)
 		signer  = types.MakeSigner(p.config, header.Number, header.Time)
 	)
+	// Warm up the accounts touched by the transactions before executing them
+	statedb.Preload(touchedAccounts(block, signer))
+
 	// Iterate over and process the individual transactions
-	for i, tx := range block.Transactions() {
+	for it := block.IterateTransactions(); it.Next(); {
+		i, tx := it.Index(), it.Transaction()
+
 		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
@@ -103,6 +134,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
+	evm.FilterTrace(tx)
 	evm.Reset(txContext, statedb)
 
 	// Apply the transaction to the current state (included in the env).
@@ -145,6 +177,24 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	return receipt, err
 }
 
+// touchedAccounts returns the fee recipient along with the senders and the
+// recipients of all transactions in the block. Transactions with an invalid
+// signature are skipped, they will fail during processing anyway.
+func touchedAccounts(block *types.Block, signer types.Signer) []common.Address {
+	addrs := make([]common.Address, 0, 2*len(block.Transactions())+1)
+	addrs = append(addrs, block.Coinbase())
+	for it := block.IterateTransactions(); it.Next(); {
+		tx := it.Transaction()
+		if from, err := types.Sender(signer, tx); err == nil {
+			addrs = append(addrs, from)
+		}
+		if to := tx.To(); to != nil {
+			addrs = append(addrs, *to)
+		}
+	}
+	return addrs
+}
+
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
@@ -154,7 +204,9 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	if err != nil {
 		return nil, err
 	}
-	// Create a new context to be used in the EVM environment
+	// Create a new context to be used in the EVM environment. The return data
+	// cap is not part of the consensus rules.
+	cfg.MaxReturnDataSize = 0
 	blockContext := NewEVMBlockContext(header, bc, author)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, cfg)
 	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -428,3 +429,52 @@ func GenerateBadBlock(parent *types.Block, engine consensus.Engine, txs types.Tr
 	}
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
 }
+
+// Tests that converting a transaction into a message retains all the fields of
+// the newer transaction types.
+func TestTransactionToMessage(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.NewCancunSigner(big.NewInt(1))
+		to      = common.Address{0xaa}
+		baseFee = big.NewInt(10)
+	)
+	tx, err := types.SignNewTx(key, signer, &types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      3,
+		GasTipCap:  uint256.NewInt(2),
+		GasFeeCap:  uint256.NewInt(20),
+		Gas:        21000,
+		To:         &to,
+		Value:      uint256.NewInt(1),
+		AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}},
+		BlobFeeCap: uint256.NewInt(5),
+		BlobHashes: []common.Hash{{0x01}, {0x02}},
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	msg, err := TransactionToMessage(tx, signer, baseFee)
+	if err != nil {
+		t.Fatalf("failed to convert transaction: %v", err)
+	}
+	if msg.From != sender {
+		t.Errorf("sender mismatch: have %x, want %x", msg.From, sender)
+	}
+	if msg.GasFeeCap.Cmp(big.NewInt(20)) != 0 || msg.GasTipCap.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("fee caps mismatch: have %v/%v, want 20/2", msg.GasFeeCap, msg.GasTipCap)
+	}
+	if msg.GasPrice.Cmp(big.NewInt(12)) != 0 {
+		t.Errorf("effective gas price mismatch: have %v, want 12", msg.GasPrice)
+	}
+	if len(msg.AccessList) != 1 || msg.AccessList[0].Address != to {
+		t.Errorf("access list mismatch: have %v", msg.AccessList)
+	}
+	if msg.BlobGasFeeCap == nil || msg.BlobGasFeeCap.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("blob fee cap mismatch: have %v, want 5", msg.BlobGasFeeCap)
+	}
+	if len(msg.BlobHashes) != 2 || msg.BlobHashes[1] != (common.Hash{0x02}) {
+		t.Errorf("blob hashes mismatch: have %v", msg.BlobHashes)
+	}
+}
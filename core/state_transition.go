@@ -63,15 +63,29 @@ func (result *ExecutionResult) Revert() []byte {
 	return common.CopyBytes(result.ReturnData)
 }
 
-// IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool, isEIP3860 bool) (uint64, error) {
+// IntrinsicGasBreakdown is the intrinsic gas of a message split up by the
+// components it is charged for.
+type IntrinsicGasBreakdown struct {
+	Base                uint64 `json:"base"`                // Flat cost of a transaction or contract creation
+	ZeroData            uint64 `json:"zeroData"`            // Cost of the zero data bytes
+	NonZeroData         uint64 `json:"nonZeroData"`         // Cost of the non-zero data bytes
+	InitCode            uint64 `json:"initCode"`            // Cost of the init code words (EIP-3860)
+	AccessListAddresses uint64 `json:"accessListAddresses"` // Cost of the access list addresses
+	AccessListSlots     uint64 `json:"accessListSlots"`     // Cost of the access list storage keys
+}
+
+// IntrinsicGas computes the 'intrinsic gas' for a message with the given data,
+// along with its breakdown by component.
+func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool, isEIP3860 bool) (uint64, IntrinsicGasBreakdown, error) {
+	var breakdown IntrinsicGasBreakdown
+
 	// Set the starting gas for the raw transaction
-	var gas uint64
 	if isContractCreation && isHomestead {
-		gas = params.TxGasContractCreation
+		breakdown.Base = params.TxGasContractCreation
 	} else {
-		gas = params.TxGas
+		breakdown.Base = params.TxGas
 	}
+	gas := breakdown.Base
 	dataLen := uint64(len(data))
 	// Bump the required gas by the amount of transactional data
 	if dataLen > 0 {
@@ -88,29 +102,33 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 			nonZeroGas = params.TxDataNonZeroGasEIP2028
 		}
 		if (math.MaxUint64-gas)/nonZeroGas < nz {
-			return 0, ErrGasUintOverflow
+			return 0, IntrinsicGasBreakdown{}, ErrGasUintOverflow
 		}
-		gas += nz * nonZeroGas
+		breakdown.NonZeroData = nz * nonZeroGas
+		gas += breakdown.NonZeroData
 
 		z := dataLen - nz
 		if (math.MaxUint64-gas)/params.TxDataZeroGas < z {
-			return 0, ErrGasUintOverflow
+			return 0, IntrinsicGasBreakdown{}, ErrGasUintOverflow
 		}
-		gas += z * params.TxDataZeroGas
+		breakdown.ZeroData = z * params.TxDataZeroGas
+		gas += breakdown.ZeroData
 
 		if isContractCreation && isEIP3860 {
 			lenWords := toWordSize(dataLen)
 			if (math.MaxUint64-gas)/params.InitCodeWordGas < lenWords {
-				return 0, ErrGasUintOverflow
+				return 0, IntrinsicGasBreakdown{}, ErrGasUintOverflow
 			}
-			gas += lenWords * params.InitCodeWordGas
+			breakdown.InitCode = lenWords * params.InitCodeWordGas
+			gas += breakdown.InitCode
 		}
 	}
 	if accessList != nil {
-		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
-		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+		breakdown.AccessListAddresses = uint64(len(accessList)) * params.TxAccessListAddressGas
+		breakdown.AccessListSlots = uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+		gas += breakdown.AccessListAddresses + breakdown.AccessListSlots
 	}
-	return gas, nil
+	return gas, breakdown, nil
 }
 
 // toWordSize returns the ceiled word size required for init code payment calculation.
@@ -136,6 +154,9 @@ type Message struct {
 	Data       []byte
 	AccessList types.AccessList
 
+	BlobGasFeeCap *big.Int
+	BlobHashes    []common.Hash
+
 	// When SkipAccountChecks is true, the message nonce is not checked against the
 	// account nonce in state. It also disables checking that the sender is an EOA.
 	// This field will be set to true for operations like RPC eth_call.
@@ -154,8 +175,12 @@ func TransactionToMessage(tx *types.Transaction, s types.Signer, baseFee *big.In
 		Value:             tx.Value(),
 		Data:              tx.Data(),
 		AccessList:        tx.AccessList(),
+		BlobHashes:        tx.BlobHashes(),
 		SkipAccountChecks: false,
 	}
+	if blobFeeCap := tx.BlobGasFeeCap(); blobFeeCap != nil {
+		msg.BlobGasFeeCap = new(big.Int).Set(blobFeeCap)
+	}
 	// If baseFee provided, set gasPrice to effectiveGasPrice.
 	if baseFee != nil {
 		msg.GasPrice = cmath.BigMin(msg.GasPrice.Add(msg.GasTipCap, baseFee), msg.GasFeeCap)
@@ -339,7 +364,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	)
 
 	// Check clauses 4-5, subtract intrinsic gas if everything is correct
-	gas, err := IntrinsicGas(msg.Data, msg.AccessList, contractCreation, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
+	gas, _, err := IntrinsicGas(msg.Data, msg.AccessList, contractCreation, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
 	if err != nil {
 		return nil, err
 	}
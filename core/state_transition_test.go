@@ -0,0 +1,56 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the intrinsic gas breakdown accounts for every component and adds
+// up to the total.
+func TestIntrinsicGasBreakdown(t *testing.T) {
+	var (
+		data       = []byte{0, 0, 1, 2, 0, 3}
+		accessList = types.AccessList{
+			{Address: common.Address{1}, StorageKeys: []common.Hash{{1}, {2}}},
+			{Address: common.Address{2}},
+		}
+	)
+	gas, breakdown, err := IntrinsicGas(data, accessList, true, true, true, true)
+	if err != nil {
+		t.Fatalf("failed to compute intrinsic gas: %v", err)
+	}
+	want := IntrinsicGasBreakdown{
+		Base:                params.TxGasContractCreation,
+		ZeroData:            3 * params.TxDataZeroGas,
+		NonZeroData:         3 * params.TxDataNonZeroGasEIP2028,
+		InitCode:            params.InitCodeWordGas,
+		AccessListAddresses: 2 * params.TxAccessListAddressGas,
+		AccessListSlots:     2 * params.TxAccessListStorageKeyGas,
+	}
+	if breakdown != want {
+		t.Fatalf("breakdown mismatch: have %+v, want %+v", breakdown, want)
+	}
+	total := want.Base + want.ZeroData + want.NonZeroData + want.InitCode + want.AccessListAddresses + want.AccessListSlots
+	if gas != total {
+		t.Fatalf("total mismatch: have %d, want %d", gas, total)
+	}
+}
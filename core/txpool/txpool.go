@@ -81,7 +81,7 @@ var (
 
 	// ErrNegativeValue is a sanity error to ensure no one is able to specify a
 	// transaction with a negative value.
-	ErrNegativeValue = errors.New("negative value")
+	ErrNegativeValue = types.ErrNegativeValue
 
 	// ErrOversizedData is returned if the input data of a transaction is greater
 	// than some meaningful limit a user might use. This is not a consensus error
@@ -521,6 +521,25 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
+// InspectSorted retrieves a snapshot of the pending and queued transactions of
+// the pool, grouped by account and sorted ascending by nonce. The nonce ordering
+// is taken from the account lists' cached sort, so callers don't need to sort
+// the returned slices themselves.
+func (pool *TxPool) InspectSorted() (pending, queued map[common.Address][]*types.Transaction) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pending = make(map[common.Address][]*types.Transaction, len(pool.pending))
+	for addr, list := range pool.pending {
+		pending[addr] = list.Flatten()
+	}
+	queued = make(map[common.Address][]*types.Transaction, len(pool.queue))
+	for addr, list := range pool.queue {
+		queued[addr] = list.Flatten()
+	}
+	return pending, queued
+}
+
 // ContentFrom retrieves the data content of the transaction pool, returning the
 // pending as well as queued transactions of this address, grouped by nonce.
 func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
@@ -599,15 +618,15 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // and does not require the pool mutex to be held.
 func (pool *TxPool) validateTxBasics(tx *types.Transaction, local bool) error {
 	// Accept only legacy transactions until EIP-2718/2930 activates.
-	if !pool.eip2718.Load() && tx.Type() != types.LegacyTxType {
+	if !pool.eip2718.Load() && !tx.IsLegacy() {
 		return core.ErrTxTypeNotSupported
 	}
 	// Reject dynamic fee transactions until EIP-1559 activates.
-	if !pool.eip1559.Load() && tx.Type() == types.DynamicFeeTxType {
+	if !pool.eip1559.Load() && tx.IsDynamicFee() {
 		return core.ErrTxTypeNotSupported
 	}
 	// Reject blob transactions forever, those will have their own pool.
-	if tx.Type() == types.BlobTxType {
+	if tx.IsBlob() {
 		return core.ErrTxTypeNotSupported
 	}
 	// Reject transactions over defined size to prevent DOS attacks
@@ -647,7 +666,7 @@ func (pool *TxPool) validateTxBasics(tx *types.Transaction, local bool) error {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul.Load(), pool.shanghai.Load())
+	intrGas, _, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul.Load(), pool.shanghai.Load())
 	if err != nil {
 		return err
 	}
@@ -951,7 +970,9 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 // senders are not among the locally tracked ones, full pricing constraints will apply.
 //
 // This method is used to add transactions from the p2p network and does not wait for pool
-// reorganization and internal event propagation.
+// reorganization and internal event propagation. The validation itself is synchronous:
+// the returned slice holds the outcome of every transaction, nil if it was accepted, and
+// the pool lock is acquired once for the whole batch.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
 	return pool.addTxs(txs, false, false)
 }
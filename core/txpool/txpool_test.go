@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"sort"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -126,7 +127,7 @@ func setupPool() (*TxPool, *ecdsa.PrivateKey) {
 }
 
 func setupPoolWithConfig(config *params.ChainConfig) (*TxPool, *ecdsa.PrivateKey) {
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(10000000, statedb, new(event.Feed))
 
 	key, _ := crypto.GenerateKey()
@@ -218,7 +219,7 @@ func (c *testChain) State() (*state.StateDB, error) {
 	// a state change between those fetches.
 	stdb := c.statedb
 	if *c.trigger {
-		c.statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		c.statedb = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 		// simulate that the new head block included tx0 and tx1
 		c.statedb.SetNonce(c.address, 2)
 		c.statedb.SetBalance(c.address, new(big.Int).SetUint64(params.Ether))
@@ -234,10 +235,10 @@ func TestStateChangeDuringReset(t *testing.T) {
 	t.Parallel()
 
 	var (
-		key, _     = crypto.GenerateKey()
-		address    = crypto.PubkeyToAddress(key.PublicKey)
-		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
-		trigger    = false
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		statedb = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		trigger = false
 	)
 
 	// setup pool with 2 transaction in it
@@ -434,7 +435,7 @@ func TestChainFork(t *testing.T) {
 
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	resetState := func() {
-		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 		statedb.AddBalance(addr, big.NewInt(100000000000000))
 
 		pool.chain = newTestBlockChain(1000000, statedb, new(event.Feed))
@@ -463,7 +464,7 @@ func TestDoubleNonce(t *testing.T) {
 
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	resetState := func() {
-		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 		statedb.AddBalance(addr, big.NewInt(100000000000000))
 
 		pool.chain = newTestBlockChain(1000000, statedb, new(event.Feed))
@@ -663,7 +664,7 @@ func TestPostponing(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the postponing with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
@@ -875,7 +876,7 @@ func testQueueGlobalLimiting(t *testing.T, nolocals bool) {
 	t.Parallel()
 
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -967,7 +968,7 @@ func testQueueTimeLimiting(t *testing.T, nolocals bool) {
 	evictionInterval = time.Millisecond * 100
 
 	// Create the pool to test the non-expiration enforcement
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -1152,7 +1153,7 @@ func TestPendingGlobalLimiting(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -1254,7 +1255,7 @@ func TestCapClearsFromAll(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -1288,7 +1289,7 @@ func TestPendingMinimumAllowance(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -1336,7 +1337,7 @@ func TestRepricing(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
@@ -1584,7 +1585,7 @@ func TestRepricingKeepsLocals(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	pool := NewTxPool(testTxPoolConfig, eip1559Config, blockchain)
@@ -1657,7 +1658,7 @@ func TestUnderpricing(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -1771,7 +1772,7 @@ func TestStableUnderpricing(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -2003,7 +2004,7 @@ func TestDeduplication(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
@@ -2069,7 +2070,7 @@ func TestReplacement(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
@@ -2274,7 +2275,7 @@ func testJournaling(t *testing.T, nolocals bool) {
 	os.Remove(journal)
 
 	// Create the original pool to inject transaction into the journal
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	config := testTxPoolConfig
@@ -2372,7 +2373,7 @@ func TestStatusCheck(t *testing.T) {
 	t.Parallel()
 
 	// Create the pool to test the status retrievals with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
 
 	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
@@ -2440,6 +2441,38 @@ func TestSlotCount(t *testing.T) {
 	}
 }
 
+// Tests that the pool snapshot returns the transactions of each account sorted
+// by nonce, regardless of the order they were added in.
+func TestInspectSorted(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, account, big.NewInt(1000000))
+
+	for _, nonce := range []uint64{2, 0, 5, 1, 4} {
+		if err := pool.AddRemotesSync([]*types.Transaction{transaction(nonce, 100000, key)})[0]; err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	pending, queued := pool.InspectSorted()
+
+	check := func(kind string, txs []*types.Transaction, nonces []uint64) {
+		if len(txs) != len(nonces) {
+			t.Fatalf("%s transaction count mismatch: have %d, want %d", kind, len(txs), len(nonces))
+		}
+		for i, tx := range txs {
+			if tx.Nonce() != nonces[i] {
+				t.Errorf("%s transaction %d: nonce mismatch: have %d, want %d", kind, i, tx.Nonce(), nonces[i])
+			}
+		}
+	}
+	check("pending", pending[account], []uint64{0, 1, 2})
+	check("queued", queued[account], []uint64{4, 5})
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
@@ -2580,3 +2613,43 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 		pool.AddRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// Benchmarks retrieving the pool contents sorted by nonce, comparing a snapshot
+// of the unordered transaction lookup sorted by the caller to InspectSorted.
+func BenchmarkInspectSorted(b *testing.B) {
+	pool, _ := setupPool()
+	defer pool.Stop()
+
+	// Fill the pool with 10000 transactions from 100 accounts
+	for i := 0; i < 100; i++ {
+		key, _ := crypto.GenerateKey()
+		account := crypto.PubkeyToAddress(key.PublicKey)
+		testAddBalance(pool, account, big.NewInt(1000000000))
+
+		for j := 0; j < 100; j++ {
+			tx := transaction(uint64(j), 100000, key)
+			pool.all.Add(tx, false)
+			pool.promoteTx(account, tx.Hash(), tx)
+		}
+	}
+	b.Run("unordered+sort", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			txs := make(map[common.Address][]*types.Transaction)
+			pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+				from, _ := types.Sender(pool.signer, tx)
+				txs[from] = append(txs[from], tx)
+				return true
+			}, true, true)
+			for _, list := range txs {
+				sort.Slice(list, func(i, j int) bool { return list[i].Nonce() < list[j].Nonce() })
+			}
+		}
+	})
+	b.Run("sorted", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			pool.InspectSorted()
+		}
+	})
+}
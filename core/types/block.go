@@ -28,6 +28,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -125,25 +126,43 @@ func (h *Header) Size() common.StorageSize {
 	return headerSize + common.StorageSize(len(h.Extra)+(h.Difficulty.BitLen()+h.Number.BitLen()+baseFeeBits)/8)
 }
 
+// BlockSanityError is returned by the sanity checks when a block field holds a
+// value no valid block can have.
+type BlockSanityError struct {
+	Field  string      // Name of the offending field
+	Value  interface{} // Offending value, or its bit length/size for unbounded fields
+	Reason string
+}
+
+func (e *BlockSanityError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Reason, e.Field, e.Value)
+}
+
 // SanityCheck checks a few basic things -- these checks are way beyond what
 // any 'sane' production values should hold, and can mainly be used to prevent
 // that the unbounded fields are stuffed with junk data to add processing
 // overhead
 func (h *Header) SanityCheck() error {
 	if h.Number != nil && !h.Number.IsUint64() {
-		return fmt.Errorf("too large block number: bitlen %d", h.Number.BitLen())
+		return &BlockSanityError{Field: "block number", Value: h.Number.BitLen(), Reason: "too large"}
 	}
 	if h.Difficulty != nil {
+		if h.Difficulty.Sign() < 0 {
+			return &BlockSanityError{Field: "block difficulty", Value: h.Difficulty, Reason: "negative"}
+		}
 		if diffLen := h.Difficulty.BitLen(); diffLen > 80 {
-			return fmt.Errorf("too large block difficulty: bitlen %d", diffLen)
+			return &BlockSanityError{Field: "block difficulty", Value: diffLen, Reason: "too large"}
 		}
 	}
+	if h.GasLimit > params.MaxGasLimit {
+		return &BlockSanityError{Field: "gas limit", Value: h.GasLimit, Reason: "too large"}
+	}
 	if eLen := len(h.Extra); eLen > 100*1024 {
-		return fmt.Errorf("too large block extradata: size %d", eLen)
+		return &BlockSanityError{Field: "block extradata", Value: eLen, Reason: "too large"}
 	}
 	if h.BaseFee != nil {
 		if bfLen := h.BaseFee.BitLen(); bfLen > 256 {
-			return fmt.Errorf("too large base fee: bitlen %d", bfLen)
+			return &BlockSanityError{Field: "base fee", Value: bfLen, Reason: "too large"}
 		}
 	}
 	return nil
@@ -163,6 +182,15 @@ func (h *Header) EmptyReceipts() bool {
 	return h.ReceiptHash == EmptyReceiptsHash
 }
 
+// WithExtra returns a copy of the header with the extra data replaced by a copy
+// of extra. The header itself is left untouched, so blocks built from it keep
+// their cached hash.
+func (h *Header) WithExtra(extra []byte) *Header {
+	cpy := CopyHeader(h)
+	cpy.Extra = common.CopyBytes(extra)
+	return cpy
+}
+
 // Body is a simple (mutable, non-safe) data container for storing and moving
 // a block's data contents (transactions and uncles) together.
 type Body struct {
@@ -315,6 +343,45 @@ func (b *Block) EncodeRLP(w io.Writer) error {
 func (b *Block) Uncles() []*Header          { return b.uncles }
 func (b *Block) Transactions() Transactions { return b.transactions }
 
+// TransactionsCopy returns a copy of the transaction list of the block, which
+// the caller may modify without affecting the block.
+func (b *Block) TransactionsCopy() Transactions {
+	return append(Transactions(nil), b.transactions...)
+}
+
+// IterateTransactions returns an iterator over the transactions of the block.
+func (b *Block) IterateTransactions() *TransactionIterator {
+	return &TransactionIterator{txs: b.transactions, index: -1}
+}
+
+// TransactionIterator iterates over the transactions of a block in order,
+// without exposing the underlying transaction list.
+type TransactionIterator struct {
+	txs   Transactions
+	index int
+}
+
+// Next moves the iterator to the next transaction, returning whether there was
+// one. It must be called before the first transaction is accessed.
+func (it *TransactionIterator) Next() bool {
+	if it.index+1 >= len(it.txs) {
+		it.index = len(it.txs)
+		return false
+	}
+	it.index++
+	return true
+}
+
+// Transaction returns the transaction the iterator is currently at.
+func (it *TransactionIterator) Transaction() *Transaction {
+	return it.txs[it.index]
+}
+
+// Index returns the position of the current transaction within the block.
+func (it *TransactionIterator) Index() int {
+	return it.index
+}
+
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
 		if transaction.Hash() == hash {
@@ -371,9 +438,22 @@ func (b *Block) Size() uint64 {
 }
 
 // SanityCheck can be used to prevent that unbounded fields are
-// stuffed with junk data to add processing overhead
+// stuffed with junk data to add processing overhead. Besides the header
+// checks, non-genesis blocks must have a timestamp and every transaction
+// must pass its own sanity check.
 func (b *Block) SanityCheck() error {
-	return b.header.SanityCheck()
+	if err := b.header.SanityCheck(); err != nil {
+		return err
+	}
+	if b.header.Number != nil && b.header.Number.Sign() > 0 && b.header.Time == 0 {
+		return &BlockSanityError{Field: "timestamp", Value: b.header.Time, Reason: "missing"}
+	}
+	for i, tx := range b.transactions {
+		if err := tx.SanityCheck(); err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return nil
 }
 
 type writeCounter uint64
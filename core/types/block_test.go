@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"errors"
 	"hash"
 	"math/big"
 	"reflect"
@@ -343,3 +344,98 @@ func TestRlpDecodeParentHash(t *testing.T) {
 		}
 	}
 }
+
+// Tests that block sanity checks reject malformed blocks with a structured error.
+func TestBlockSanityCheck(t *testing.T) {
+	valid := func() *Header {
+		return &Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), GasLimit: 8_000_000, Time: 1}
+	}
+	if err := NewBlockWithHeader(valid()).SanityCheck(); err != nil {
+		t.Fatalf("valid block rejected: %v", err)
+	}
+	tests := []struct {
+		header *Header
+		txs    []*Transaction
+		field  string
+	}{
+		{header: func() *Header { h := valid(); h.GasLimit = params.MaxGasLimit + 1; return h }(), field: "gas limit"},
+		{header: func() *Header { h := valid(); h.Time = 0; return h }(), field: "timestamp"},
+		{header: func() *Header { h := valid(); h.Difficulty = big.NewInt(-1); return h }(), field: "block difficulty"},
+		{header: valid(), txs: []*Transaction{NewTx(&LegacyTx{GasPrice: big.NewInt(1), Value: big.NewInt(-1)})}, field: "transaction value"},
+	}
+	for i, test := range tests {
+		err := NewBlockWithHeader(test.header).WithBody(test.txs, nil).SanityCheck()
+		var serr *BlockSanityError
+		if !errors.As(err, &serr) {
+			t.Fatalf("test %d: expected sanity error, got %v", i, err)
+		}
+		if serr.Field != test.field {
+			t.Errorf("test %d: field mismatch: have %q, want %q", i, serr.Field, test.field)
+		}
+	}
+	// The genesis block has no timestamp requirement
+	genesis := valid()
+	genesis.Number, genesis.Time = big.NewInt(0), 0
+	if err := NewBlockWithHeader(genesis).SanityCheck(); err != nil {
+		t.Fatalf("genesis block rejected: %v", err)
+	}
+}
+
+func TestHeaderWithExtra(t *testing.T) {
+	header := &Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Extra: []byte("original")}
+	block := NewBlockWithHeader(header)
+	hash := block.Hash()
+
+	// Mutating the extra data returned by the block must not affect it
+	extra := block.Extra()
+	extra[0] = 'X'
+	if block.Hash() != hash || block.Header().Hash() != hash {
+		t.Fatal("block hash changed by mutating the returned extra data")
+	}
+	// Replacing the extra data yields a new header, leaving the old one intact
+	modified := block.Header().WithExtra([]byte("modified"))
+	if !bytes.Equal(header.Extra, []byte("original")) {
+		t.Fatalf("original header modified: %q", header.Extra)
+	}
+	if block.Hash() != hash || block.Header().Hash() != hash {
+		t.Fatal("block hash changed by WithExtra")
+	}
+	if modified.Hash() == hash {
+		t.Fatal("header hash unchanged by new extra data")
+	}
+	if have, want := NewBlockWithHeader(modified).Hash(), modified.Hash(); have != want {
+		t.Fatalf("block hash mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestTransactionIterator(t *testing.T) {
+	txs := Transactions{
+		NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil),
+		NewTransaction(1, common.Address{2}, big.NewInt(2), 21000, big.NewInt(1), nil),
+		NewTransaction(2, common.Address{3}, big.NewInt(3), 21000, big.NewInt(1), nil),
+	}
+	block := NewBlockWithHeader(&Header{Number: big.NewInt(1)}).WithBody(txs, nil)
+
+	var count int
+	for it := block.IterateTransactions(); it.Next(); count++ {
+		if it.Index() != count {
+			t.Fatalf("index mismatch: have %d, want %d", it.Index(), count)
+		}
+		if it.Transaction().Hash() != txs[count].Hash() {
+			t.Fatalf("transaction %d mismatch", count)
+		}
+	}
+	if count != len(txs) {
+		t.Fatalf("iterated transaction count mismatch: have %d, want %d", count, len(txs))
+	}
+	// An empty block yields nothing
+	if NewBlockWithHeader(&Header{Number: big.NewInt(1)}).IterateTransactions().Next() {
+		t.Fatal("iterator of empty block yielded a transaction")
+	}
+	// Modifying the copy must not affect the block
+	cpy := block.TransactionsCopy()
+	cpy[0] = nil
+	if block.Transactions()[0] == nil {
+		t.Fatal("block transactions modified through copy")
+	}
+}
@@ -90,6 +90,12 @@ func (b Bloom) Test(topic []byte) bool {
 		v3 == v3&b[i3]
 }
 
+// Contains reports whether d may have been added to the filter. False positives
+// are possible, but an added item is always reported.
+func (b Bloom) Contains(d []byte) bool {
+	return b.Test(d)
+}
+
 // MarshalText encodes b as a hex string with 0x prefix.
 func (b Bloom) MarshalText() ([]byte, error) {
 	return hexutil.Bytes(b[:]).MarshalText()
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/big"
 	"testing"
+	"testing/quick"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -76,6 +77,34 @@ func TestBloomExtensively(t *testing.T) {
 	}
 }
 
+// Tests that the filter contains every added item, and that it survives a text
+// encoding roundtrip.
+func TestBloomContains(t *testing.T) {
+	prop := func(items [][]byte) bool {
+		var bloom Bloom
+		for _, item := range items {
+			bloom.Add(item)
+		}
+		text, err := bloom.MarshalText()
+		if err != nil {
+			return false
+		}
+		var decoded Bloom
+		if err := decoded.UnmarshalText(text); err != nil || decoded != bloom {
+			return false
+		}
+		for _, item := range items {
+			if !decoded.Contains(item) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func BenchmarkBloom9(b *testing.B) {
 	test := []byte("testestestest")
 	for i := 0; i < b.N; i++ {
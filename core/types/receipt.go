@@ -48,6 +48,22 @@ const (
 	ReceiptStatusSuccessful = uint64(1)
 )
 
+// ReceiptStatus is the post-byzantium status code of a transaction.
+type ReceiptStatus uint64
+
+// String returns "success" or "failed" for the known status codes, and
+// "unknown" otherwise.
+func (s ReceiptStatus) String() string {
+	switch uint64(s) {
+	case ReceiptStatusSuccessful:
+		return "success"
+	case ReceiptStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
 // Receipt represents the results of a transaction.
 type Receipt struct {
 	// Consensus fields: These fields are defined by the Yellow Paper
@@ -236,6 +252,20 @@ func (r *Receipt) statusEncoding() []byte {
 	return r.PostState
 }
 
+// StatusString returns a human-readable representation of the receipt status:
+// "success" or "failed" for post-byzantium receipts, and the intermediate state
+// root for pre-byzantium ones.
+func (r *Receipt) StatusString() string {
+	switch {
+	case len(r.PostState) == common.HashLength:
+		return "pre-byzantium-root:" + common.BytesToHash(r.PostState).Hex()
+	case len(r.PostState) != 0:
+		return "unknown"
+	default:
+		return ReceiptStatus(r.Status).String()
+	}
+}
+
 // Size returns the approximate memory used by all internal contents. It is used
 // to approximate and limit the memory consumption of various caches.
 func (r *Receipt) Size() common.StorageSize {
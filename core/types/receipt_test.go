@@ -22,6 +22,7 @@ import (
 	"math"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -357,6 +358,77 @@ func TestEffectiveGasPriceNotRequired(t *testing.T) {
 	}
 }
 
+// Test that receipts as served by eth_getTransactionReceipt can be decoded for
+// every transaction type, including the pre-Byzantium form carrying a state
+// root instead of a status, and that unknown RPC-only fields are ignored.
+func TestReceiptUnmarshalJSONTypes(t *testing.T) {
+	bloom := `"0x` + strings.Repeat("00", BloomByteLength) + `"`
+	log := `{"address":"0x0000000000000000000000000000000000000011","topics":["0x000000000000000000000000000000000000000000000000000000000000dead"],"data":"0x0100ff","blockNumber":"0x1","transactionHash":"0x8e2d6bb4d0a5d3b2a9a9f3a5c7f4b0fd8fd5cbb4ce6b42f1f5c0e2b2a4c4e3d1","transactionIndex":"0x0","blockHash":"0x3b4bf0a1f2a8f1e3c4d5b6a7980a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2","logIndex":"0x0","removed":false}`
+	tests := []struct {
+		name   string
+		input  string
+		typ    uint8
+		root   []byte
+		status uint64
+		logs   int
+	}{
+		{
+			name:  "frontier",
+			input: `{"blockHash":"0x3b4bf0a1f2a8f1e3c4d5b6a7980a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2","blockNumber":"0xf4240","contractAddress":null,"cumulativeGasUsed":"0x5208","effectiveGasPrice":"0xba43b7400","from":"0x39fa8c5f2793459d6622857e7d9fbb4bd91766d3","gasUsed":"0x5208","logs":[],"logsBloom":` + bloom + `,"root":"0x0b3a37dc4b1f4c2c1d8d0e2a8a7a6c7f3e0f6b4c6b7a1e2d3c4b5a6978877665","to":"0xc083e9947cf02b8ffc7d3090ae9aea72df98fd47","transactionHash":"0x8e2d6bb4d0a5d3b2a9a9f3a5c7f4b0fd8fd5cbb4ce6b42f1f5c0e2b2a4c4e3d1","transactionIndex":"0x0"}`,
+			typ:   LegacyTxType,
+			root:  common.FromHex("0x0b3a37dc4b1f4c2c1d8d0e2a8a7a6c7f3e0f6b4c6b7a1e2d3c4b5a6978877665"),
+		},
+		{
+			name:   "legacy",
+			input:  `{"blockHash":"0x3b4bf0a1f2a8f1e3c4d5b6a7980a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2","blockNumber":"0x4c4b40","contractAddress":null,"cumulativeGasUsed":"0x1d4c0","effectiveGasPrice":"0x4a817c800","from":"0x39fa8c5f2793459d6622857e7d9fbb4bd91766d3","gasUsed":"0xb1a3","logs":[` + log + `],"logsBloom":` + bloom + `,"status":"0x1","to":"0xc083e9947cf02b8ffc7d3090ae9aea72df98fd47","transactionHash":"0x8e2d6bb4d0a5d3b2a9a9f3a5c7f4b0fd8fd5cbb4ce6b42f1f5c0e2b2a4c4e3d1","transactionIndex":"0x0","type":"0x0"}`,
+			typ:    LegacyTxType,
+			status: ReceiptStatusSuccessful,
+			logs:   1,
+		},
+		{
+			name:   "access list",
+			input:  `{"blockHash":"0x3b4bf0a1f2a8f1e3c4d5b6a7980a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2","blockNumber":"0xc5d488","contractAddress":null,"cumulativeGasUsed":"0x2dc6c0","effectiveGasPrice":"0x2540be400","from":"0x39fa8c5f2793459d6622857e7d9fbb4bd91766d3","gasUsed":"0x6a4e","logs":[],"logsBloom":` + bloom + `,"status":"0x0","to":"0xc083e9947cf02b8ffc7d3090ae9aea72df98fd47","transactionHash":"0x8e2d6bb4d0a5d3b2a9a9f3a5c7f4b0fd8fd5cbb4ce6b42f1f5c0e2b2a4c4e3d1","transactionIndex":"0x5","type":"0x1"}`,
+			typ:    AccessListTxType,
+			status: ReceiptStatusFailed,
+		},
+		{
+			name:   "dynamic fee",
+			input:  `{"blockHash":"0x3b4bf0a1f2a8f1e3c4d5b6a7980a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2","blockNumber":"0xd59f80","contractAddress":"0x5fbdb2315678afecb367f032d93f642f64180aa3","cumulativeGasUsed":"0x3d0900","effectiveGasPrice":"0x77359400","from":"0x39fa8c5f2793459d6622857e7d9fbb4bd91766d3","gasUsed":"0x1e8480","logs":[` + log + `,` + log + `],"logsBloom":` + bloom + `,"status":"0x1","to":null,"transactionHash":"0x8e2d6bb4d0a5d3b2a9a9f3a5c7f4b0fd8fd5cbb4ce6b42f1f5c0e2b2a4c4e3d1","transactionIndex":"0x9","type":"0x2"}`,
+			typ:    DynamicFeeTxType,
+			status: ReceiptStatusSuccessful,
+			logs:   2,
+		},
+		{
+			name:   "blob",
+			input:  `{"blobGasPrice":"0x1","blobGasUsed":"0x20000","blockHash":"0x3b4bf0a1f2a8f1e3c4d5b6a7980a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2","blockNumber":"0x12a05f2","contractAddress":null,"cumulativeGasUsed":"0x5208","effectiveGasPrice":"0x3b9aca00","from":"0x39fa8c5f2793459d6622857e7d9fbb4bd91766d3","gasUsed":"0x5208","logs":[],"logsBloom":` + bloom + `,"status":"0x1","to":"0xc083e9947cf02b8ffc7d3090ae9aea72df98fd47","transactionHash":"0x8e2d6bb4d0a5d3b2a9a9f3a5c7f4b0fd8fd5cbb4ce6b42f1f5c0e2b2a4c4e3d1","transactionIndex":"0x0","type":"0x3"}`,
+			typ:    BlobTxType,
+			status: ReceiptStatusSuccessful,
+		},
+	}
+	for _, test := range tests {
+		var r Receipt
+		if err := json.Unmarshal([]byte(test.input), &r); err != nil {
+			t.Errorf("%s: failed to unmarshal receipt: %v", test.name, err)
+			continue
+		}
+		if r.Type != test.typ {
+			t.Errorf("%s: type mismatch: have %d, want %d", test.name, r.Type, test.typ)
+		}
+		if !bytes.Equal(r.PostState, test.root) {
+			t.Errorf("%s: root mismatch: have %x, want %x", test.name, r.PostState, test.root)
+		}
+		if r.Status != test.status {
+			t.Errorf("%s: status mismatch: have %d, want %d", test.name, r.Status, test.status)
+		}
+		if len(r.Logs) != test.logs {
+			t.Errorf("%s: log count mismatch: have %d, want %d", test.name, len(r.Logs), test.logs)
+		}
+		if r.GasUsed == 0 || r.CumulativeGasUsed < r.GasUsed {
+			t.Errorf("%s: invalid gas: used %d, cumulative %d", test.name, r.GasUsed, r.CumulativeGasUsed)
+		}
+	}
+}
+
 // TestTypedReceiptEncodingDecoding reproduces a flaw that existed in the receipt
 // rlp decoder, which failed due to a shadowing error.
 func TestTypedReceiptEncodingDecoding(t *testing.T) {
@@ -517,3 +589,38 @@ func clearComputedFieldsOnLogs(logs []*Log) []*Log {
 	}
 	return l
 }
+
+func TestReceiptStatusString(t *testing.T) {
+	root := common.HexToHash("0x5e6f")
+	tests := []struct {
+		receipt *Receipt
+		want    string
+	}{
+		{&Receipt{Status: ReceiptStatusSuccessful}, "success"},
+		{&Receipt{Status: ReceiptStatusFailed}, "failed"},
+		{&Receipt{PostState: root.Bytes()}, "pre-byzantium-root:" + root.Hex()},
+		{&Receipt{PostState: []byte{0x01, 0x02}}, "unknown"},
+		{&Receipt{Status: 2}, "unknown"},
+	}
+	for i, tt := range tests {
+		if have := tt.receipt.StatusString(); have != tt.want {
+			t.Errorf("test %d: status string mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+func TestReceiptStatusValueString(t *testing.T) {
+	tests := []struct {
+		status ReceiptStatus
+		want   string
+	}{
+		{ReceiptStatus(ReceiptStatusSuccessful), "success"},
+		{ReceiptStatus(ReceiptStatusFailed), "failed"},
+		{ReceiptStatus(2), "unknown"},
+	}
+	for i, tt := range tests {
+		if have := tt.status.String(); have != tt.want {
+			t.Errorf("test %d: status string mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
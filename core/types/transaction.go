@@ -48,6 +48,47 @@ const (
 	BlobTxType       = 0x03
 )
 
+// Errors returned by transaction field validation. Those shared with the
+// transaction pool and state transition are aliased by the core packages, so
+// errors.Is matches across both validation paths.
+var (
+	ErrInvalidChainID = errors.New("invalid chain id")
+	ErrZeroGas        = errors.New("gas limit is zero")
+	ErrMissingFee     = errors.New("missing max fee or max priority fee")
+	ErrNegativeFee    = errors.New("negative max fee or max priority fee")
+	ErrNegativeValue  = errors.New("negative value")
+	ErrNonceMax       = errors.New("nonce has max value")
+	ErrFeeCapVeryHigh = errors.New("max fee per gas higher than 2^256-1")
+	ErrTipVeryHigh    = errors.New("max priority fee per gas higher than 2^256-1")
+	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
+)
+
+// TxValidationCode identifies the invariant violated by a transaction that
+// failed validation.
+type TxValidationCode int
+
+const (
+	TxInvalidChainID TxValidationCode = iota + 1
+	TxZeroGas
+	TxNonceOverflow
+	TxNegativeValue
+	TxMissingFee
+	TxNegativeFee
+	TxFeeCapVeryHigh
+	TxTipVeryHigh
+	TxTipAboveFeeCap
+)
+
+// TxValidationError is returned by transaction validation when a field
+// violates a pool invariant. It wraps one of the sentinel errors above.
+type TxValidationError struct {
+	Code TxValidationCode
+	Err  error
+}
+
+func (e *TxValidationError) Error() string { return e.Err.Error() }
+func (e *TxValidationError) Unwrap() error { return e.Err }
+
 // Transaction is an Ethereum transaction.
 type Transaction struct {
 	inner TxData    // Consensus contents of a transaction
@@ -90,6 +131,10 @@ type TxData interface {
 	rawSignatureValues() (v, r, s *big.Int)
 	setSignatureValues(chainID, v, r, s *big.Int)
 
+	// validate checks the fields against the transaction pool invariants,
+	// returning a *TxValidationError on failure.
+	validate(chainID *big.Int) error
+
 	// effectiveGasPrice computes the gas price paid by the transaction, given
 	// the inclusion block baseFee.
 	//
@@ -101,7 +146,7 @@ type TxData interface {
 
 // EncodeRLP implements rlp.Encoder
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	if tx.Type() == LegacyTxType {
+	if tx.IsLegacy() {
 		return rlp.Encode(w, tx.inner)
 	}
 	// It's an EIP-2718 typed TX envelope.
@@ -124,7 +169,7 @@ func (tx *Transaction) encodeTyped(w *bytes.Buffer) error {
 // For legacy transactions, it returns the RLP encoding. For EIP-2718 typed
 // transactions, it returns the type and payload.
 func (tx *Transaction) MarshalBinary() ([]byte, error) {
-	if tx.Type() == LegacyTxType {
+	if tx.IsLegacy() {
 		return rlp.EncodeToBytes(tx.inner)
 	}
 	var buf bytes.Buffer
@@ -264,6 +309,44 @@ func (tx *Transaction) Type() uint8 {
 	return tx.inner.txType()
 }
 
+// IsLegacy reports whether the transaction is a legacy transaction.
+func (tx *Transaction) IsLegacy() bool {
+	return tx.Type() == LegacyTxType
+}
+
+// IsAccessList reports whether the transaction is an EIP-2930 access list
+// transaction.
+func (tx *Transaction) IsAccessList() bool {
+	return tx.Type() == AccessListTxType
+}
+
+// IsDynamicFee reports whether the transaction is an EIP-1559 dynamic fee
+// transaction.
+func (tx *Transaction) IsDynamicFee() bool {
+	return tx.Type() == DynamicFeeTxType
+}
+
+// IsBlob reports whether the transaction is an EIP-4844 blob transaction.
+func (tx *Transaction) IsBlob() bool {
+	return tx.Type() == BlobTxType
+}
+
+// SupportsAccessList reports whether the transaction type carries an access
+// list, which is the case for all typed transactions.
+func (tx *Transaction) SupportsAccessList() bool {
+	switch tx.Type() {
+	case AccessListTxType, DynamicFeeTxType, BlobTxType:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsBlobGas reports whether the transaction type pays for blob gas.
+func (tx *Transaction) SupportsBlobGas() bool {
+	return tx.IsBlob()
+}
+
 // ChainId returns the EIP155 chain ID of the transaction. The return value will always be
 // non-nil. For legacy transactions which are not replay-protected, the return value is
 // zero.
@@ -298,6 +381,25 @@ func (tx *Transaction) BlobGasFeeCap() *big.Int { return tx.inner.blobGasFeeCap(
 // BlobHashes returns the hases of the blob commitments for blob transactions, nil otherwise.
 func (tx *Transaction) BlobHashes() []common.Hash { return tx.inner.blobHashes() }
 
+// BlobGasPrice returns the EIP-4844 price per data gas in a block with the given
+// excess data gas for blob transactions, nil otherwise.
+func (tx *Transaction) BlobGasPrice(excessBlobGas uint64) *big.Int {
+	if !tx.IsBlob() {
+		return nil
+	}
+	return calcBlobGasPrice(excessBlobGas)
+}
+
+// BlobGasCost returns the cost of the data gas used by the transaction in a block
+// with the given excess data gas for blob transactions, nil otherwise.
+func (tx *Transaction) BlobGasCost(excessBlobGas uint64) *big.Int {
+	price := tx.BlobGasPrice(excessBlobGas)
+	if price == nil {
+		return nil
+	}
+	return price.Mul(price, new(big.Int).SetUint64(tx.BlobGas()))
+}
+
 // Value returns the ether amount of the transaction.
 func (tx *Transaction) Value() *big.Int { return new(big.Int).Set(tx.inner.value()) }
 
@@ -313,13 +415,42 @@ func (tx *Transaction) To() *common.Address {
 // Cost returns (gas * gasPrice) + (blobGas * blobGasPrice) + value.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
-	if tx.Type() == BlobTxType {
+	if tx.IsBlob() {
 		total.Add(total, new(big.Int).Mul(tx.BlobGasFeeCap(), new(big.Int).SetUint64(tx.BlobGas())))
 	}
 	total.Add(total, tx.Value())
 	return total
 }
 
+// Validate checks the transaction fields against the invariants enforced by the
+// transaction pool. Only dynamic fee transactions currently carry client-side
+// validation rules; legacy, access list and blob transactions always pass.
+func (tx *Transaction) Validate(chainID *big.Int) error {
+	return tx.inner.validate(chainID)
+}
+
+// SanityCheck verifies that none of the numeric fields of the transaction are
+// negative. Upper bounds on fees are left to the state transition, which
+// reports them with the sender's context.
+func (tx *Transaction) SanityCheck() error {
+	fields := []struct {
+		name  string
+		value *big.Int
+	}{
+		{"value", tx.inner.value()},
+		{"gas price", tx.inner.gasPrice()},
+		{"max priority fee", tx.inner.gasTipCap()},
+		{"max fee", tx.inner.gasFeeCap()},
+		{"max fee per data gas", tx.inner.blobGasFeeCap()},
+	}
+	for _, f := range fields {
+		if f.value != nil && f.value.Sign() < 0 {
+			return &BlockSanityError{Field: "transaction " + f.name, Value: f.value, Reason: "negative"}
+		}
+	}
+	return nil
+}
+
 // RawSignatureValues returns the V, R, S signature values of the transaction.
 // The return values should not be modified by the caller.
 func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
@@ -401,7 +532,7 @@ func (tx *Transaction) Hash() common.Hash {
 	}
 
 	var h common.Hash
-	if tx.Type() == LegacyTxType {
+	if tx.IsLegacy() {
 		h = rlpHash(tx.inner)
 	} else {
 		h = prefixedRlpHash(tx.Type(), tx.inner)
@@ -417,14 +548,14 @@ func (tx *Transaction) Size() uint64 {
 		return size.(uint64)
 	}
 	c := writeCounter(0)
-	if tx.Type() == BlobTxType {
+	if tx.IsBlob() {
 		rlp.Encode(&c, &tx.inner) // TODO(karalabe): Replace with SSZ encoding
 	} else {
 		rlp.Encode(&c, &tx.inner)
 	}
 
 	size := uint64(c)
-	if tx.Type() != LegacyTxType {
+	if !tx.IsLegacy() {
 		size += 1 // type byte
 	}
 	tx.size.Store(size)
@@ -454,7 +585,7 @@ func (s Transactions) Len() int { return len(s) }
 // constructed by decoding or via public API in this package.
 func (s Transactions) EncodeIndex(i int, w *bytes.Buffer) {
 	tx := s[i]
-	if tx.Type() == LegacyTxType {
+	if tx.IsLegacy() {
 		rlp.Encode(w, tx.inner)
 	} else {
 		tx.encodeTyped(w)
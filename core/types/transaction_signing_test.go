@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"errors"
 	"math/big"
 	"testing"
@@ -136,3 +137,56 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+// Tests that the signature hash of every signer is what the transaction's
+// signature actually signs, so it can be verified independently of Sender.
+func TestSignerHashSigned(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		chainID = big.NewInt(1)
+		to      = common.Address{0x01}
+		legacy  = &LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)}
+		al      = &AccessListTx{ChainID: chainID, Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)}
+		dyn     = &DynamicFeeTx{ChainID: chainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)}
+	)
+	tests := []struct {
+		name   string
+		signer Signer
+		txs    []TxData
+	}{
+		{"frontier", FrontierSigner{}, []TxData{legacy}},
+		{"homestead", HomesteadSigner{}, []TxData{legacy}},
+		{"eip155", NewEIP155Signer(chainID), []TxData{legacy}},
+		{"eip2930", NewEIP2930Signer(chainID), []TxData{legacy, al}},
+		{"london", NewLondonSigner(chainID), []TxData{legacy, al, dyn}},
+		{"cancun", NewCancunSigner(chainID), []TxData{legacy, al, dyn}},
+	}
+	for _, tt := range tests {
+		for _, data := range tt.txs {
+			tx, err := SignNewTx(key, tt.signer, data)
+			if err != nil {
+				t.Fatalf("%s: failed to sign type %d transaction: %v", tt.name, data.txType(), err)
+			}
+			hash := tt.signer.Hash(tx)
+			sig, err := crypto.Sign(hash[:], key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantR, wantS, wantV, err := tt.signer.SignatureValues(tx, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, r, s := tx.RawSignatureValues()
+			if r.Cmp(wantR) != 0 || s.Cmp(wantS) != 0 || v.Cmp(wantV) != 0 {
+				t.Errorf("%s: type %d signature mismatch: have (%v, %v, %v), want (%v, %v, %v)", tt.name, tx.Type(), r, s, v, wantR, wantS, wantV)
+			}
+			pub, err := crypto.Ecrecover(hash[:], sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(pub, crypto.FromECDSAPub(&key.PublicKey)) {
+				t.Errorf("%s: type %d recovered key mismatch", tt.name, tx.Type())
+			}
+		}
+	}
+}
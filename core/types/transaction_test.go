@@ -30,6 +30,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -259,6 +260,66 @@ func TestRecipientNormal(t *testing.T) {
 	}
 }
 
+func TestDynamicFeeTxValidate(t *testing.T) {
+	valid := func() *DynamicFeeTx {
+		return &DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     1,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(2),
+			Gas:       21000,
+			To:        &testAddr,
+			Value:     big.NewInt(10),
+		}
+	}
+	tests := []struct {
+		mutate func(tx *DynamicFeeTx)
+		code   TxValidationCode
+	}{
+		{func(tx *DynamicFeeTx) { tx.ChainID = big.NewInt(2) }, TxInvalidChainID},
+		{func(tx *DynamicFeeTx) { tx.ChainID = nil }, TxInvalidChainID},
+		{func(tx *DynamicFeeTx) { tx.Gas = 0 }, TxZeroGas},
+		{func(tx *DynamicFeeTx) { tx.Nonce = ^uint64(0) }, TxNonceOverflow},
+		{func(tx *DynamicFeeTx) { tx.Value = big.NewInt(-1) }, TxNegativeValue},
+		{func(tx *DynamicFeeTx) { tx.GasFeeCap = nil }, TxMissingFee},
+		{func(tx *DynamicFeeTx) { tx.GasTipCap = big.NewInt(-1) }, TxNegativeFee},
+		{func(tx *DynamicFeeTx) { tx.GasFeeCap = new(big.Int).Lsh(big.NewInt(1), 256) }, TxFeeCapVeryHigh},
+		{func(tx *DynamicFeeTx) { tx.GasTipCap = new(big.Int).Lsh(big.NewInt(1), 256) }, TxTipVeryHigh},
+		{func(tx *DynamicFeeTx) { tx.GasTipCap = big.NewInt(3) }, TxTipAboveFeeCap},
+	}
+	if err := valid().Validate(big.NewInt(1)); err != nil {
+		t.Fatalf("valid transaction rejected: %v", err)
+	}
+	for i, test := range tests {
+		tx := valid()
+		test.mutate(tx)
+		err := tx.Validate(big.NewInt(1))
+		var verr *TxValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("test %d: expected validation error, got %v", i, err)
+		}
+		if verr.Code != test.code {
+			t.Errorf("test %d: error code mismatch: have %d, want %d", i, verr.Code, test.code)
+		}
+	}
+	// Validation should be reachable through the transaction wrapper.
+	inner := valid()
+	inner.GasTipCap = big.NewInt(3)
+	err := NewTx(inner).Validate(nil)
+	var verr *TxValidationError
+	if !errors.As(err, &verr) || verr.Code != TxTipAboveFeeCap {
+		t.Fatalf("wrapped transaction: have %v, want %v", err, ErrTipAboveFeeCap)
+	}
+	if !errors.Is(err, ErrTipAboveFeeCap) {
+		t.Fatalf("wrapped transaction error does not match sentinel: %v", err)
+	}
+	// Non dynamic fee transactions have no client-side validation rules.
+	legacy := NewTx(&LegacyTx{Nonce: ^uint64(0), GasPrice: big.NewInt(1)})
+	if err := legacy.Validate(big.NewInt(1)); err != nil {
+		t.Fatalf("legacy transaction rejected: %v", err)
+	}
+}
+
 func TestTransactionPriceNonceSortLegacy(t *testing.T) {
 	testTransactionPriceNonceSort(t, nil)
 }
@@ -599,3 +660,113 @@ func TestTransactionSizes(t *testing.T) {
 		}
 	}
 }
+
+func TestAccessListContains(t *testing.T) {
+	var (
+		addr1 = common.Address{0x01}
+		addr2 = common.Address{0x02}
+		key1  = common.Hash{0x01}
+		key2  = common.Hash{0x02}
+		al    = AccessList{
+			{Address: addr1, StorageKeys: []common.Hash{key1}},
+			{Address: addr2, StorageKeys: nil},
+			{Address: addr1, StorageKeys: []common.Hash{key2}},
+		}
+	)
+	tests := []struct {
+		addr common.Address
+		key  *common.Hash
+		want bool
+	}{
+		{addr1, nil, true},
+		{addr1, &key1, true},
+		{addr1, &key2, true}, // key in a later duplicate tuple
+		{addr2, nil, true},
+		{addr2, &key1, false},
+		{common.Address{0x03}, nil, false},
+	}
+	for i, tt := range tests {
+		if have := al.Contains(tt.addr, tt.key); have != tt.want {
+			t.Errorf("test %d: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestAccessListMerge(t *testing.T) {
+	var (
+		addr1 = common.Address{0x01}
+		addr2 = common.Address{0x02}
+		addr3 = common.Address{0x03}
+		key1  = common.Hash{0x01}
+		key2  = common.Hash{0x02}
+		a     = AccessList{
+			{Address: addr1, StorageKeys: []common.Hash{key1}},
+			{Address: addr2, StorageKeys: []common.Hash{}},
+		}
+		b = AccessList{
+			{Address: addr3, StorageKeys: []common.Hash{key2}},
+			{Address: addr1, StorageKeys: []common.Hash{key2, key1}},
+		}
+	)
+	want := AccessList{
+		{Address: addr1, StorageKeys: []common.Hash{key1, key2}},
+		{Address: addr2, StorageKeys: []common.Hash{}},
+		{Address: addr3, StorageKeys: []common.Hash{key2}},
+	}
+	if have := a.Merge(b); !reflect.DeepEqual(have, want) {
+		t.Fatalf("merged list mismatch:\nhave %v\nwant %v", have, want)
+	}
+	if len(a[0].StorageKeys) != 1 {
+		t.Fatalf("receiver modified by merge: %v", a)
+	}
+}
+
+func TestBlobGasPrice(t *testing.T) {
+	tx := NewTx(&BlobTx{BlobHashes: []common.Hash{{0x01}, {0x02}}})
+	tests := []struct {
+		excess uint64
+		price  int64
+	}{
+		{0, 1},
+		{1542706, 1}, // Last excess at the minimum price
+		{1542707, 2},
+		{10 * 1024 * 1024, 111},
+	}
+	for i, tt := range tests {
+		if have := tx.BlobGasPrice(tt.excess); have.Int64() != tt.price {
+			t.Errorf("test %d: blob gas price mismatch: have %v, want %d", i, have, tt.price)
+		}
+		want := tt.price * 2 * params.BlobTxDataGasPerBlob
+		if have := tx.BlobGasCost(tt.excess); have.Int64() != want {
+			t.Errorf("test %d: blob gas cost mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	legacy := NewTx(&LegacyTx{})
+	if price, cost := legacy.BlobGasPrice(0), legacy.BlobGasCost(0); price != nil || cost != nil {
+		t.Errorf("non-blob transaction priced: price %v, cost %v", price, cost)
+	}
+}
+
+func TestTransactionTypePredicates(t *testing.T) {
+	tests := []struct {
+		tx                                           *Transaction
+		legacy, accessList, dynamicFee, blob, hasACL bool
+	}{
+		{NewTx(&LegacyTx{}), true, false, false, false, false},
+		{NewTx(&AccessListTx{}), false, true, false, false, true},
+		{NewTx(&DynamicFeeTx{}), false, false, true, false, true},
+		{NewTx(&BlobTx{}), false, false, false, true, true},
+	}
+	for _, tt := range tests {
+		tx := tt.tx
+		if tx.IsLegacy() != tt.legacy || tx.IsAccessList() != tt.accessList || tx.IsDynamicFee() != tt.dynamicFee || tx.IsBlob() != tt.blob {
+			t.Errorf("type %d: predicate mismatch", tx.Type())
+		}
+		if tx.SupportsAccessList() != tt.hasACL {
+			t.Errorf("type %d: access list support mismatch: have %v, want %v", tx.Type(), tx.SupportsAccessList(), tt.hasACL)
+		}
+		if tx.SupportsBlobGas() != tt.blob {
+			t.Errorf("type %d: blob gas support mismatch: have %v, want %v", tx.Type(), tx.SupportsBlobGas(), tt.blob)
+		}
+	}
+}
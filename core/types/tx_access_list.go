@@ -42,6 +42,54 @@ func (al AccessList) StorageKeys() int {
 	return sum
 }
 
+// Contains reports whether the access list includes the given address, or if key
+// is non-nil, the given storage slot of the address.
+func (al AccessList) Contains(addr common.Address, key *common.Hash) bool {
+	for _, tuple := range al {
+		if tuple.Address != addr {
+			continue
+		}
+		if key == nil {
+			return true
+		}
+		for _, k := range tuple.StorageKeys {
+			if k == *key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Merge returns the union of the two access lists, with one tuple per address
+// and without duplicate storage keys. Addresses and keys are kept in the order
+// they first appear in, starting with al. Neither list is modified.
+func (al AccessList) Merge(other AccessList) AccessList {
+	var (
+		merged  AccessList
+		indices = make(map[common.Address]int)
+		seen    = make(map[common.Address]map[common.Hash]struct{})
+	)
+	for _, list := range []AccessList{al, other} {
+		for _, tuple := range list {
+			idx, ok := indices[tuple.Address]
+			if !ok {
+				idx = len(merged)
+				indices[tuple.Address] = idx
+				seen[tuple.Address] = make(map[common.Hash]struct{})
+				merged = append(merged, AccessTuple{Address: tuple.Address, StorageKeys: []common.Hash{}})
+			}
+			for _, key := range tuple.StorageKeys {
+				if _, ok := seen[tuple.Address][key]; !ok {
+					seen[tuple.Address][key] = struct{}{}
+					merged[idx].StorageKeys = append(merged[idx].StorageKeys, key)
+				}
+			}
+		}
+	}
+	return merged
+}
+
 // AccessListTx is the data of EIP-2930 access list transactions.
 type AccessListTx struct {
 	ChainID    *big.Int        // destination chain ID
@@ -109,6 +157,8 @@ func (tx *AccessListTx) blobGas() uint64           { return 0 }
 func (tx *AccessListTx) blobGasFeeCap() *big.Int   { return nil }
 func (tx *AccessListTx) blobHashes() []common.Hash { return nil }
 
+func (tx *AccessListTx) validate(*big.Int) error { return nil }
+
 func (tx *AccessListTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
 	return dst.Set(tx.GasPrice)
 }
@@ -17,9 +17,11 @@
 package types
 
 import (
+	"crypto/sha256"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
@@ -109,6 +111,8 @@ func (tx *BlobTx) blobGas() uint64           { return params.BlobTxDataGasPerBlo
 func (tx *BlobTx) blobGasFeeCap() *big.Int   { return tx.BlobFeeCap.ToBig() }
 func (tx *BlobTx) blobHashes() []common.Hash { return tx.BlobHashes }
 
+func (tx *BlobTx) validate(*big.Int) error { return nil }
+
 func (tx *BlobTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
 	if baseFee == nil {
 		return dst.Set(tx.GasFeeCap.ToBig())
@@ -130,3 +134,27 @@ func (tx *BlobTx) setSignatureValues(chainID, v, r, s *big.Int) {
 	tx.R.SetFromBig(r)
 	tx.S.SetFromBig(s)
 }
+
+// BlobSidecar carries a single data blob referenced by a block, together with
+// the KZG commitment and proof needed to verify it. Sidecars are transmitted
+// alongside blocks rather than as part of the block body.
+type BlobSidecar struct {
+	BlockHash  common.Hash // Hash of the block referencing the blob
+	Index      uint64      // Position of the blob among all blobs of the block
+	Blob       []byte      // Blob contents, BlobSize bytes long
+	Commitment [48]byte    // KZG commitment to the blob
+	Proof      [48]byte    // KZG proof of the commitment
+}
+
+// VersionedHash returns the versioned hash of the sidecar's KZG commitment, as
+// referenced by the blob transaction carrying it.
+func (sc *BlobSidecar) VersionedHash() common.Hash {
+	h := common.Hash(sha256.Sum256(sc.Commitment[:]))
+	h[0] = params.BlobTxHashVersion
+	return h
+}
+
+// calcBlobGasPrice calculates the price per data gas from the excess data gas.
+func calcBlobGasPrice(excessBlobGas uint64) *big.Int {
+	return math.FakeExponential(big.NewInt(params.BlobTxMinDataGasprice), new(big.Int).SetUint64(excessBlobGas), big.NewInt(params.BlobTxDataGaspriceUpdateFraction))
+}
@@ -17,6 +17,7 @@
 package types
 
 import (
+	"math"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -98,6 +99,8 @@ func (tx *DynamicFeeTx) blobGas() uint64           { return 0 }
 func (tx *DynamicFeeTx) blobGasFeeCap() *big.Int   { return nil }
 func (tx *DynamicFeeTx) blobHashes() []common.Hash { return nil }
 
+func (tx *DynamicFeeTx) validate(chainID *big.Int) error { return tx.Validate(chainID) }
+
 func (tx *DynamicFeeTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
 	if baseFee == nil {
 		return dst.Set(tx.GasFeeCap)
@@ -116,3 +119,42 @@ func (tx *DynamicFeeTx) rawSignatureValues() (v, r, s *big.Int) {
 func (tx *DynamicFeeTx) setSignatureValues(chainID, v, r, s *big.Int) {
 	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
 }
+
+// Validate checks the transaction fields against the invariants enforced by the
+// transaction pool, allowing clients to reject malformed transactions before
+// submitting them. If chainID is non-nil, the transaction's chain ID must match.
+func (tx *DynamicFeeTx) Validate(chainID *big.Int) error {
+	invalid := func(code TxValidationCode, err error) error {
+		return &TxValidationError{Code: code, Err: err}
+	}
+	if chainID != nil && (tx.ChainID == nil || tx.ChainID.Cmp(chainID) != 0) {
+		return invalid(TxInvalidChainID, ErrInvalidChainID)
+	}
+	if tx.Gas == 0 {
+		return invalid(TxZeroGas, ErrZeroGas)
+	}
+	// This is a pool and state transition rule rather than a consensus one: a
+	// sender nonce at the maximum value can never be incremented again.
+	if tx.Nonce == math.MaxUint64 {
+		return invalid(TxNonceOverflow, ErrNonceMax)
+	}
+	if tx.Value != nil && tx.Value.Sign() < 0 {
+		return invalid(TxNegativeValue, ErrNegativeValue)
+	}
+	if tx.GasFeeCap == nil || tx.GasTipCap == nil {
+		return invalid(TxMissingFee, ErrMissingFee)
+	}
+	if tx.GasFeeCap.Sign() < 0 || tx.GasTipCap.Sign() < 0 {
+		return invalid(TxNegativeFee, ErrNegativeFee)
+	}
+	if tx.GasFeeCap.BitLen() > 256 {
+		return invalid(TxFeeCapVeryHigh, ErrFeeCapVeryHigh)
+	}
+	if tx.GasTipCap.BitLen() > 256 {
+		return invalid(TxTipVeryHigh, ErrTipVeryHigh)
+	}
+	if tx.GasFeeCap.Cmp(tx.GasTipCap) < 0 {
+		return invalid(TxTipAboveFeeCap, ErrTipAboveFeeCap)
+	}
+	return nil
+}
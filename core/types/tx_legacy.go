@@ -106,6 +106,8 @@ func (tx *LegacyTx) blobGas() uint64           { return 0 }
 func (tx *LegacyTx) blobGasFeeCap() *big.Int   { return nil }
 func (tx *LegacyTx) blobHashes() []common.Hash { return nil }
 
+func (tx *LegacyTx) validate(*big.Int) error { return nil }
+
 func (tx *LegacyTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
 	return dst.Set(tx.GasPrice)
 }
@@ -18,6 +18,7 @@ package vm
 
 import (
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/holiman/uint256"
@@ -62,9 +63,35 @@ type Contract struct {
 	value *big.Int
 }
 
+// contractPool recycles the contracts of finished call frames if the EVM is
+// configured with RecycleFrames.
+var contractPool = sync.Pool{
+	New: func() interface{} {
+		return new(Contract)
+	},
+}
+
 // NewContract returns a new contract environment for the execution of EVM.
 func NewContract(caller ContractRef, object ContractRef, value *big.Int, gas uint64) *Contract {
-	c := &Contract{CallerAddress: caller.Address(), caller: caller, self: object}
+	return new(Contract).init(caller, object, value, gas)
+}
+
+// newPooledContract is like NewContract, but takes the contract from the pool.
+// It must be handed back via returnContract once the call frame is done.
+func newPooledContract(caller ContractRef, object ContractRef, value *big.Int, gas uint64) *Contract {
+	return contractPool.Get().(*Contract).init(caller, object, value, gas)
+}
+
+// returnContract clears the contract of a finished call frame and puts it back
+// into the pool.
+func returnContract(c *Contract) {
+	*c = Contract{}
+	contractPool.Put(c)
+}
+
+// init sets up the contract for a new call frame.
+func (c *Contract) init(caller ContractRef, object ContractRef, value *big.Int, gas uint64) *Contract {
+	c.CallerAddress, c.caller, c.self = caller.Address(), caller, object
 
 	if parent, ok := caller.(*Contract); ok {
 		// Reuse JUMPDEST analysis from parent context if available.
@@ -129,6 +156,14 @@ func (c *Contract) isCode(udest uint64) bool {
 	return c.analysis.codeSegment(udest)
 }
 
+// WithCachedJumpdests sets a precomputed JUMPDEST analysis of the contract code,
+// as obtained by codeBitmap, sparing the analysis on the first jump. It returns
+// the contract for chaining calls.
+func (c *Contract) WithCachedJumpdests(bitmap bitvec) *Contract {
+	c.analysis = bitmap
+	return c
+}
+
 // AsDelegate sets the contract to be a delegate call and returns the current
 // contract (for chaining calls)
 func (c *Contract) AsDelegate() *Contract {
@@ -140,6 +140,32 @@ func ActivePrecompiles(rules params.Rules) []common.Address {
 	}
 }
 
+// activePrecompiledContracts returns the precompiled contracts enabled with the
+// given rules.
+func activePrecompiledContracts(rules params.Rules) map[common.Address]PrecompiledContract {
+	switch {
+	case rules.IsBerlin:
+		return PrecompiledContractsBerlin
+	case rules.IsIstanbul:
+		return PrecompiledContractsIstanbul
+	case rules.IsByzantium:
+		return PrecompiledContractsByzantium
+	default:
+		return PrecompiledContractsHomestead
+	}
+}
+
+// PrecompileGas returns the gas cost of running the precompiled contract at addr
+// with the given input, without running it. The boolean is false if there is no
+// precompiled contract at addr under the given rules.
+func PrecompileGas(addr common.Address, input []byte, rules params.Rules) (uint64, bool) {
+	p, ok := activePrecompiledContracts(rules)[addr]
+	if !ok {
+		return 0, false
+	}
+	return p.RequiredGas(input), true
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 // It returns
 // - the returned bytes,
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -391,3 +392,42 @@ func BenchmarkPrecompiledBLS12381G2MultiExpWorstCase(b *testing.B) {
 	}
 	benchmarkPrecompiled("0f", testcase, b)
 }
+
+// Tests that the gas cost of every precompiled contract can be looked up without
+// running it, and only for the contracts active under the given rules.
+func TestPrecompileGas(t *testing.T) {
+	var (
+		berlin = params.Rules{IsByzantium: true, IsIstanbul: true, IsBerlin: true}
+		input  = make([]byte, 32)
+	)
+	tests := []struct {
+		addr byte
+		gas  uint64
+	}{
+		{1, params.EcrecoverGas},
+		{2, params.Sha256BaseGas + params.Sha256PerWordGas},
+		{3, params.Ripemd160BaseGas + params.Ripemd160PerWordGas},
+		{4, params.IdentityBaseGas + params.IdentityPerWordGas},
+		{5, 200}, // EIP-2565 minimum
+		{6, params.Bn256AddGasIstanbul},
+		{7, params.Bn256ScalarMulGasIstanbul},
+		{8, params.Bn256PairingBaseGasIstanbul},
+		{9, 0}, // Malformed input is charged nothing
+	}
+	for _, tt := range tests {
+		gas, ok := PrecompileGas(common.BytesToAddress([]byte{tt.addr}), input, berlin)
+		if !ok {
+			t.Errorf("precompile %d: not found", tt.addr)
+			continue
+		}
+		if gas != tt.gas {
+			t.Errorf("precompile %d: gas mismatch: have %d, want %d", tt.addr, gas, tt.gas)
+		}
+	}
+	if _, ok := PrecompileGas(common.BytesToAddress([]byte{10}), input, berlin); ok {
+		t.Errorf("precompile 10 found before activation")
+	}
+	if _, ok := PrecompileGas(common.BytesToAddress([]byte{9}), input, params.Rules{IsByzantium: true}); ok {
+		t.Errorf("precompile 9 found before Istanbul")
+	}
+}
@@ -49,6 +49,72 @@ func EnableEIP(eipNum int, jt *JumpTable) error {
 	return nil
 }
 
+// EIPConflictError is returned by ValidateExtraEips if a combination of EIPs
+// can't be activated together.
+type EIPConflictError struct {
+	EIPs   []int  // EIPs involved in the conflict
+	Reason string // Explanation of the conflict
+}
+
+func (e *EIPConflictError) Error() string {
+	return fmt.Sprintf("incompatible EIPs %v: %s", e.EIPs, e.Reason)
+}
+
+// eipRequirement describes an EIP which another one builds upon, and which may
+// either be part of the chain rules or, if it is activateable, be enabled too.
+type eipRequirement struct {
+	eip    int                     // EIP required to be active
+	active func(params.Rules) bool // Whether the chain rules include the EIP
+	reason string                  // Why the EIP is required
+}
+
+// eipRequirements lists the prerequisites of the activateable EIPs.
+var eipRequirements = map[int]eipRequirement{
+	3529: {2929, func(r params.Rules) bool { return r.IsBerlin }, "the reduced refunds are defined on top of the EIP-2929 storage pricing"},
+	3198: {1559, func(r params.Rules) bool { return r.IsLondon }, "BASEFEE returns the EIP-1559 base fee of the block"},
+}
+
+// eipConflicts lists the pairs of activateable EIPs which are mutually exclusive.
+var eipConflicts = []struct {
+	a, b   int
+	reason string
+}{
+	{2200, 2929, "both define the SSTORE gas cost, the one enabled last overrides the other"},
+	{2200, 3529, "both define the SSTORE gas cost, the one enabled last overrides the other"},
+}
+
+// ValidateExtraEips checks that the given EIPs can be activated together on top
+// of the chain rules, returning an EIPConflictError for the first EIP listed
+// twice, pair of conflicting EIPs or EIP with a missing prerequisite. EIPs not
+// known to the interpreter are not checked.
+func ValidateExtraEips(eips []int, rules params.Rules) error {
+	enabled := make(map[int]bool, len(eips))
+	for _, eip := range eips {
+		if enabled[eip] {
+			return &EIPConflictError{EIPs: []int{eip}, Reason: "enabled more than once"}
+		}
+		enabled[eip] = true
+	}
+	for _, conflict := range eipConflicts {
+		if enabled[conflict.a] && enabled[conflict.b] {
+			return &EIPConflictError{EIPs: []int{conflict.a, conflict.b}, Reason: conflict.reason}
+		}
+	}
+	for _, eip := range eips {
+		req, ok := eipRequirements[eip]
+		if !ok || req.active(rules) {
+			continue
+		}
+		// Only activateable EIPs can be enabled on top of the chain rules, the
+		// others must be part of them
+		if enabled[req.eip] && ValidEip(req.eip) {
+			continue
+		}
+		return &EIPConflictError{EIPs: []int{eip, req.eip}, Reason: fmt.Sprintf("EIP-%d requires EIP-%d, as %s", eip, req.eip, req.reason)}
+	}
+	return nil
+}
+
 func ValidEip(eipNum int) bool {
 	_, ok := activators[eipNum]
 	return ok
@@ -188,6 +254,7 @@ func enable1153(jt *JumpTable) {
 		constantGas: params.WarmStorageReadCostEIP2929,
 		minStack:    minStack(2, 0),
 		maxStack:    maxStack(2, 0),
+		writes:      true,
 	}
 }
 
@@ -202,9 +269,6 @@ func opTload(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]by
 
 // opTstore implements TSTORE opcode
 func opTstore(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
-	if interpreter.readOnly {
-		return nil, ErrWriteProtection
-	}
 	loc := scope.Stack.pop()
 	val := scope.Stack.pop()
 	interpreter.evm.StateDB.SetTransientState(scope.Contract.Address(), loc.Bytes32(), val.Bytes32())
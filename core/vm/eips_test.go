@@ -0,0 +1,108 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestValidateExtraEips(t *testing.T) {
+	var (
+		istanbul = params.Rules{IsIstanbul: true}
+		berlin   = params.Rules{IsIstanbul: true, IsBerlin: true}
+		london   = params.Rules{IsIstanbul: true, IsBerlin: true, IsLondon: true}
+	)
+	tests := []struct {
+		eips     []int
+		rules    params.Rules
+		conflict []int
+	}{
+		{eips: nil, rules: istanbul},
+		{eips: []int{3855, 1153}, rules: istanbul},
+		{eips: []int{2929, 3529}, rules: berlin},
+		{eips: []int{3198}, rules: london},
+		{eips: []int{1234}, rules: istanbul}, // unknown EIPs are left to activation
+		{eips: []int{2929}, rules: istanbul},
+		{eips: []int{2929, 3529}, rules: istanbul},
+
+		{eips: []int{3855, 3855}, rules: london, conflict: []int{3855}},
+		{eips: []int{2929, 2200}, rules: berlin, conflict: []int{2200, 2929}},
+		{eips: []int{2929, 3529, 2200}, rules: berlin, conflict: []int{2200, 2929}},
+		{eips: []int{3529}, rules: istanbul, conflict: []int{3529, 2929}},
+		{eips: []int{3198}, rules: berlin, conflict: []int{3198, 1559}},
+		{eips: []int{1559, 3198}, rules: berlin, conflict: []int{3198, 1559}}, // 1559 can't be activated
+	}
+	for i, tt := range tests {
+		err := ValidateExtraEips(tt.eips, tt.rules)
+		if tt.conflict == nil {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		var conflict *EIPConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("test %d: error mismatch: have %v, want conflict", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(conflict.EIPs, tt.conflict) {
+			t.Errorf("test %d: conflicting EIPs mismatch: have %v, want %v", i, conflict.EIPs, tt.conflict)
+		}
+	}
+}
+
+// Tests that an invalid combination of extra EIPs fails the EVM construction.
+func TestInvalidExtraEips(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:             big.NewInt(1),
+		HomesteadBlock:      new(big.Int),
+		EIP150Block:         new(big.Int),
+		EIP155Block:         new(big.Int),
+		EIP158Block:         new(big.Int),
+		ByzantiumBlock:      new(big.Int),
+		ConstantinopleBlock: new(big.Int),
+		PetersburgBlock:     new(big.Int),
+		IstanbulBlock:       new(big.Int),
+	}
+	var conflict *EIPConflictError
+	if _, err := NewValidatedEVM(BlockContext{BlockNumber: new(big.Int)}, TxContext{}, nil, config, Config{ExtraEips: []int{3855, 3529}}); !errors.As(err, &conflict) {
+		t.Fatalf("error mismatch: have %v, want conflict", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("NewEVM accepted conflicting EIPs")
+			}
+		}()
+		NewEVM(BlockContext{BlockNumber: new(big.Int)}, TxContext{}, nil, config, Config{ExtraEips: []int{3855, 3529}})
+	}()
+	evm, err := NewValidatedEVM(BlockContext{BlockNumber: new(big.Int)}, TxContext{}, nil, config, Config{ExtraEips: []int{3855, 2929}})
+	if err != nil {
+		t.Fatalf("valid EIPs rejected: %v", err)
+	}
+	if !reflect.DeepEqual(evm.Config.ExtraEips, []int{3855, 2929}) {
+		t.Fatalf("valid EIPs not activated: %v", evm.Config.ExtraEips)
+	}
+	if !evm.interpreter.table[PUSH0].HasCost() {
+		t.Fatal("PUSH0 not activated")
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ValidateEOFCode checks an EOF code section as specified by EIP-3670: every
+// byte that is not immediate data of a PUSH instruction must be an opcode
+// defined by the instruction set of the given rules, or the designated INVALID
+// instruction, and the immediate data of the last PUSH must not be truncated.
+func ValidateEOFCode(code []byte, rules params.Rules) error {
+	// Instruction sets of forks not defined yet fall back to the latest one,
+	// the same as in the interpreter.
+	table, _ := LookupInstructionSet(rules)
+
+	bits := codeBitmap(code)
+	for pos := uint64(0); pos < uint64(len(code)); pos++ {
+		if !bits.codeSegment(pos) {
+			continue
+		}
+		op := OpCode(code[pos])
+		if op != INVALID && table[op].undefined {
+			return fmt.Errorf("%w: opcode %#x at offset %d", ErrUndefinedInstruction, byte(op), pos)
+		}
+		if op.IsPush() {
+			if size := uint64(op - PUSH1 + 1); pos+size >= uint64(len(code)) {
+				return fmt.Errorf("%w: %v at offset %d", ErrTruncatedImmediate, op, pos)
+			}
+		}
+	}
+	return nil
+}
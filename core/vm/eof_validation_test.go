@@ -0,0 +1,56 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestValidateEOFCode(t *testing.T) {
+	var (
+		shanghai = params.Rules{IsShanghai: true}
+		london   = params.Rules{IsLondon: true}
+	)
+	tests := []struct {
+		code  string
+		rules params.Rules
+		err   error
+	}{
+		{"", shanghai, nil},
+		{"00", shanghai, nil},
+		{"6001600201fe", shanghai, nil}, // push1 1 push1 2 add invalid
+		{"600c00", shanghai, nil},       // undefined byte as push data
+		{"7f" + strings.Repeat("0c", 32) + "00", shanghai, nil},
+		{"0c", shanghai, ErrUndefinedInstruction},
+		{"60010c", shanghai, ErrUndefinedInstruction},
+		{"5f00", shanghai, nil}, // push0
+		{"5f00", london, ErrUndefinedInstruction},
+		{"61ff", shanghai, ErrTruncatedImmediate},
+		{"60", shanghai, ErrTruncatedImmediate},
+	}
+	for i, tt := range tests {
+		err := ValidateEOFCode(common.FromHex(tt.code), tt.rules)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d (%s): error mismatch: have %v, want %v", i, tt.code, err, tt.err)
+		}
+	}
+}
@@ -37,6 +37,11 @@ var (
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
 	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
 	ErrNonceUintOverflow        = errors.New("nonce uint64 overflow")
+	ErrReturnDataTooLarge       = errors.New("return data too large")
+	ErrUndefinedInstruction     = errors.New("undefined instruction")
+	ErrTruncatedImmediate       = errors.New("truncated immediate")
+	ErrValueOverflow            = errors.New("call value overflows callee balance")
+	ErrBudgetExhausted          = errors.New("instruction budget exhausted")
 
 	// errStopToken is an internal token indicating interpreter loop termination,
 	// never returned to outside callers.
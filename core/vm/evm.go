@@ -21,6 +21,7 @@ import (
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
@@ -41,18 +42,7 @@ type (
 )
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
-	var precompiles map[common.Address]PrecompiledContract
-	switch {
-	case evm.chainRules.IsBerlin:
-		precompiles = PrecompiledContractsBerlin
-	case evm.chainRules.IsIstanbul:
-		precompiles = PrecompiledContractsIstanbul
-	case evm.chainRules.IsByzantium:
-		precompiles = PrecompiledContractsByzantium
-	default:
-		precompiles = PrecompiledContractsHomestead
-	}
-	p, ok := precompiles[addr]
+	p, ok := activePrecompiledContracts(evm.chainRules)[addr]
 	return p, ok
 }
 
@@ -110,6 +100,9 @@ type EVM struct {
 	// virtual machine configuration options used to initialise the
 	// evm.
 	Config Config
+	// tracer is the tracer of the configuration, installed into Config for
+	// the transactions selected by its TraceFilter.
+	tracer EVMLogger
 	// global (to this context) ethereum virtual machine
 	// used throughout the execution of the tx.
 	interpreter *EVMInterpreter
@@ -122,18 +115,41 @@ type EVM struct {
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
-// only ever be used *once*.
+// only ever be used *once*. It panics if the extra EIPs of the config can't be
+// activated together, use NewValidatedEVM for configs from untrusted sources.
 func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig *params.ChainConfig, config Config) *EVM {
+	evm, err := NewValidatedEVM(blockCtx, txCtx, statedb, chainConfig, config)
+	if err != nil {
+		panic(err)
+	}
+	return evm
+}
+
+// NewValidatedEVM returns a new EVM like NewEVM, but returns an error instead if
+// the extra EIPs of the config can't be activated together on top of the chain
+// rules, see ValidateExtraEips.
+func NewValidatedEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig *params.ChainConfig, config Config) (*EVM, error) {
 	evm := &EVM{
 		Context:     blockCtx,
 		TxContext:   txCtx,
 		StateDB:     statedb,
 		Config:      config,
+		tracer:      config.Tracer,
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil, blockCtx.Time),
 	}
+	if err := ValidateExtraEips(config.ExtraEips, evm.chainRules); err != nil {
+		return nil, err
+	}
 	evm.interpreter = NewEVMInterpreter(evm)
-	return evm
+	return evm, nil
+}
+
+// NewEVMWithTracer returns a new EVM with the given tracer installed, overriding
+// any tracer already present in config.
+func NewEVMWithTracer(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig *params.ChainConfig, config Config, tracer EVMLogger) *EVM {
+	config.Tracer = tracer
+	return NewEVM(blockCtx, txCtx, statedb, chainConfig, config)
 }
 
 // Reset resets the EVM with a new transaction context.Reset
@@ -143,6 +159,56 @@ func (evm *EVM) Reset(txCtx TxContext, statedb StateDB) {
 	evm.StateDB = statedb
 }
 
+// FilterTrace installs the configured tracer if the transaction about to be
+// executed is selected by Config.TraceFilter, or removes it otherwise, letting
+// the transaction run without any tracing overhead. Without a filter, the tracer
+// is always installed.
+func (evm *EVM) FilterTrace(tx *types.Transaction) {
+	if evm.tracer == nil || evm.Config.TraceFilter == nil {
+		return
+	}
+	if evm.Config.TraceFilter(tx) {
+		evm.Config.Tracer = evm.tracer
+	} else {
+		evm.Config.Tracer = nil
+	}
+}
+
+// newContract creates the contract of a call frame, recycling one of a finished
+// frame if configured to.
+func (evm *EVM) newContract(caller ContractRef, object ContractRef, value *big.Int, gas uint64) *Contract {
+	if evm.Config.RecycleFrames {
+		return newPooledContract(caller, object, value, gas)
+	}
+	return NewContract(caller, object, value, gas)
+}
+
+// releaseContract hands the contract of a finished call frame back for recycling
+// if configured to. The contract must not be used afterwards.
+func (evm *EVM) releaseContract(c *Contract) {
+	if evm.Config.RecycleFrames {
+		returnContract(c)
+	}
+}
+
+// preimageHooker is implemented by state databases able to report the keccak256
+// preimages of the account addresses and storage keys they hash.
+type preimageHooker interface {
+	SetPreimageHook(hook func(hash common.Hash, preimage []byte))
+}
+
+// hookPreimages routes the preimages hashed by the state database to the tracer,
+// if any, returning a function to detach it again. The hook is only installed
+// for the duration of a top-level call, as the state database may be shared.
+func (evm *EVM) hookPreimages() func() {
+	db, ok := evm.StateDB.(preimageHooker)
+	if !ok || evm.Config.Tracer == nil {
+		return func() {}
+	}
+	db.SetPreimageHook(evm.Config.Tracer.CapturePreimage)
+	return func() { db.SetPreimageHook(nil) }
+}
+
 // Cancel cancels any running EVM operation. This may be called concurrently and
 // it's safe to be called multiple times.
 func (evm *EVM) Cancel() {
@@ -176,6 +242,9 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if evm.depth == 0 {
+		defer evm.hookPreimages()()
+	}
 	// Fail if we're trying to transfer more than the available balance
 	if value.Sign() != 0 && !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
@@ -230,18 +299,19 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 			addrCopy := addr
 			// If the account has no code, we can abort here
 			// The depth-check is already done, and precompiles handled above
-			contract := NewContract(caller, AccountRef(addrCopy), value, gas)
+			contract := evm.newContract(caller, AccountRef(addrCopy), value, gas)
 			contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), code)
 			ret, err = evm.interpreter.Run(contract, input, false)
 			gas = contract.Gas
+			evm.releaseContract(contract)
 		}
 	}
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
 	// when we're in homestead this also counts for code storage gas errors.
-	if err != nil {
+	if err != nil && err != ErrBudgetExhausted {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != ErrExecutionReverted {
+		if err != ErrExecutionReverted && err != ErrReturnDataTooLarge {
 			gas = 0
 		}
 		// TODO: consider clearing up unused snapshots:
@@ -263,6 +333,9 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if evm.depth == 0 {
+		defer evm.hookPreimages()()
+	}
 	// Fail if we're trying to transfer more than the available balance
 	// Note although it's noop to transfer X ether to caller itself. But
 	// if caller doesn't have enough balance, it would be an error to allow
@@ -287,14 +360,15 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		addrCopy := addr
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
-		contract := NewContract(caller, AccountRef(caller.Address()), value, gas)
+		contract := evm.newContract(caller, AccountRef(caller.Address()), value, gas)
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
+		evm.releaseContract(contract)
 	}
-	if err != nil {
+	if err != nil && err != ErrBudgetExhausted {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != ErrExecutionReverted {
+		if err != ErrExecutionReverted && err != ErrReturnDataTooLarge {
 			gas = 0
 		}
 	}
@@ -311,6 +385,9 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if evm.depth == 0 {
+		defer evm.hookPreimages()()
+	}
 	var snapshot = evm.StateDB.Snapshot()
 
 	// Invoke tracer hooks that signal entering/exiting a call frame
@@ -331,14 +408,15 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	} else {
 		addrCopy := addr
 		// Initialise a new contract and make initialise the delegate values
-		contract := NewContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
+		contract := evm.newContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
+		evm.releaseContract(contract)
 	}
-	if err != nil {
+	if err != nil && err != ErrBudgetExhausted {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != ErrExecutionReverted {
+		if err != ErrExecutionReverted && err != ErrReturnDataTooLarge {
 			gas = 0
 		}
 	}
@@ -354,6 +432,9 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if evm.depth == 0 {
+		defer evm.hookPreimages()()
+	}
 	// We take a snapshot here. This is a bit counter-intuitive, and could probably be skipped.
 	// However, even a staticcall is considered a 'touch'. On mainnet, static calls were introduced
 	// after all empty accounts were deleted, so this is not required. However, if we omit this,
@@ -384,17 +465,18 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		addrCopy := addr
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
-		contract := NewContract(caller, AccountRef(addrCopy), new(big.Int), gas)
+		contract := evm.newContract(caller, AccountRef(addrCopy), new(big.Int), gas)
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
 		// When an error was returned by the EVM or when setting the creation code
 		// above we revert to the snapshot and consume any gas remaining. Additionally
 		// when we're in Homestead this also counts for code storage gas errors.
 		ret, err = evm.interpreter.Run(contract, input, true)
 		gas = contract.Gas
+		evm.releaseContract(contract)
 	}
-	if err != nil {
+	if err != nil && err != ErrBudgetExhausted {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != ErrExecutionReverted {
+		if err != ErrExecutionReverted && err != ErrReturnDataTooLarge {
 			gas = 0
 		}
 	}
@@ -420,6 +502,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, common.Address{}, gas, ErrDepth
 	}
+	if evm.depth == 0 {
+		defer evm.hookPreimages()()
+	}
 	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
@@ -448,7 +533,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	// Initialise a new contract and set the code that is to be used by the EVM.
 	// The contract is a scoped environment for this execution context only.
-	contract := NewContract(caller, AccountRef(address), value, gas)
+	contract := evm.newContract(caller, AccountRef(address), value, gas)
 	contract.SetCodeOptionalHash(&address, codeAndHash)
 
 	if evm.Config.Tracer != nil {
@@ -487,9 +572,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
 	// when we're in homestead this also counts for code storage gas errors.
-	if err != nil && (evm.chainRules.IsHomestead || err != ErrCodeStoreOutOfGas) {
+	if err != nil && err != ErrBudgetExhausted && (evm.chainRules.IsHomestead || err != ErrCodeStoreOutOfGas) {
 		evm.StateDB.RevertToSnapshot(snapshot)
-		if err != ErrExecutionReverted {
+		if err != ErrExecutionReverted && err != ErrReturnDataTooLarge {
 			contract.UseGas(contract.Gas)
 		}
 	}
@@ -501,7 +586,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 			evm.Config.Tracer.CaptureExit(ret, gas-contract.Gas, err)
 		}
 	}
-	return ret, address, contract.Gas, err
+	leftOverGas := contract.Gas
+	evm.releaseContract(contract)
+	return ret, address, leftOverGas, err
 }
 
 // Create creates a new contract using code as deployment code.
@@ -30,6 +30,18 @@ const (
 	GasExtStep     uint64 = 20
 )
 
+// GasFractionRule returns the numerator/denominator fraction of the available
+// gas, rounded in favour of the available gas the same way as the "all but one
+// 64th" rule of EIP-150: available - available/denominator*(denominator-numerator).
+// A zero denominator or a fraction above one leaves the available gas as is.
+func GasFractionRule(available, numerator, denominator uint64) uint64 {
+	if denominator == 0 || numerator >= denominator {
+		return available
+	}
+	// The withheld amount never exceeds available, so this can't wrap.
+	return available - available/denominator*(denominator-numerator)
+}
+
 // callGas returns the actual gas cost of the call.
 //
 // The cost of gas was changed during the homestead price change HF.
@@ -37,7 +49,7 @@ const (
 func callGas(isEip150 bool, availableGas, base uint64, callCost *uint256.Int) (uint64, error) {
 	if isEip150 {
 		availableGas = availableGas - base
-		gas := availableGas - availableGas/64
+		gas := GasFractionRule(availableGas, 63, 64)
 		// If the bit length exceeds 64 bit we know that the newly calculated "gas" for EIP150
 		// is smaller than the requested amount. Therefore we return the new gas instead
 		// of returning an error.
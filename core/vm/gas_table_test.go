@@ -83,7 +83,7 @@ func TestEIP2200(t *testing.T) {
 	for i, tt := range eip2200Tests {
 		address := common.BytesToAddress([]byte("contract"))
 
-		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 		statedb.CreateAccount(address)
 		statedb.SetCode(address, hexutil.MustDecode(tt.input))
 		statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{tt.original}))
@@ -135,7 +135,7 @@ func TestCreateGas(t *testing.T) {
 		var gasUsed = uint64(0)
 		doCheck := func(testGas int) bool {
 			address := common.BytesToAddress([]byte("contract"))
-			statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+			statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 			statedb.CreateAccount(address)
 			statedb.SetCode(address, hexutil.MustDecode(tt.code))
 			statedb.Finalise(true)
@@ -177,3 +177,35 @@ func TestCreateGas(t *testing.T) {
 		}
 	}
 }
+
+// FuzzGasFractionRule checks the fraction rule for every small fraction against
+// a big integer model, along with the legacy 63/64 arithmetic it replaces.
+func FuzzGasFractionRule(f *testing.F) {
+	f.Add(uint64(0))
+	f.Add(uint64(63))
+	f.Add(uint64(100000))
+	f.Add(uint64(math.MaxUint64))
+
+	f.Fuzz(func(t *testing.T, available uint64) {
+		if have, want := GasFractionRule(available, 63, 64), available-available/64; have != want {
+			t.Fatalf("63/64 of %d mismatch: have %d, want %d", available, have, want)
+		}
+		for denominator := uint64(0); denominator <= 16; denominator++ {
+			for numerator := uint64(0); numerator <= 16; numerator++ {
+				have := GasFractionRule(available, numerator, denominator)
+				if denominator == 0 || numerator >= denominator {
+					if have != available {
+						t.Fatalf("%d/%d of %d mismatch: have %d, want %d", numerator, denominator, available, have, available)
+					}
+					continue
+				}
+				avail, denom := new(big.Int).SetUint64(available), new(big.Int).SetUint64(denominator)
+				withheld := new(big.Int).Div(avail, denom)
+				withheld.Mul(withheld, new(big.Int).SetUint64(denominator-numerator))
+				if want := new(big.Int).Sub(avail, withheld); !want.IsUint64() || have != want.Uint64() {
+					t.Fatalf("%d/%d of %d mismatch: have %d, want %v", numerator, denominator, available, have, want)
+				}
+			}
+		}
+	})
+}
@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -307,7 +309,17 @@ func opCallDataCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 	// These values are checked for overflow during gas cost calculation
 	memOffset64 := memOffset.Uint64()
 	length64 := length.Uint64()
-	scope.Memory.Set(memOffset64, length64, getData(scope.Contract.Input, dataOffset64, length64))
+
+	// Copy whatever calldata is available and zero-fill the rest in place
+	var copied uint64
+	if input := scope.Contract.Input; dataOffset64 < uint64(len(input)) {
+		copied = uint64(len(input)) - dataOffset64
+		if copied > length64 {
+			copied = length64
+		}
+		scope.Memory.Set(memOffset64, copied, input[dataOffset64:dataOffset64+copied])
+	}
+	scope.Memory.WriteZero(memOffset64+copied, length64-copied)
 
 	return nil, nil
 }
@@ -515,16 +527,33 @@ func opSload(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]by
 	hash := common.Hash(loc.Bytes32())
 	val := interpreter.evm.StateDB.GetState(scope.Contract.Address(), hash)
 	loc.SetBytes(val.Bytes())
+
+	if tracer := interpreter.evm.Config.Tracer; tracer != nil {
+		tracer.CaptureStorageRead(scope.Contract.Address(), hash, val)
+	}
+	if tracer := interpreter.evm.Config.StorageTracer; tracer != nil {
+		tracer.CaptureStorageRead(scope.Contract.Address(), hash, val)
+	}
 	return nil, nil
 }
 
 func opSstore(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
-	if interpreter.readOnly {
-		return nil, ErrWriteProtection
+	var (
+		loc  = scope.Stack.pop()
+		val  = scope.Stack.pop()
+		addr = scope.Contract.Address()
+		key  = common.Hash(loc.Bytes32())
+	)
+	if tracer, storageTracer := interpreter.evm.Config.Tracer, interpreter.evm.Config.StorageTracer; tracer != nil || storageTracer != nil {
+		old := interpreter.evm.StateDB.GetState(addr, key)
+		if tracer != nil {
+			tracer.CaptureStorageWrite(addr, key, old, val.Bytes32())
+		}
+		if storageTracer != nil {
+			storageTracer.CaptureStorageWrite(addr, key, old, val.Bytes32())
+		}
 	}
-	loc := scope.Stack.pop()
-	val := scope.Stack.pop()
-	interpreter.evm.StateDB.SetState(scope.Contract.Address(), loc.Bytes32(), val.Bytes32())
+	interpreter.evm.StateDB.SetState(addr, key, val.Bytes32())
 	return nil, nil
 }
 
@@ -574,9 +603,6 @@ func opGas(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte
 }
 
 func opCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
-	if interpreter.readOnly {
-		return nil, ErrWriteProtection
-	}
 	var (
 		value        = scope.Stack.pop()
 		offset, size = scope.Stack.pop(), scope.Stack.pop()
@@ -584,7 +610,7 @@ func opCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 		gas          = scope.Contract.Gas
 	)
 	if interpreter.evm.chainRules.IsEIP150 {
-		gas -= gas / 64
+		gas = GasFractionRule(gas, 63, 64)
 	}
 	// reuse size int for stackvalue
 	stackvalue := size
@@ -611,7 +637,7 @@ func opCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 	scope.Stack.push(&stackvalue)
 	scope.Contract.Gas += returnGas
 
-	if suberr == ErrExecutionReverted {
+	if suberr == ErrExecutionReverted || suberr == ErrReturnDataTooLarge {
 		interpreter.returnData = res // set REVERT data to return data buffer
 		return res, nil
 	}
@@ -620,9 +646,6 @@ func opCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 }
 
 func opCreate2(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
-	if interpreter.readOnly {
-		return nil, ErrWriteProtection
-	}
 	var (
 		endowment    = scope.Stack.pop()
 		offset, size = scope.Stack.pop(), scope.Stack.pop()
@@ -631,7 +654,7 @@ func opCreate2(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 		gas          = scope.Contract.Gas
 	)
 	// Apply EIP150
-	gas -= gas / 64
+	gas = GasFractionRule(gas, 63, 64)
 	scope.Contract.UseGas(gas)
 	// reuse size int for stackvalue
 	stackvalue := size
@@ -651,7 +674,7 @@ func opCreate2(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 	scope.Stack.push(&stackvalue)
 	scope.Contract.Gas += returnGas
 
-	if suberr == ErrExecutionReverted {
+	if suberr == ErrExecutionReverted || suberr == ErrReturnDataTooLarge {
 		interpreter.returnData = res // set REVERT data to return data buffer
 		return res, nil
 	}
@@ -659,6 +682,9 @@ func opCreate2(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 	return nil, nil
 }
 
+// maxBalance is the exclusive upper bound of account balances, 2^256.
+var maxBalance = new(big.Int).Lsh(big1, 256)
+
 func opCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	stack := scope.Stack
 	// Pop gas. The actual gas in interpreter.evm.callGasTemp.
@@ -681,6 +707,14 @@ func opCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byt
 	if !value.IsZero() {
 		gas += params.CallStipend
 		bigVal = value.ToBig()
+		// Synthetic balances may get close enough to the limit for the
+		// transfer to wrap around the callee balance.
+		if interpreter.evm.Config.CheckValueOverflow && toAddr != scope.Contract.Address() {
+			balance := new(big.Int).Add(interpreter.evm.StateDB.GetBalance(toAddr), bigVal)
+			if balance.Cmp(maxBalance) >= 0 {
+				return nil, ErrValueOverflow
+			}
+		}
 	}
 
 	ret, returnGas, err := interpreter.evm.Call(scope.Contract, toAddr, args, gas, bigVal)
@@ -691,7 +725,8 @@ func opCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byt
 		temp.SetOne()
 	}
 	stack.push(&temp)
-	if err == nil || err == ErrExecutionReverted {
+	ret = interpreter.capReturnData(ret)
+	if err == nil || err == ErrExecutionReverted || err == ErrReturnDataTooLarge {
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
 	scope.Contract.Gas += returnGas
@@ -726,7 +761,8 @@ func opCallCode(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([
 		temp.SetOne()
 	}
 	stack.push(&temp)
-	if err == nil || err == ErrExecutionReverted {
+	ret = interpreter.capReturnData(ret)
+	if err == nil || err == ErrExecutionReverted || err == ErrReturnDataTooLarge {
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
 	scope.Contract.Gas += returnGas
@@ -754,7 +790,8 @@ func opDelegateCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 		temp.SetOne()
 	}
 	stack.push(&temp)
-	if err == nil || err == ErrExecutionReverted {
+	ret = interpreter.capReturnData(ret)
+	if err == nil || err == ErrExecutionReverted || err == ErrReturnDataTooLarge {
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
 	scope.Contract.Gas += returnGas
@@ -782,7 +819,8 @@ func opStaticCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 		temp.SetOne()
 	}
 	stack.push(&temp)
-	if err == nil || err == ErrExecutionReverted {
+	ret = interpreter.capReturnData(ret)
+	if err == nil || err == ErrExecutionReverted || err == ErrReturnDataTooLarge {
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
 	scope.Contract.Gas += returnGas
@@ -793,11 +831,26 @@ func opStaticCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 
 func opReturn(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	offset, size := scope.Stack.pop(), scope.Stack.pop()
+
+	// Oversized return data is truncated and reverts the frame, so the caller
+	// can still observe the truncated data.
+	if limit := interpreter.evm.Config.MaxReturnDataSize; limit != 0 && size.Uint64() > limit {
+		return scope.Memory.GetPtr(int64(offset.Uint64()), int64(limit)), ErrReturnDataTooLarge
+	}
 	ret := scope.Memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
 
 	return ret, errStopToken
 }
 
+// capReturnData truncates the data returned by a sub-call, e.g. by a precompile,
+// to the configured maximum return data size, if any.
+func (in *EVMInterpreter) capReturnData(ret []byte) []byte {
+	if limit := in.evm.Config.MaxReturnDataSize; limit != 0 && uint64(len(ret)) > limit {
+		return ret[:limit]
+	}
+	return ret
+}
+
 func opRevert(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	offset, size := scope.Stack.pop(), scope.Stack.pop()
 	ret := scope.Memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
@@ -815,9 +868,6 @@ func opStop(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byt
 }
 
 func opSelfdestruct(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
-	if interpreter.readOnly {
-		return nil, ErrWriteProtection
-	}
 	beneficiary := scope.Stack.pop()
 	balance := interpreter.evm.StateDB.GetBalance(scope.Contract.Address())
 	interpreter.evm.StateDB.AddBalance(beneficiary.Bytes20(), balance)
@@ -834,9 +884,6 @@ func opSelfdestruct(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 // make log instruction function
 func makeLog(size int) executionFunc {
 	return func(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
-		if interpreter.readOnly {
-			return nil, ErrWriteProtection
-		}
 		topics := make([]common.Hash, size)
 		stack := scope.Stack
 		mStart, mSize := stack.pop(), stack.pop()
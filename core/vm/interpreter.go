@@ -17,18 +17,83 @@
 package vm
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// defaultCancelCheckInterval is the number of opcodes between two checks for the
+// cancellation of the context of Execute, used if no YieldInterval is configured.
+const defaultCancelCheckInterval = 10000
+
 // Config are the configuration options for the Interpreter
 type Config struct {
-	Tracer                  EVMLogger // Opcode logger
-	NoBaseFee               bool      // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
-	EnablePreimageRecording bool      // Enables recording of SHA3/keccak preimages
-	ExtraEips               []int     // Additional EIPS that are to be enabled
+	Tracer                  EVMLogger                        // Opcode logger
+	NoBaseFee               bool                             // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
+	EnablePreimageRecording bool                             // Enables recording of SHA3/keccak preimages
+	ExtraEips               []int                            // Additional EIPS that are to be enabled
+	WitnessCollection       bool                             // Enables collection of the state access witness during block processing
+	MaxReturnDataSize       uint64                           // Maximum size of the data returned by a call frame, for RPC and simulation use only (0 = unlimited)
+	YieldInterval           uint64                           // Number of opcodes after which the interpreter yields the processor (0 = never)
+	Profiling               *ProfilingConfig                 // Counting of code executions, nil if disabled
+	Profile                 *ExecutionProfile                // Counting of executed opcodes, nil if disabled
+	InstructionBudget       uint64                           // Number of opcodes executable per top-level call before aborting without revert, for testing (0 = unlimited)
+	RetryHook               func(op OpCode, pc uint64) bool  // Called before executing each opcode, which is retried while it returns true
+	TraceFilter             func(tx *types.Transaction) bool // Selects the transactions traced during block processing (nil = all)
+	StepBackBuffer          uint                             // Number of last dispatched instructions retained for EVM.LastSteps (0 = disabled)
+	RecycleFrames           bool                             // Recycles the contracts and scopes of finished call frames, which tracers must not retain
+	StorageTracer           StorageLogger                    // Storage access logger, invoked by SLOAD and SSTORE without tracing every opcode
+	CheckValueOverflow      bool                             // Aborts CALLs whose value overflows the callee balance, for synthetic test states only
+}
+
+// ProfilingConfig configures the counting of code executions, allowing a JIT
+// compiler to detect the hot contracts worth compiling.
+type ProfilingConfig struct {
+	HitCounter   *HitCounter                             // Number of executions by code hash, nil if disabled
+	HitThreshold uint64                                  // Number of executions after which JITHint is called (0 = never)
+	JITHint      func(codeHash common.Hash, code []byte) // Called once when a code exceeds HitThreshold executions, possibly concurrently
+}
+
+// HitCounter counts the executions of codes by their hash. It is safe for
+// concurrent use, so that a ProfilingConfig can be shared by parallel EVMs.
+type HitCounter struct {
+	hits map[common.Hash]uint64
+	lock sync.Mutex
+}
+
+// NewHitCounter creates an empty code execution counter.
+func NewHitCounter() *HitCounter {
+	return &HitCounter{hits: make(map[common.Hash]uint64)}
+}
+
+// hit counts an execution of the code with the given hash, returning the new
+// number of executions.
+func (c *HitCounter) hit(codeHash common.Hash) uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.hits[codeHash]++
+	return c.hits[codeHash]
+}
+
+// Hits returns the number of executions counted for the code with the given hash.
+func (c *HitCounter) Hits(codeHash common.Hash) uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.hits[codeHash]
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -37,6 +102,25 @@ type ScopeContext struct {
 	Memory   *Memory
 	Stack    *Stack
 	Contract *Contract
+	Depth    int // Call depth of the executing frame, starting at 1
+
+	// FaultReason is a human readable explanation of the error that aborted
+	// the execution, set before the tracer is notified of the fault.
+	FaultReason string
+}
+
+// scopePool recycles the scopes of finished call frames if the EVM is configured
+// with RecycleFrames.
+var scopePool = sync.Pool{
+	New: func() interface{} {
+		return new(ScopeContext)
+	},
+}
+
+// CallDepth returns the nesting level of the executing frame, starting at 0 in
+// the outermost call.
+func (ctx *ScopeContext) CallDepth() int {
+	return ctx.Depth - 1
 }
 
 // EVMInterpreter represents an EVM interpreter
@@ -49,6 +133,19 @@ type EVMInterpreter struct {
 
 	readOnly   bool   // Whether to throw on stateful modifications
 	returnData []byte // Last CALL's return data for subsequent reuse
+
+	pc atomic.Uint64 // Program counter of the last dispatched opcode, for concurrent monitoring
+
+	trace    io.Writer // Destination of the low-level instruction trace, nil if disabled
+	traceBuf []byte    // Scratch buffer for formatting instruction trace lines
+
+	stepBack *stepBackBuffer // Ring buffer of the last dispatched instructions, nil if disabled
+
+	jumpdests map[common.Hash]bitvec // JUMPDEST analyses precomputed by Warmup, keyed by code hash
+
+	steps uint64 // Number of opcodes executed across all frames of the top-level call, for yielding, cancellation checks and the instruction budget
+
+	ctx context.Context // Context of the running Execute call, nil if started by Run
 }
 
 // NewEVMInterpreter returns a new instance of the Interpreter.
@@ -93,7 +190,126 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 		}
 	}
 	evm.Config.ExtraEips = extraEips
-	return &EVMInterpreter{evm: evm, table: table}
+	in := &EVMInterpreter{evm: evm, table: table}
+	if evm.Config.StepBackBuffer > 0 {
+		in.stepBack = newStepBackBuffer(evm.Config.StepBackBuffer)
+	}
+	return in
+}
+
+// Reset prepares the interpreter for running the next transaction against the
+// given transaction context and state, without reallocating it. Stacks are
+// pooled and memories allocated per call frame, so only the return data of the
+// last call and the step counter have to be dropped.
+func (in *EVMInterpreter) Reset(txCtx TxContext, statedb StateDB) {
+	in.evm.Reset(txCtx, statedb)
+	in.readOnly = false
+	in.returnData = nil
+	in.steps = 0
+}
+
+// Warmup precomputes the JUMPDEST analysis of the given code and caches it for
+// the lifetime of the interpreter. Contracts running the code reuse the cached
+// analysis instead of redoing it in every top-level call.
+func (in *EVMInterpreter) Warmup(code []byte) {
+	if in.jumpdests == nil {
+		in.jumpdests = make(map[common.Hash]bitvec)
+	}
+	in.jumpdests[crypto.Keccak256Hash(code)] = codeBitmap(code)
+}
+
+// CurrentPC returns the program counter of the opcode most recently dispatched
+// by the interpreter, in whichever call frame is executing. It is safe to call
+// concurrently with a running execution.
+func (in *EVMInterpreter) CurrentPC() uint64 {
+	return in.pc.Load()
+}
+
+// TraceInstructions enables writing a line in the format "%08d %-15s %d\n" with
+// the program counter, opcode name and gas cost of every executed instruction
+// into w, independently of any configured tracer. Passing nil disables it.
+//
+// Instructions failing before execution, e.g. running out of gas, are not
+// written. Errors returned by w are ignored.
+func (in *EVMInterpreter) TraceInstructions(w io.Writer) {
+	in.trace = w
+}
+
+// traceInstruction writes a single instruction trace line, reusing the scratch
+// buffer to avoid allocating.
+func (in *EVMInterpreter) traceInstruction(pc uint64, op OpCode, cost uint64) {
+	buf := in.traceBuf[:0]
+	for n := uint64(10_000_000); n > 1 && pc < n; n /= 10 {
+		buf = append(buf, '0')
+	}
+	buf = strconv.AppendUint(buf, pc, 10)
+	buf = append(buf, ' ')
+
+	name := op.String()
+	buf = append(buf, name...)
+	for i := len(name); i < 15; i++ {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, ' ')
+	buf = strconv.AppendUint(buf, cost, 10)
+	buf = append(buf, '\n')
+
+	in.trace.Write(buf)
+	in.traceBuf = buf
+}
+
+// ProfiledRun executes the contract like Run, while recording a CPU profile of
+// the execution into w in the pprof format. Besides the output and error of the
+// execution, it returns the amount of gas consumed.
+//
+// The profile is sampled at the default rate of the runtime, so only executions
+// lasting at least tens of milliseconds yield meaningful results. As CPU
+// profiling is process-wide, an error is returned without running the contract
+// if a profile is already being recorded.
+func (in *EVMInterpreter) ProfiledRun(contract *Contract, input []byte, readOnly bool, w io.Writer) (ret []byte, gasUsed uint64, err error) {
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return nil, 0, err
+	}
+	gas := contract.Gas
+	ret, err = in.Run(contract, input, readOnly)
+	pprof.StopCPUProfile()
+
+	return ret, gas - contract.Gas, err
+}
+
+// Execute runs the contract like Run, but aborts the execution once ctx is
+// cancelled. The context is checked every YieldInterval opcodes, or every 10000
+// opcodes if none is configured, in all frames of the execution. The error of
+// the context is returned on cancellation, which reverts the state changes like
+// any other execution error.
+func (in *EVMInterpreter) Execute(ctx context.Context, contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
+	if in.ctx == nil {
+		in.ctx = ctx
+		defer func() { in.ctx = nil }()
+	}
+	return in.Run(contract, input, readOnly)
+}
+
+// newScope creates the scope of a call frame, recycling one of a finished frame
+// if configured to.
+func (in *EVMInterpreter) newScope(mem *Memory, stack *Stack, contract *Contract) *ScopeContext {
+	var scope *ScopeContext
+	if in.evm.Config.RecycleFrames {
+		scope = scopePool.Get().(*ScopeContext)
+	} else {
+		scope = new(ScopeContext)
+	}
+	*scope = ScopeContext{Memory: mem, Stack: stack, Contract: contract, Depth: in.evm.depth}
+	return scope
+}
+
+// releaseScope hands the scope of a finished call frame back for recycling if
+// configured to.
+func (in *EVMInterpreter) releaseScope(scope *ScopeContext) {
+	if in.evm.Config.RecycleFrames {
+		*scope = ScopeContext{}
+		scopePool.Put(scope)
+	}
 }
 
 // Run loops and evaluates the contract's code with the given input data and returns
@@ -101,12 +317,20 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 //
 // It's important to note that any errors returned by the interpreter should be
 // considered a revert-and-consume-all-gas operation except for
-// ErrExecutionReverted which means revert-and-keep-gas-left.
+// ErrExecutionReverted and ErrReturnDataTooLarge which mean revert-and-keep-gas-left.
 func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
 	// Increment the call depth which is restricted to 1024
 	in.evm.depth++
 	defer func() { in.evm.depth-- }()
 
+	// Start counting and recording the instructions of a new top-level call afresh
+	if in.evm.depth == 1 {
+		in.steps = 0
+		if in.stepBack != nil {
+			in.stepBack.reset()
+		}
+	}
+
 	// Make sure the readOnly is only set if we aren't in readOnly yet.
 	// This also makes sure that the readOnly flag isn't removed for child calls.
 	if readOnly && !in.readOnly {
@@ -122,16 +346,23 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	if len(contract.Code) == 0 {
 		return nil, nil
 	}
+	if prof := in.evm.Config.Profiling; prof != nil && prof.HitCounter != nil {
+		hits := prof.HitCounter.hit(contract.CodeHash)
+		if prof.HitThreshold != 0 && hits == prof.HitThreshold+1 && prof.JITHint != nil {
+			prof.JITHint(contract.CodeHash, contract.Code)
+		}
+	}
+	if contract.analysis == nil && contract.CodeHash != (common.Hash{}) {
+		if analysis, ok := in.jumpdests[contract.CodeHash]; ok {
+			contract.WithCachedJumpdests(analysis)
+		}
+	}
 
 	var (
 		op          OpCode        // current opcode
 		mem         = NewMemory() // bound memory
 		stack       = newstack()  // local stack
-		callContext = &ScopeContext{
-			Memory:   mem,
-			Stack:    stack,
-			Contract: contract,
-		}
+		callContext = in.newScope(mem, stack, contract)
 		// For optimisation reason we're using uint64 as the program counter.
 		// It's theoretically possible to go above 2^64. The YP defines the PC
 		// to be uint256. Practically much less so feasible.
@@ -143,18 +374,34 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		logged  bool   // deferred EVMLogger should ignore already logged steps
 		res     []byte // result of the opcode execution function
 		debug   = in.evm.Config.Tracer != nil
+		yield   = in.evm.Config.YieldInterval
+		profile = in.evm.Config.Profile
+		budget  = in.evm.Config.InstructionBudget
+		retry   = in.evm.Config.RetryHook
+		step    *StepRecord     // record of the current instruction in the step back buffer
+		done    <-chan struct{} // cancellation channel of the Execute context, if any
 	)
 	// Don't move this deferred function, it's placed before the capturestate-deferred method,
 	// so that it get's executed _after_: the capturestate needs the stacks before
 	// they are returned to the pools
 	defer func() {
 		returnStack(stack)
+		in.releaseScope(callContext)
 	}()
 	contract.Input = input
 
+	if in.ctx != nil {
+		done = in.ctx.Done()
+	}
+	checkInterval := yield
+	if checkInterval == 0 {
+		checkInterval = defaultCancelCheckInterval
+	}
+
 	if debug {
 		defer func() {
 			if err != nil {
+				callContext.FaultReason = faultReason(op, err, gasCopy, cost)
 				if !logged {
 					in.evm.Config.Tracer.CaptureState(pcCopy, op, gasCopy, cost, callContext, in.returnData, in.evm.depth, err)
 				} else {
@@ -168,6 +415,25 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	// the execution of one of the operations or until the done flag is set by the
 	// parent context.
 	for {
+		if yield != 0 || done != nil || budget != 0 {
+			in.steps++
+			// Abort once the instruction budget is used up. Unlike any other
+			// error, this leaves the state changes in place and the gas unspent
+			if budget != 0 && in.steps > budget {
+				return nil, ErrBudgetExhausted
+			}
+			// Let other goroutines run during long executions
+			if yield != 0 && in.steps%yield == 0 {
+				runtime.Gosched()
+			}
+			if done != nil && in.steps%checkInterval == 0 {
+				select {
+				case <-done:
+					return nil, in.ctx.Err()
+				default:
+				}
+			}
+		}
 		if debug {
 			// Capture pre-execution values for tracing.
 			logged, pcCopy, gasCopy = false, pc, contract.Gas
@@ -175,8 +441,20 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
+		if profile != nil {
+			profile.OpcodeFrequency[op].Add(1)
+		}
 		operation := in.table[op]
+		in.pc.Store(pc)
 		cost = operation.constantGas // For tracing
+		if in.stepBack != nil {
+			step = in.stepBack.add(pc, op, contract.Gas, cost, in.evm.depth)
+		}
+		// Enforce the write protection of static calls before anything else,
+		// so that no malformed operation gets past it
+		if in.readOnly && operation.writes {
+			return nil, ErrWriteProtection
+		}
 		// Validate stack
 		if sLen := stack.len(); sLen < operation.minStack {
 			return nil, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
@@ -209,6 +487,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			var dynamicCost uint64
 			dynamicCost, err = operation.dynamicGas(in.evm, contract, stack, mem, memorySize)
 			cost += dynamicCost // for tracing
+			if step != nil {
+				step.Cost = cost
+			}
 			if err != nil || !contract.UseGas(dynamicCost) {
 				return nil, ErrOutOfGas
 			}
@@ -224,6 +505,17 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			in.evm.Config.Tracer.CaptureState(pc, op, gasCopy, cost, callContext, in.returnData, in.evm.depth, err)
 			logged = true
 		}
+		// Let the retry hook hold the operation back, e.g. until a conflicting
+		// state access is resolved. Every retry is reported to the tracer as a
+		// new attempt of the same step, without charging the gas again.
+		for retry != nil && retry(op, pc) {
+			if debug {
+				in.evm.Config.Tracer.CaptureState(pc, op, gasCopy, cost, callContext, in.returnData, in.evm.depth, err)
+			}
+		}
+		if in.trace != nil {
+			in.traceInstruction(pc, op, cost)
+		}
 		// execute the operation
 		res, err = operation.execute(&pc, in, callContext)
 		if err != nil {
@@ -238,3 +530,22 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 
 	return res, err
 }
+
+// faultReason describes why executing op failed with err, given the gas that
+// was available to the operation and its (possibly partial) cost.
+func faultReason(op OpCode, err error, gas, cost uint64) string {
+	var (
+		underflow *ErrStackUnderflow
+		overflow  *ErrStackOverflow
+	)
+	switch {
+	case errors.As(err, &underflow):
+		return fmt.Sprintf("stack underflow on %v: need %d items, have %d", op, underflow.required, underflow.stackLen)
+	case errors.As(err, &overflow):
+		return fmt.Sprintf("stack overflow on %v: limit %d items, have %d", op, overflow.limit, overflow.stackLen)
+	case errors.Is(err, ErrOutOfGas) && cost > gas:
+		return fmt.Sprintf("out of gas on %v: need %d, have %d (short by %d)", op, cost, gas, cost-gas)
+	default:
+		return fmt.Sprintf("%v on %v", err, op)
+	}
+}
@@ -17,7 +17,16 @@
 package vm
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math/big"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -35,25 +44,43 @@ var loopInterruptTests = []string{
 	"600160045b818157",
 }
 
-func TestLoopInterrupt(t *testing.T) {
-	address := common.BytesToAddress([]byte("contract"))
+// testContractAddress is the address the code run by the interpreter tests is
+// deployed at.
+var testContractAddress = common.BytesToAddress([]byte("contract"))
+
+// newTestEVM creates an EVM over a fresh state holding the given code at
+// testContractAddress. Value transfers are no-ops, so the contract can be called
+// without funding the caller.
+func newTestEVM(code []byte, config Config) *EVM {
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.CreateAccount(testContractAddress)
+	statedb.SetCode(testContractAddress, code)
+	statedb.Finalise(true)
+
 	vmctx := BlockContext{
-		Transfer: func(StateDB, common.Address, common.Address, *big.Int) {},
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
 	}
+	return NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, config)
+}
 
-	for i, tt := range loopInterruptTests {
-		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
-		statedb.CreateAccount(address)
-		statedb.SetCode(address, common.Hex2Bytes(tt))
-		statedb.Finalise(true)
+// newTestContract creates a contract running the code deployed by newTestEVM
+// with the given amount of gas.
+func newTestContract(evm *EVM, gas uint64) *Contract {
+	contract := NewContract(AccountRef(common.Address{}), AccountRef(testContractAddress), new(big.Int), gas)
+	contract.SetCallCode(&testContractAddress, evm.StateDB.GetCodeHash(testContractAddress), evm.StateDB.GetCode(testContractAddress))
+	return contract
+}
 
-		evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{})
+func TestLoopInterrupt(t *testing.T) {
+	for i, tt := range loopInterruptTests {
+		evm := newTestEVM(common.Hex2Bytes(tt), Config{})
 
 		errChannel := make(chan error)
 		timeout := make(chan bool)
 
 		go func(evm *EVM) {
-			_, _, err := evm.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+			_, _, err := evm.Call(AccountRef(common.Address{}), testContractAddress, nil, math.MaxUint64, new(big.Int))
 			errChannel <- err
 		}(evm)
 
@@ -74,3 +101,367 @@ func TestLoopInterrupt(t *testing.T) {
 		}
 	}
 }
+
+func TestExecuteCancel(t *testing.T) {
+	for i, tt := range loopInterruptTests {
+		var (
+			evm      = newTestEVM(common.Hex2Bytes(tt), Config{})
+			contract = newTestContract(evm, math.MaxUint64)
+		)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		errChannel := make(chan error)
+		go func() {
+			_, err := evm.Interpreter().Execute(ctx, contract, nil, false)
+			errChannel <- err
+		}()
+		select {
+		case <-time.After(time.Second):
+			t.Errorf("test %d timed out", i)
+		case err := <-errChannel:
+			if err != context.DeadlineExceeded {
+				t.Errorf("test %d: error mismatch: have %v, want %v", i, err, context.DeadlineExceeded)
+			}
+		}
+		cancel()
+	}
+}
+
+// Tests that the program counter can be sampled while the interpreter is
+// running. Run with -race to detect unsynchronised access.
+func TestCurrentPCConcurrent(t *testing.T) {
+	evm := newTestEVM(common.Hex2Bytes(loopInterruptTests[0]), Config{})
+
+	done := make(chan struct{})
+	go func() {
+		evm.Call(AccountRef(common.Address{}), testContractAddress, nil, math.MaxUint64, new(big.Int))
+		close(done)
+	}()
+	// The loop body spans the instructions at pc 2 (JUMPDEST) to 4 (JUMP)
+	deadline := time.After(100 * time.Millisecond)
+	for sampling := true; sampling; {
+		select {
+		case <-deadline:
+			sampling = false
+		default:
+			if pc := evm.Interpreter().CurrentPC(); pc > 4 {
+				t.Fatalf("unexpected program counter %d", pc)
+			}
+		}
+	}
+	evm.Cancel()
+	<-done
+}
+
+func TestProfiledRun(t *testing.T) {
+	var (
+		evm      = newTestEVM(common.Hex2Bytes(loopInterruptTests[0]), Config{})
+		contract = newTestContract(evm, 10_000_000)
+		profile  bytes.Buffer
+	)
+	// The infinite loop runs until all the gas is consumed
+	_, gasUsed, err := evm.Interpreter().ProfiledRun(contract, nil, false, &profile)
+	if err != ErrOutOfGas {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrOutOfGas)
+	}
+	if gasUsed != 10_000_000 {
+		t.Fatalf("gas used mismatch: have %d, want %d", gasUsed, 10_000_000)
+	}
+	// The profile is gzip compressed protobuf
+	if blob := profile.Bytes(); len(blob) < 2 || blob[0] != 0x1f || blob[1] != 0x8b {
+		t.Fatalf("invalid profile: %x", blob)
+	}
+}
+
+func TestProfilingHitCounter(t *testing.T) {
+	var (
+		hints []common.Hash
+		prof  = &ProfilingConfig{
+			HitCounter:   NewHitCounter(),
+			HitThreshold: 2,
+			JITHint: func(codeHash common.Hash, code []byte) {
+				hints = append(hints, codeHash)
+			},
+		}
+		// push(32) push(1024) mstore push(0) push(0) return
+		evm = newTestEVM(common.Hex2Bytes("60206104005260006000f3"), Config{Profiling: prof})
+	)
+	for i := 0; i < 5; i++ {
+		if _, err := evm.Interpreter().Run(newTestContract(evm, 100000), nil, false); err != nil {
+			t.Fatal(err)
+		}
+		if i < 2 && len(hints) != 0 {
+			t.Fatalf("call %d: hint before exceeding the threshold", i)
+		}
+	}
+	codeHash := evm.StateDB.GetCodeHash(testContractAddress)
+	if hits := prof.HitCounter.Hits(codeHash); hits != 5 {
+		t.Fatalf("hit count mismatch: have %d, want 5", hits)
+	}
+	if len(hints) != 1 || hints[0] != codeHash {
+		t.Fatalf("hint mismatch: have %v, want [%x]", hints, codeHash)
+	}
+}
+
+// Tests that state modifications in static calls are rejected before the stack
+// is validated, so a missing operand doesn't mask the write protection error.
+func TestWriteProtectionBeforeStackValidation(t *testing.T) {
+	for _, op := range []OpCode{SSTORE, LOG0, LOG4, CREATE, CREATE2, SELFDESTRUCT} {
+		evm := newTestEVM([]byte{byte(op)}, Config{})
+		if _, err := evm.Interpreter().Run(newTestContract(evm, 100000), nil, true); err != ErrWriteProtection {
+			t.Errorf("%v: error mismatch: have %v, want %v", op, err, ErrWriteProtection)
+		}
+		// Outside of static calls the missing operands are reported
+		var underflow *ErrStackUnderflow
+		if _, err := evm.Interpreter().Run(newTestContract(evm, 100000), nil, false); !errors.As(err, &underflow) {
+			t.Errorf("%v: error mismatch: have %v, want stack underflow", op, err)
+		}
+	}
+}
+
+func TestExecutionProfile(t *testing.T) {
+	var (
+		profile = new(ExecutionProfile)
+		// push(1) push(2) add dup1 mul push(0) mstore push(32) push(0) return
+		evm = newTestEVM(common.Hex2Bytes("6001600201800260005260206000f3"), Config{Profile: profile})
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := evm.Interpreter().Run(newTestContract(evm, 100000), nil, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]uint64{"PUSH1": 10, "ADD": 2, "DUP1": 2, "MUL": 2, "MSTORE": 2, "RETURN": 2}
+	if have := profile.Report(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("report mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestRetryHook(t *testing.T) {
+	run := func(hook func(op OpCode, pc uint64) bool) ([]byte, uint64) {
+		var (
+			// push(1) push(2) add dup1 mul push(0) mstore push(32) push(0) return
+			evm      = newTestEVM(common.Hex2Bytes("6001600201800260005260206000f3"), Config{RetryHook: hook})
+			contract = newTestContract(evm, 100000)
+		)
+		ret, err := evm.Interpreter().Run(contract, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ret, 100000 - contract.Gas
+	}
+	// Retry the MUL a few times as if a conflict was detected
+	const retries = 3
+	attempts := make(map[OpCode]int)
+	ret, gas := run(func(op OpCode, pc uint64) bool {
+		attempts[op]++
+		return op == MUL && attempts[op] <= retries
+	})
+	if attempts[MUL] != retries+1 {
+		t.Errorf("MUL attempt count mismatch: have %d, want %d", attempts[MUL], retries+1)
+	}
+	if attempts[ADD] != 1 {
+		t.Errorf("ADD attempt count mismatch: have %d, want 1", attempts[ADD])
+	}
+	// Retries must neither change the result nor cost extra gas
+	wantRet, wantGas := run(nil)
+	if !bytes.Equal(ret, wantRet) {
+		t.Errorf("result mismatch: have %x, want %x", ret, wantRet)
+	}
+	if gas != wantGas {
+		t.Errorf("gas mismatch: have %d, want %d", gas, wantGas)
+	}
+}
+
+// Tests that a profiling config can be shared by EVMs running in parallel.
+func TestProfilingHitCounterConcurrent(t *testing.T) {
+	var (
+		hints atomic.Int32
+		prof  = &ProfilingConfig{
+			HitCounter:   NewHitCounter(),
+			HitThreshold: 10,
+			JITHint: func(common.Hash, []byte) {
+				hints.Add(1)
+			},
+		}
+		codeHash common.Hash
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 2; i++ {
+		// push(32) push(1024) mstore push(0) push(0) return
+		evm := newTestEVM(common.Hex2Bytes("60206104005260006000f3"), Config{Profiling: prof})
+		codeHash = evm.StateDB.GetCodeHash(testContractAddress)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := evm.Interpreter().Run(newTestContract(evm, 100000), nil, false); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hits := prof.HitCounter.Hits(codeHash); hits != 200 {
+		t.Fatalf("hit count mismatch: have %d, want 200", hits)
+	}
+	if n := hints.Load(); n != 1 {
+		t.Fatalf("hint count mismatch: have %d, want 1", n)
+	}
+}
+
+func TestTraceInstructions(t *testing.T) {
+	var (
+		// push(1) push(2) add dup1 mul push(0) mstore push(32) push(0) return
+		evm      = newTestEVM(common.Hex2Bytes("6001600201800260005260206000f3"), Config{})
+		contract = newTestContract(evm, 100_000)
+		trace    bytes.Buffer
+	)
+	evm.Interpreter().TraceInstructions(&trace)
+	ret, err := evm.Interpreter().Run(contract, nil, false)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret); have.Uint64() != 9 {
+		t.Fatalf("return value mismatch: have %v, want 9", have)
+	}
+	want := `00000000 PUSH1           3
+00000002 PUSH1           3
+00000004 ADD             3
+00000005 DUP1            3
+00000006 MUL             5
+00000007 PUSH1           3
+00000009 MSTORE          6
+00000010 PUSH1           3
+00000012 PUSH1           3
+00000014 RETURN          0
+`
+	if have := trace.String(); have != want {
+		t.Fatalf("trace mismatch:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+	// Writing a trace line should not allocate once the buffer is warmed up
+	evm.Interpreter().TraceInstructions(io.Discard)
+	if allocs := testing.AllocsPerRun(100, func() {
+		evm.Interpreter().traceInstruction(12345678, PUSH32, 1<<40)
+	}); allocs != 0 {
+		t.Fatalf("instruction tracing allocates: %v allocs per line", allocs)
+	}
+	// Disabling the trace should stop writing
+	trace.Reset()
+	evm.Interpreter().TraceInstructions(nil)
+	contract.Gas = 100_000
+	if _, err := evm.Interpreter().Run(contract, nil, false); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if trace.Len() != 0 {
+		t.Fatalf("disabled trace written: %q", trace.String())
+	}
+}
+
+// This measures the allocations of executing many short calls in a row, as done
+// by batched script execution.
+func BenchmarkInterpreterBatch(b *testing.B) {
+	// push(32) push(1024) mstore push(0) push(0) return
+	evm := newTestEVM(common.Hex2Bytes("60206104005260006000f3"), Config{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := evm.Call(AccountRef(common.Address{}), testContractAddress, nil, 100000, new(big.Int)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// This measures the allocations of running a batch of transactions through the
+// same interpreter, resetting it in between.
+func BenchmarkInterpreterReset(b *testing.B) {
+	// push(32) push(1024) mstore push(0) push(0) return
+	evm := newTestEVM(common.Hex2Bytes("60206104005260006000f3"), Config{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evm.Interpreter().Reset(TxContext{Origin: common.Address{byte(i)}}, evm.StateDB)
+		if _, _, err := evm.Call(AccountRef(common.Address{}), testContractAddress, nil, 100000, new(big.Int)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// This measures the first call into a maximum size contract, with and without
+// the JUMPDEST analysis precomputed by Warmup.
+func BenchmarkInterpreterWarmup(b *testing.B) {
+	// push(3) jump jumpdest stop, padded with data to the maximum code size
+	code := make([]byte, params.MaxCodeSize)
+	copy(code, common.Hex2Bytes("6003565b00"))
+	for i := 5; i < len(code); i++ {
+		code[i] = byte(PUSH1)
+	}
+	for _, warm := range []bool{false, true} {
+		name := "cold"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			evm := newTestEVM(code, Config{})
+			if warm {
+				evm.Interpreter().Warmup(code)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := evm.Call(AccountRef(common.Address{}), testContractAddress, nil, 100000, new(big.Int)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// This measures the worst latency seen by a concurrent goroutine sharing the
+// processor with a loop of about a million opcodes, with and without yielding.
+func BenchmarkInterpreterYield(b *testing.B) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	// push(142857) jumpdest push(1) swap1 sub dup1 push(4) jumpi stop
+	code := common.Hex2Bytes("62022e095b600190038060045700")
+
+	for _, interval := range []uint64{0, 1000} {
+		b.Run(fmt.Sprintf("interval-%d", interval), func(b *testing.B) {
+			evm := newTestEVM(code, Config{YieldInterval: interval})
+
+			var worst time.Duration
+			for i := 0; i < b.N; i++ {
+				var (
+					stop = make(chan struct{})
+					done = make(chan time.Duration)
+				)
+				go func() {
+					var max time.Duration
+					for last := time.Now(); ; last = time.Now() {
+						select {
+						case <-stop:
+							done <- max
+							return
+						default:
+						}
+						runtime.Gosched()
+						if elapsed := time.Since(last); elapsed > max {
+							max = elapsed
+						}
+					}
+				}()
+				if _, _, err := evm.Call(AccountRef(common.Address{}), testContractAddress, nil, math.MaxUint64, new(big.Int)); err != nil {
+					b.Fatal(err)
+				}
+				close(stop)
+				if max := <-done; max > worst {
+					worst = max
+				}
+			}
+			b.ReportMetric(float64(worst.Microseconds()), "max-latency-µs")
+		})
+	}
+}
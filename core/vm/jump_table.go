@@ -42,6 +42,11 @@ type operation struct {
 
 	// memorySize returns the memory size required for the operation
 	memorySize memorySizeFunc
+
+	// undefined denotes if the instruction is not officially defined in the jump table
+	undefined bool
+	// writes denotes whether the operation modifies the state, forbidden in static calls
+	writes bool
 }
 
 var (
@@ -161,6 +166,7 @@ func newConstantinopleInstructionSet() JumpTable {
 		minStack:    minStack(4, 1),
 		maxStack:    maxStack(4, 1),
 		memorySize:  memoryCreate2,
+		writes:      true,
 	}
 	return validate(instructionSet)
 }
@@ -547,6 +553,7 @@ func newFrontierInstructionSet() JumpTable {
 			dynamicGas: gasSStore,
 			minStack:   minStack(2, 0),
 			maxStack:   maxStack(2, 0),
+			writes:     true,
 		},
 		JUMP: {
 			execute:     opJump,
@@ -974,6 +981,7 @@ func newFrontierInstructionSet() JumpTable {
 			minStack:   minStack(2, 0),
 			maxStack:   maxStack(2, 0),
 			memorySize: memoryLog,
+			writes:     true,
 		},
 		LOG1: {
 			execute:    makeLog(1),
@@ -981,6 +989,7 @@ func newFrontierInstructionSet() JumpTable {
 			minStack:   minStack(3, 0),
 			maxStack:   maxStack(3, 0),
 			memorySize: memoryLog,
+			writes:     true,
 		},
 		LOG2: {
 			execute:    makeLog(2),
@@ -988,6 +997,7 @@ func newFrontierInstructionSet() JumpTable {
 			minStack:   minStack(4, 0),
 			maxStack:   maxStack(4, 0),
 			memorySize: memoryLog,
+			writes:     true,
 		},
 		LOG3: {
 			execute:    makeLog(3),
@@ -995,6 +1005,7 @@ func newFrontierInstructionSet() JumpTable {
 			minStack:   minStack(5, 0),
 			maxStack:   maxStack(5, 0),
 			memorySize: memoryLog,
+			writes:     true,
 		},
 		LOG4: {
 			execute:    makeLog(4),
@@ -1002,6 +1013,7 @@ func newFrontierInstructionSet() JumpTable {
 			minStack:   minStack(6, 0),
 			maxStack:   maxStack(6, 0),
 			memorySize: memoryLog,
+			writes:     true,
 		},
 		CREATE: {
 			execute:     opCreate,
@@ -1010,6 +1022,7 @@ func newFrontierInstructionSet() JumpTable {
 			minStack:    minStack(3, 1),
 			maxStack:    maxStack(3, 1),
 			memorySize:  memoryCreate,
+			writes:      true,
 		},
 		CALL: {
 			execute:     opCall,
@@ -1039,13 +1052,14 @@ func newFrontierInstructionSet() JumpTable {
 			dynamicGas: gasSelfdestruct,
 			minStack:   minStack(1, 0),
 			maxStack:   maxStack(1, 0),
+			writes:     true,
 		},
 	}
 
 	// Fill all unassigned slots with opUndefined.
 	for i, entry := range tbl {
 		if entry == nil {
-			tbl[i] = &operation{execute: opUndefined, maxStack: maxStack(0, 0)}
+			tbl[i] = &operation{execute: opUndefined, maxStack: maxStack(0, 0), undefined: true}
 		}
 	}
 
@@ -26,7 +26,8 @@ import (
 // execution. CaptureState is called for each step of the VM with the
 // current VM state.
 // Note that reference types are actual VM data structures; make copies
-// if you need to retain them beyond the current call.
+// if you need to retain them beyond the current call. Stack items should be
+// read through Stack.Back and Stack.Len, or copied out with Stack.ToSlice.
 type EVMLogger interface {
 	// Transaction level
 	CaptureTxStart(gasLimit uint64)
@@ -40,4 +41,16 @@ type EVMLogger interface {
 	// Opcode level
 	CaptureState(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, rData []byte, depth int, err error)
 	CaptureFault(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, depth int, err error)
+	// State key hashing
+	CapturePreimage(hash common.Hash, preimage []byte)
+	// Storage level, invoked by SLOAD and SSTORE
+	StorageLogger
+}
+
+// StorageLogger is used to collect the storage slots read and written by an
+// execution. It can be installed on its own through Config.StorageTracer, which
+// unlike an EVMLogger doesn't make the interpreter capture every opcode.
+type StorageLogger interface {
+	CaptureStorageRead(addr common.Address, key, value common.Hash)
+	CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash)
 }
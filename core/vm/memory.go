@@ -20,6 +20,9 @@ import (
 	"github.com/holiman/uint256"
 )
 
+// zeroSlice is a source of zero bytes for clearing memory in chunks.
+var zeroSlice = make([]byte, 4096)
+
 // Memory implements a simple memory model for the ethereum virtual machine.
 type Memory struct {
 	store       []byte
@@ -45,6 +48,34 @@ func (m *Memory) Set(offset, size uint64, value []byte) {
 	}
 }
 
+// WriteZero zeroes offset + size without allocating a zero-filled value.
+func (m *Memory) WriteZero(offset, size uint64) {
+	if size > 0 {
+		// length of store may never be less than offset + size.
+		// The store should be resized PRIOR to setting the memory
+		if offset+size > uint64(len(m.store)) {
+			panic("invalid memory: store empty")
+		}
+		for dst := m.store[offset : offset+size]; len(dst) > 0; {
+			dst = dst[copy(dst, zeroSlice):]
+		}
+	}
+}
+
+// FastCopy copies size bytes from src to dst within the memory, as needed by
+// MCOPY (EIP-5656), without allocating. The ranges may overlap: the builtin copy
+// has memmove semantics, so no intermediate buffer is needed either.
+func (m *Memory) FastCopy(dst, src, size uint64) {
+	if size > 0 {
+		// length of store may never be less than offset + size.
+		// The store should be resized PRIOR to setting the memory
+		if dst+size > uint64(len(m.store)) || src+size > uint64(len(m.store)) {
+			panic("invalid memory: store empty")
+		}
+		copy(m.store[dst:dst+size], m.store[src:src+size])
+	}
+}
+
 // Set32 sets the 32 bytes starting at offset to the value of val, left-padded with zeroes to
 // 32 bytes.
 func (m *Memory) Set32(offset uint64, val *uint256.Int) {
@@ -0,0 +1,93 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestMemoryWriteZero(t *testing.T) {
+	// Span several zero chunks and leave dirty bytes on both sides
+	size := uint64(3*len(zeroSlice) + 7)
+
+	mem := NewMemory()
+	mem.Resize(size + 2)
+	mem.Set(0, size+2, bytes.Repeat([]byte{0xff}, int(size+2)))
+	mem.WriteZero(1, size)
+
+	data := mem.Data()
+	if data[0] != 0xff || data[size+1] != 0xff {
+		t.Fatalf("bytes outside the range modified: %x, %x", data[0], data[size+1])
+	}
+	if !bytes.Equal(data[1:size+1], make([]byte, size)) {
+		t.Fatalf("range not zeroed")
+	}
+	// A zero-sized write is a no-op, regardless of the offset
+	mem.WriteZero(size*2, 0)
+}
+
+func TestMemoryFastCopy(t *testing.T) {
+	const size = 256
+
+	var (
+		rng   = rand.New(rand.NewSource(1))
+		mem   = NewMemory()
+		model = make([]byte, size)
+	)
+	defer mem.Free()
+	mem.Resize(size)
+
+	rng.Read(model)
+	mem.Set(0, size, model)
+	for i := 0; i < 10000; i++ {
+		var (
+			length = uint64(rng.Intn(size / 2))
+			src    = uint64(rng.Intn(size - int(length) + 1))
+			dst    = uint64(rng.Intn(size - int(length) + 1))
+		)
+		buf := append([]byte(nil), model[src:src+length]...)
+		copy(model[dst:], buf)
+
+		mem.FastCopy(dst, src, length)
+		if !bytes.Equal(mem.Data(), model) {
+			t.Fatalf("copy %d (%d bytes from %d to %d): memory mismatch\nhave %x\nwant %x", i, length, src, dst, mem.Data(), model)
+		}
+	}
+}
+
+func BenchmarkMemoryWriteZero(b *testing.B) {
+	mem := NewMemory()
+	mem.Resize(32768)
+
+	// Not a constant, so the benchmark allocates like the opcodes would
+	size := uint64(mem.Len())
+
+	b.Run("zero", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mem.WriteZero(0, size)
+		}
+	})
+	b.Run("make+copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mem.Set(0, size, make([]byte, size))
+		}
+	})
+}
@@ -28,6 +28,67 @@ func (op OpCode) IsPush() bool {
 	return PUSH1 <= op && op <= PUSH32
 }
 
+// The category classifiers below partition all defined opcodes: every opcode
+// belongs to exactly one category.
+
+// IsArithmetic specifies if an opcode is an arithmetic operation, including
+// KECCAK256.
+func (op OpCode) IsArithmetic() bool {
+	return (ADD <= op && op <= SIGNEXTEND) || op == KECCAK256
+}
+
+// IsComparison specifies if an opcode is a comparison operation.
+func (op OpCode) IsComparison() bool {
+	return LT <= op && op <= ISZERO
+}
+
+// IsBitwise specifies if an opcode is a bitwise logic operation.
+func (op OpCode) IsBitwise() bool {
+	return AND <= op && op <= SAR
+}
+
+// IsEnvironment specifies if an opcode queries the execution environment.
+func (op OpCode) IsEnvironment() bool {
+	return (ADDRESS <= op && op <= EXTCODEHASH) || op == GAS
+}
+
+// IsBlock specifies if an opcode queries block information.
+func (op OpCode) IsBlock() bool {
+	return BLOCKHASH <= op && op <= BASEFEE
+}
+
+// IsStack specifies if an opcode only manipulates the stack. PUSH0 belongs
+// here as it has no immediate, unlike the opcodes matched by IsPush.
+func (op OpCode) IsStack() bool {
+	return op == POP || op == PUSH0 || (DUP1 <= op && op <= SWAP16)
+}
+
+// IsMemory specifies if an opcode accesses memory.
+func (op OpCode) IsMemory() bool {
+	return (MLOAD <= op && op <= MSTORE8) || op == MSIZE
+}
+
+// IsStorage specifies if an opcode accesses persistent or transient storage.
+func (op OpCode) IsStorage() bool {
+	return op == SLOAD || op == SSTORE || op == TLOAD || op == TSTORE
+}
+
+// IsControl specifies if an opcode alters the control flow, including calls,
+// contract creation and execution halting.
+func (op OpCode) IsControl() bool {
+	switch op {
+	case STOP, JUMP, JUMPI, PC, JUMPDEST,
+		CREATE, CALL, CALLCODE, RETURN, DELEGATECALL, CREATE2, STATICCALL, REVERT, INVALID, SELFDESTRUCT:
+		return true
+	}
+	return false
+}
+
+// IsLog specifies if an opcode emits a log.
+func (op OpCode) IsLog() bool {
+	return LOG0 <= op && op <= LOG4
+}
+
 // 0x0 range - arithmetic ops.
 const (
 	STOP       OpCode = 0x0
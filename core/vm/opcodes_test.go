@@ -0,0 +1,48 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that every defined opcode belongs to exactly one category, and that
+// undefined opcodes belong to none.
+func TestOpCodeCategories(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		op := OpCode(i)
+		categories := []bool{
+			op.IsArithmetic(), op.IsComparison(), op.IsBitwise(), op.IsEnvironment(),
+			op.IsBlock(), op.IsStack(), op.IsMemory(), op.IsPush(), op.IsStorage(),
+			op.IsControl(), op.IsLog(),
+		}
+		var matches int
+		for _, match := range categories {
+			if match {
+				matches++
+			}
+		}
+		want := 1
+		if strings.HasSuffix(op.String(), "not defined") {
+			want = 0
+		}
+		if matches != want {
+			t.Errorf("opcode %v: matched %d categories, want %d", op, matches, want)
+		}
+	}
+}
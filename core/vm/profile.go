@@ -0,0 +1,38 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import "sync/atomic"
+
+// ExecutionProfile counts the opcodes executed by the interpreter, providing a
+// cheap alternative to a tracer for performance profiling. The counters are
+// updated atomically, so a profile may be shared by concurrently running EVMs.
+type ExecutionProfile struct {
+	OpcodeFrequency [256]atomic.Uint64 // Number of executions by opcode
+}
+
+// Report returns the number of executions of every opcode executed at least
+// once, keyed by opcode name.
+func (p *ExecutionProfile) Report() map[string]uint64 {
+	report := make(map[string]uint64)
+	for op := range p.OpcodeFrequency {
+		if count := p.OpcodeFrequency[op].Load(); count > 0 {
+			report[OpCode(op).String()] = count
+		}
+	}
+	return report
+}
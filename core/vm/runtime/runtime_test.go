@@ -17,9 +17,12 @@
 package runtime
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -103,7 +106,7 @@ func TestExecute(t *testing.T) {
 }
 
 func TestCall(t *testing.T) {
-	state, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	state := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	address := common.HexToAddress("0x0a")
 	state.SetCode(address, []byte{
 		byte(vm.PUSH1), 10,
@@ -125,6 +128,267 @@ func TestCall(t *testing.T) {
 	}
 }
 
+// Tests that return data is unlimited by default, as required by consensus.
+func TestReturnDataUnlimited(t *testing.T) {
+	// Return 300KB of zeroes
+	returner := []byte{
+		byte(vm.PUSH3), 0x04, 0xb0, 0x00,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	cfg := &Config{State: state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase())), GasLimit: 100_000_000}
+	ret, _, err := Execute(returner, nil, cfg)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if len(ret) != 300*1024 {
+		t.Fatalf("return data size mismatch: have %d, want %d", len(ret), 300*1024)
+	}
+}
+
+// Tests that return data exceeding the configured limit is truncated and
+// reverts the returning frame.
+func TestReturnDataLimit(t *testing.T) {
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	// Return 1MB of zeroes
+	returner := []byte{
+		byte(vm.PUSH3), 0x10, 0x00, 0x00,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	statedb.SetCode(common.HexToAddress("0xbb"), returner)
+
+	ret, _, err := Execute(returner, nil, &Config{State: statedb.Copy(), EVMConfig: vm.Config{MaxReturnDataSize: 256 * 1024}})
+	if err != vm.ErrReturnDataTooLarge {
+		t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrReturnDataTooLarge)
+	}
+	if len(ret) != 256*1024 {
+		t.Fatalf("return data size mismatch: have %d, want %d", len(ret), 256*1024)
+	}
+	// Call the returner and return the call status along with the size of
+	// the return data observed by the caller
+	caller := []byte{
+		byte(vm.PUSH1), 0,
+		byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.RETURNDATASIZE), byte(vm.PUSH1), 32, byte(vm.MSTORE),
+		byte(vm.PUSH1), 64, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	ret, _, err = Execute(caller, nil, &Config{State: statedb.Copy(), EVMConfig: vm.Config{MaxReturnDataSize: 1024}})
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if status := new(big.Int).SetBytes(ret[:32]); status.Sign() != 0 {
+		t.Errorf("call status mismatch: have %v, want 0", status)
+	}
+	if size := new(big.Int).SetBytes(ret[32:]); size.Cmp(big.NewInt(1024)) != 0 {
+		t.Errorf("return data size mismatch: have %v, want 1024", size)
+	}
+}
+
+func TestCallValueOverflow(t *testing.T) {
+	// Send 1 wei to an account holding the maximum balance, returning the
+	// call status
+	caller := []byte{
+		byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	maxBalance := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	for _, check := range []bool{false, true} {
+		statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		statedb.SetBalance(common.BytesToAddress([]byte("contract")), big.NewInt(1))
+		statedb.SetBalance(common.HexToAddress("0xbb"), maxBalance)
+
+		ret, _, err := Execute(caller, nil, &Config{State: statedb, EVMConfig: vm.Config{CheckValueOverflow: check}})
+		if check {
+			if err != vm.ErrValueOverflow {
+				t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrValueOverflow)
+			}
+			if balance := statedb.GetBalance(common.HexToAddress("0xbb")); balance.Cmp(maxBalance) != 0 {
+				t.Fatalf("callee balance modified: %v", balance)
+			}
+			continue
+		}
+		// Without the opt-in check the transfer goes through as before
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		if status := new(big.Int).SetBytes(ret); status.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("call status mismatch: have %v, want 1", status)
+		}
+		if balance := statedb.GetBalance(common.HexToAddress("0xbb")); balance.Cmp(new(big.Int).Add(maxBalance, big.NewInt(1))) != 0 {
+			t.Errorf("callee balance mismatch: have %v, want %v+1", balance, maxBalance)
+		}
+	}
+}
+
+func TestBaseFee(t *testing.T) {
+	code := []byte{
+		byte(vm.BASEFEE),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	// The base fee of the block context must be pushed onto the stack
+	baseFee := big.NewInt(7 * params.GWei)
+	ret, _, err := Execute(code, nil, &Config{BaseFee: baseFee})
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret); have.Cmp(baseFee) != 0 {
+		t.Errorf("base fee mismatch: have %v, want %v", have, baseFee)
+	}
+	// Without an explicit base fee, the default one is used
+	ret, _, err = Execute(code, nil, nil)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret); have.Cmp(big.NewInt(params.InitialBaseFee)) != 0 {
+		t.Errorf("default base fee mismatch: have %v, want %v", have, params.InitialBaseFee)
+	}
+	// BASEFEE is undefined before London
+	berlin := &params.ChainConfig{
+		ChainID:             big.NewInt(1),
+		HomesteadBlock:      new(big.Int),
+		EIP150Block:         new(big.Int),
+		EIP155Block:         new(big.Int),
+		EIP158Block:         new(big.Int),
+		ByzantiumBlock:      new(big.Int),
+		ConstantinopleBlock: new(big.Int),
+		PetersburgBlock:     new(big.Int),
+		IstanbulBlock:       new(big.Int),
+		BerlinBlock:         new(big.Int),
+	}
+	_, _, err = Execute(code, nil, &Config{ChainConfig: berlin, BaseFee: baseFee})
+	if invalid := new(vm.ErrInvalidOpCode); !errors.As(err, &invalid) {
+		t.Fatalf("error mismatch: have %v, want invalid opcode", err)
+	}
+}
+
+func TestInstructionBudget(t *testing.T) {
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	address := common.HexToAddress("0x0a")
+	// Store a slot, then loop forever
+	statedb.SetCode(address, []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.JUMPDEST), byte(vm.PUSH1), 5, byte(vm.JUMP),
+	})
+	cfg := &Config{State: statedb, GasLimit: 1_000_000, EVMConfig: vm.Config{InstructionBudget: 100}}
+	_, leftOverGas, err := Call(address, nil, cfg)
+	if err != vm.ErrBudgetExhausted {
+		t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrBudgetExhausted)
+	}
+	// Unlike other errors, the state changes are kept and the gas isn't spent
+	if value := statedb.GetState(address, common.Hash{}); value != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("storage reverted: have %x, want 1", value)
+	}
+	if leftOverGas == 0 {
+		t.Error("remaining gas consumed")
+	}
+}
+
+// Tests that the instruction budget applies to every top-level call separately,
+// instead of being used up across the calls made on the same EVM.
+func TestInstructionBudgetPerCall(t *testing.T) {
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	address := common.HexToAddress("0x0a")
+
+	// Execute 70 instructions per call, more than half of the budget
+	var code []byte
+	for i := 0; i < 35; i++ {
+		code = append(code, byte(vm.PUSH1), 0, byte(vm.POP))
+	}
+	statedb.SetCode(address, code)
+
+	cfg := &Config{State: statedb, GasLimit: 1_000_000, EVMConfig: vm.Config{InstructionBudget: 100}}
+	setDefaults(cfg)
+	vmenv := NewEnv(cfg)
+	sender := vm.AccountRef(cfg.Origin)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := vmenv.Call(sender, address, nil, cfg.GasLimit, new(big.Int)); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestTraceFilter(t *testing.T) {
+	var (
+		statedb = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		address = common.HexToAddress("0x0a")
+		tracer  = logger.NewStructLogger(nil)
+		traced  = types.NewTransaction(0, address, new(big.Int), 100000, new(big.Int), nil)
+		skipped = types.NewTransaction(1, address, new(big.Int), 100000, new(big.Int), nil)
+	)
+	statedb.SetCode(address, []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 2, byte(vm.ADD), byte(vm.STOP)})
+
+	cfg := &Config{State: statedb, GasLimit: 100000, EVMConfig: vm.Config{
+		Tracer: tracer,
+		TraceFilter: func(tx *types.Transaction) bool {
+			return tx.Nonce() == 0
+		},
+	}}
+	setDefaults(cfg)
+	evm := NewEnv(cfg)
+
+	evm.FilterTrace(skipped)
+	if _, _, err := evm.Call(vm.AccountRef(cfg.Origin), address, nil, cfg.GasLimit, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if have := len(tracer.StructLogs()); have != 0 {
+		t.Fatalf("filtered out transaction traced: %d steps", have)
+	}
+	evm.FilterTrace(traced)
+	if _, _, err := evm.Call(vm.AccountRef(cfg.Origin), address, nil, cfg.GasLimit, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if have := len(tracer.StructLogs()); have != 4 {
+		t.Fatalf("selected transaction step count mismatch: have %d, want 4", have)
+	}
+}
+
+func TestTouchedAddresses(t *testing.T) {
+	// call returns the code calling the contract at addr without value
+	call := func(addr byte) []byte {
+		return []byte{
+			byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+			byte(vm.PUSH1), addr, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		}
+	}
+	// A two hop swap: the router swaps through two pairs, each of them moving
+	// tokens of its own
+	var (
+		statedb = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		router  = append(call(0xb1), call(0xb2)...)
+	)
+	statedb.SetCode(common.HexToAddress("0xb1"), call(0xc1))
+	statedb.SetCode(common.HexToAddress("0xb2"), call(0xc2))
+	statedb.SetCode(common.HexToAddress("0xc1"), []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD)})
+	statedb.SetCode(common.HexToAddress("0xc2"), []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD)})
+
+	if _, _, err := Execute(router, nil, &Config{State: statedb}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	touched := statedb.TouchedAddresses()
+	for _, want := range []string{"0xb1", "0xb2", "0xc1", "0xc2"} {
+		var found bool
+		for _, addr := range touched {
+			found = found || addr == common.HexToAddress(want)
+		}
+		if !found {
+			t.Errorf("address %s missing from touched set", want)
+		}
+	}
+	for i := 1; i < len(touched); i++ {
+		if bytes.Compare(touched[i-1][:], touched[i][:]) >= 0 {
+			t.Errorf("touched addresses not sorted: %x before %x", touched[i-1], touched[i])
+		}
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
@@ -159,9 +423,9 @@ func BenchmarkCall(b *testing.B) {
 }
 func benchmarkEVM_Create(bench *testing.B, code string) {
 	var (
-		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
-		sender     = common.BytesToAddress([]byte("sender"))
-		receiver   = common.BytesToAddress([]byte("receiver"))
+		statedb  = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		sender   = common.BytesToAddress([]byte("sender"))
+		receiver = common.BytesToAddress([]byte("receiver"))
 	)
 
 	statedb.CreateAccount(sender)
@@ -327,7 +591,7 @@ func TestBlockhash(t *testing.T) {
 func benchmarkNonModifyingCode(gas uint64, code []byte, name string, tracerCode string, b *testing.B) {
 	cfg := new(Config)
 	setDefaults(cfg)
-	cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	cfg.State = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	cfg.GasLimit = gas
 	if len(tracerCode) > 0 {
 		tracer, err := tracers.DefaultDirectory.New(tracerCode, new(tracers.Context), nil)
@@ -676,6 +940,45 @@ func TestColdAccountAccessCost(t *testing.T) {
 	}
 }
 
+// depthTracer records the call depth passed to CaptureState alongside the one
+// carried by the scope.
+type depthTracer struct {
+	vm.EVMLogger
+	depths [][2]int
+}
+
+func (t *depthTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.depths = append(t.depths, [2]int{depth, scope.Depth})
+}
+
+// Tests that the scope context carries the call depth of every executed opcode.
+func TestScopeContextDepth(t *testing.T) {
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetCode(common.HexToAddress("0xbb"), []byte{byte(vm.PUSH1), 0x0, byte(vm.POP), byte(vm.STOP)})
+
+	code := []byte{
+		byte(vm.PUSH1), 0x0,
+		byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	tracer := &depthTracer{EVMLogger: logger.NewStructLogger(nil)}
+	if _, _, err := Execute(code, nil, &Config{State: statedb.Copy(), EVMConfig: vm.Config{Tracer: tracer}}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	want := [][2]int{{1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {2, 2}, {2, 2}, {2, 2}, {1, 1}, {1, 1}}
+	if !reflect.DeepEqual(tracer.depths, want) {
+		t.Fatalf("depth mismatch: have %v, want %v", tracer.depths, want)
+	}
+	// The JSON logger should report the depth of each step
+	var out bytes.Buffer
+	if _, _, err := Execute(code, nil, &Config{State: statedb.Copy(), EVMConfig: vm.Config{Tracer: logger.NewJSONLogger(nil, &out)}}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if have := strings.Count(out.String(), `"depth":2`); have != 3 {
+		t.Fatalf("nested step count mismatch: have %d, want 3\n%s", have, out.String())
+	}
+}
+
 func TestRuntimeJSTracer(t *testing.T) {
 	jsTracers := []string{
 		`{enters: 0, exits: 0, enterGas: 0, gasUsed: 0, steps:0,
@@ -818,7 +1121,7 @@ func TestRuntimeJSTracer(t *testing.T) {
 	main := common.HexToAddress("0xaa")
 	for i, jsTracer := range jsTracers {
 		for j, tc := range tests {
-			statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+			statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 			statedb.SetCode(main, tc.code)
 			statedb.SetCode(common.HexToAddress("0xbb"), calleeCode)
 			statedb.SetCode(common.HexToAddress("0xcc"), calleeCode)
@@ -860,7 +1163,7 @@ func TestJSTracerCreateTx(t *testing.T) {
 	exit: function(res) { this.exits++ }}`
 	code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.RETURN)}
 
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
 	tracer, err := tracers.DefaultDirectory.New(jsTracer, new(tracers.Context), nil)
 	if err != nil {
 		t.Fatal(err)
@@ -912,3 +1215,70 @@ func BenchmarkTracerStepVsCallFrame(b *testing.B) {
 	benchmarkNonModifyingCode(10000000, code, "tracer-step-10M", stepTracer, b)
 	benchmarkNonModifyingCode(10000000, code, "tracer-call-frame-10M", callFrameTracer, b)
 }
+
+// proxyChain deploys a chain of depth contracts from address 0x11 on, each calling
+// the next one, the last of which stores a slot. It returns the address of the
+// first one.
+func proxyChain(statedb *state.StateDB, depth int) common.Address {
+	for i := depth; i > 0; i-- {
+		code := []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE)}
+		if i < depth {
+			// Delegate to the next contract in the chain, like a proxy
+			code = []byte{
+				byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+				byte(vm.PUSH1), byte(0x10 + i + 1), byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+			}
+		}
+		statedb.SetCode(common.BytesToAddress([]byte{byte(0x10 + i)}), code)
+	}
+	return common.BytesToAddress([]byte{0x11})
+}
+
+func TestRecycleFrames(t *testing.T) {
+	run := func(recycle bool) (uint64, common.Hash) {
+		statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		entry := proxyChain(statedb, 5)
+
+		cfg := &Config{State: statedb, GasLimit: 1_000_000, EVMConfig: vm.Config{RecycleFrames: recycle}}
+		// Run twice, so that the second run executes on recycled frames
+		for i := 0; i < 2; i++ {
+			if _, _, err := Call(entry, nil, cfg); err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+		}
+		_, leftOverGas, err := Call(entry, nil, cfg)
+		if err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+		return leftOverGas, statedb.IntermediateRoot(true)
+	}
+	wantGas, wantRoot := run(false)
+	haveGas, haveRoot := run(true)
+	if haveGas != wantGas {
+		t.Errorf("gas mismatch: have %d, want %d", haveGas, wantGas)
+	}
+	if haveRoot != wantRoot {
+		t.Errorf("state root mismatch: have %x, want %x", haveRoot, wantRoot)
+	}
+}
+
+func BenchmarkProxyCall(b *testing.B) {
+	for _, recycle := range []bool{false, true} {
+		b.Run(fmt.Sprintf("recycle=%v", recycle), func(b *testing.B) {
+			statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+			entry := proxyChain(statedb, 5)
+
+			cfg := &Config{State: statedb, GasLimit: 1_000_000, EVMConfig: vm.Config{RecycleFrames: recycle}}
+			setDefaults(cfg)
+			evm := NewEnv(cfg)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := evm.Call(vm.AccountRef(cfg.Origin), entry, nil, cfg.GasLimit, new(big.Int)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -39,14 +39,40 @@ func newstack() *Stack {
 	return stackPool.Get().(*Stack)
 }
 
+// NewStack creates a stack holding the given items, ordered from bottom to top.
+// The interpreter allocates its own stacks, this is meant for reconstructing a
+// recorded stack for tracers.
+func NewStack(items ...uint256.Int) *Stack {
+	return &Stack{data: append(make([]uint256.Int, 0, 16), items...)}
+}
+
 func returnStack(s *Stack) {
-	s.data = s.data[:0]
+	s.Reset()
 	stackPool.Put(s)
 }
 
-// Data returns the underlying uint256.Int array.
-func (st *Stack) Data() []uint256.Int {
-	return st.data
+// Reset empties the stack, retaining the backing array for reuse.
+func (st *Stack) Reset() {
+	st.data = st.data[:0]
+}
+
+// ToSlice returns a copy of the stack items, ordered from bottom to top. The
+// returned values are owned by the caller and may be freely modified.
+func (st *Stack) ToSlice() []*uint256.Int {
+	var (
+		vals = make([]uint256.Int, len(st.data))
+		ret  = make([]*uint256.Int, len(st.data))
+	)
+	copy(vals, st.data)
+	for i := range vals {
+		ret[i] = &vals[i]
+	}
+	return ret
+}
+
+// Len returns the number of items on the stack.
+func (st *Stack) Len() int {
+	return len(st.data)
 }
 
 func (st *Stack) push(d *uint256.Int) {
@@ -0,0 +1,88 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// Tests that ToSlice returns the stack bottom first and that the returned
+// items do not alias the interpreter stack.
+func TestStackToSlice(t *testing.T) {
+	st := newstack()
+	defer returnStack(st)
+
+	st.push(uint256.NewInt(1))
+	st.push(uint256.NewInt(2))
+
+	items := st.ToSlice()
+	if len(items) != 2 || items[0].Uint64() != 1 || items[1].Uint64() != 2 {
+		t.Fatalf("unexpected stack copy: %v", items)
+	}
+	items[1].SetUint64(42)
+	if have := st.Back(0).Uint64(); have != 2 {
+		t.Fatalf("stack modified through copy: have %d, want 2", have)
+	}
+	if st.Len() != 2 {
+		t.Fatalf("stack length mismatch: have %d, want 2", st.Len())
+	}
+}
+
+// Tests that resetting the stack empties it without dropping the allocation.
+func TestStackReset(t *testing.T) {
+	st := newstack()
+	defer returnStack(st)
+
+	for i := uint64(0); i < 32; i++ {
+		st.push(uint256.NewInt(i))
+	}
+	capacity := cap(st.data)
+
+	st.Reset()
+	if st.Len() != 0 {
+		t.Fatalf("stack not empty after reset: %d items", st.Len())
+	}
+	if cap(st.data) != capacity {
+		t.Fatalf("backing array reallocated: have cap %d, want %d", cap(st.data), capacity)
+	}
+}
+
+// Tests that stack operations don't allocate once the backing array has grown,
+// as the items are stored by value.
+func TestStackOpsNoAlloc(t *testing.T) {
+	st := newstack()
+	defer returnStack(st)
+
+	val := uint256.NewInt(1)
+	fill := func() {
+		for i := 0; i < int(params.StackLimit); i++ {
+			st.push(val)
+		}
+		st.dup(1)
+		st.swap(2)
+		for st.len() > 0 {
+			st.pop()
+		}
+	}
+	fill() // grow the backing array to its final size
+	if allocs := testing.AllocsPerRun(10, fill); allocs != 0 {
+		t.Fatalf("stack operations allocated: %v allocs per run", allocs)
+	}
+}
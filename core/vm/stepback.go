@@ -0,0 +1,76 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+// StepRecord is a compact record of a dispatched instruction, retained by the
+// step back buffer for post-mortem debugging.
+type StepRecord struct {
+	Pc    uint64
+	Op    OpCode
+	Gas   uint64 // Gas available before the instruction
+	Cost  uint64 // Gas cost of the instruction, partial if it failed while charging
+	Depth int
+}
+
+// stepBackBuffer is a ring buffer of the most recently dispatched instructions
+// across all call frames of an execution.
+type stepBackBuffer struct {
+	steps []StepRecord
+	next  int  // Position the next record is written to
+	full  bool // Whether the buffer has wrapped around
+}
+
+// newStepBackBuffer creates a ring buffer retaining the last size instructions.
+func newStepBackBuffer(size uint) *stepBackBuffer {
+	return &stepBackBuffer{steps: make([]StepRecord, size)}
+}
+
+// reset drops all records, retaining the allocated buffer.
+func (b *stepBackBuffer) reset() {
+	b.next, b.full = 0, false
+}
+
+// add records an instruction, overwriting the oldest one if the buffer is full.
+// The returned record may be updated until the next call.
+func (b *stepBackBuffer) add(pc uint64, op OpCode, gas, cost uint64, depth int) *StepRecord {
+	rec := &b.steps[b.next]
+	*rec = StepRecord{Pc: pc, Op: op, Gas: gas, Cost: cost, Depth: depth}
+
+	if b.next++; b.next == len(b.steps) {
+		b.next, b.full = 0, true
+	}
+	return rec
+}
+
+// records returns a copy of the retained instructions, oldest first.
+func (b *stepBackBuffer) records() []StepRecord {
+	if !b.full {
+		return append([]StepRecord(nil), b.steps[:b.next]...)
+	}
+	return append(append(make([]StepRecord, 0, len(b.steps)), b.steps[b.next:]...), b.steps[:b.next]...)
+}
+
+// LastSteps returns the instructions most recently dispatched by the EVM, oldest
+// first, if Config.StepBackBuffer is set. The buffer is reset at the start of
+// every top-level call, so tracers can inspect the steps leading to an error in
+// CaptureEnd.
+func (evm *EVM) LastSteps() []StepRecord {
+	if evm.interpreter.stepBack == nil {
+		return nil
+	}
+	return evm.interpreter.stepBack.records()
+}
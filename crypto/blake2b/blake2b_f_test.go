@@ -21,6 +21,37 @@ func TestF(t *testing.T) {
 	}
 }
 
+func benchmarkF(b *testing.B, sse4, avx, avx2 bool) {
+	// Enable the correct set of instructions, if supported by the CPU
+	if (sse4 && !useSSE4) || (avx && !useAVX) || (avx2 && !useAVX2) {
+		b.Skip("instruction set not supported")
+	}
+	defer func(sse4, avx, avx2 bool) {
+		useSSE4, useAVX, useAVX2 = sse4, avx, avx2
+	}(useSSE4, useAVX, useAVX2)
+	useSSE4, useAVX, useAVX2 = sse4, avx, avx2
+
+	// Make sure the selected implementation agrees with the reference output
+	test := testVectorsF[0]
+	h := test.hIn
+	F(&h, test.m, test.c, test.f, test.rounds)
+	if h != test.hOut {
+		b.Fatalf("Unexpected result\nExpected: [%#x]\nActual:   [%#x]\n", test.hOut, h)
+	}
+	b.SetBytes(int64(len(test.m) * 8))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h = test.hIn
+		F(&h, test.m, test.c, test.f, test.rounds)
+	}
+}
+
+func BenchmarkFGeneric(b *testing.B) { benchmarkF(b, false, false, false) }
+func BenchmarkFSSE4(b *testing.B)    { benchmarkF(b, true, false, false) }
+func BenchmarkFAVX(b *testing.B)     { benchmarkF(b, false, true, false) }
+func BenchmarkFAVX2(b *testing.B)    { benchmarkF(b, false, false, true) }
+
 type testVector struct {
 	hIn    [8]uint64
 	m      [16]uint64
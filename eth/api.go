@@ -335,53 +335,50 @@ func (api *DebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error)
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
-// AccountRange enumerates all accounts in the given block and start point in paging request
-func (api *DebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, start hexutil.Bytes, maxResults int, nocode, nostorage, incompletes bool) (state.IteratorDump, error) {
-	var stateDb *state.StateDB
-	var err error
-
+// stateAt returns the state at the given block, or the pending state.
+func (api *DebugAPI) stateAt(blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, error) {
 	if number, ok := blockNrOrHash.Number(); ok {
 		if number == rpc.PendingBlockNumber {
 			// If we're dumping the pending state, we need to request
 			// both the pending block as well as the pending state from
 			// the miner and operate on those
-			_, stateDb = api.eth.miner.Pending()
+			_, stateDb := api.eth.miner.Pending()
+			return stateDb, nil
+		}
+		var header *types.Header
+		if number == rpc.LatestBlockNumber {
+			header = api.eth.blockchain.CurrentBlock()
+		} else if number == rpc.FinalizedBlockNumber {
+			header = api.eth.blockchain.CurrentFinalBlock()
+		} else if number == rpc.SafeBlockNumber {
+			header = api.eth.blockchain.CurrentSafeBlock()
 		} else {
-			var header *types.Header
-			if number == rpc.LatestBlockNumber {
-				header = api.eth.blockchain.CurrentBlock()
-			} else if number == rpc.FinalizedBlockNumber {
-				header = api.eth.blockchain.CurrentFinalBlock()
-			} else if number == rpc.SafeBlockNumber {
-				header = api.eth.blockchain.CurrentSafeBlock()
-			} else {
-				block := api.eth.blockchain.GetBlockByNumber(uint64(number))
-				if block == nil {
-					return state.IteratorDump{}, fmt.Errorf("block #%d not found", number)
-				}
-				header = block.Header()
-			}
-			if header == nil {
-				return state.IteratorDump{}, fmt.Errorf("block #%d not found", number)
-			}
-			stateDb, err = api.eth.BlockChain().StateAt(header.Root)
-			if err != nil {
-				return state.IteratorDump{}, err
+			block := api.eth.blockchain.GetBlockByNumber(uint64(number))
+			if block == nil {
+				return nil, fmt.Errorf("block #%d not found", number)
 			}
+			header = block.Header()
 		}
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		return api.eth.BlockChain().StateAt(header.Root)
 	} else if hash, ok := blockNrOrHash.Hash(); ok {
 		block := api.eth.blockchain.GetBlockByHash(hash)
 		if block == nil {
-			return state.IteratorDump{}, fmt.Errorf("block %s not found", hash.Hex())
-		}
-		stateDb, err = api.eth.BlockChain().StateAt(block.Root())
-		if err != nil {
-			return state.IteratorDump{}, err
+			return nil, fmt.Errorf("block %s not found", hash.Hex())
 		}
-	} else {
-		return state.IteratorDump{}, errors.New("either block number or block hash must be specified")
+		return api.eth.BlockChain().StateAt(block.Root())
 	}
+	return nil, errors.New("either block number or block hash must be specified")
+}
 
+// AccountRange enumerates all accounts in the given block and start point in paging request
+func (api *DebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, start hexutil.Bytes, maxResults int, nocode, nostorage, incompletes bool) (state.IteratorDump, error) {
+	stateDb, err := api.stateAt(blockNrOrHash)
+	if err != nil {
+		return state.IteratorDump{}, err
+	}
 	opts := &state.DumpConfig{
 		SkipCode:          nocode,
 		SkipStorage:       nostorage,
@@ -395,6 +392,26 @@ func (api *DebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, start hex
 	return stateDb.IteratorDump(opts), nil
 }
 
+// GetAccount returns a single account in the given block in the format of
+// debug_dumpBlock, including its entire storage. The result is nil if the
+// account does not exist.
+func (api *DebugAPI) GetAccount(blockNrOrHash rpc.BlockNumberOrHash, address common.Address) (*state.DumpAccount, error) {
+	stateDb, err := api.stateAt(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return stateDb.AccountDump(address)
+}
+
+// GetStorage returns the given storage slots of an account in the given block.
+func (api *DebugAPI) GetStorage(blockNrOrHash rpc.BlockNumberOrHash, address common.Address, keys []common.Hash) (map[common.Hash]common.Hash, error) {
+	stateDb, err := api.stateAt(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return stateDb.StorageDump(address, keys)
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
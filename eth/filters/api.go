@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -43,6 +44,7 @@ type filter struct {
 	txs      []*types.Transaction
 	crit     FilterCriteria
 	logs     []*types.Log
+	cursor   uint64        // block number of the last delivered log, for persistent filters
 	s        *Subscription // associated subscription in event system
 }
 
@@ -54,6 +56,7 @@ type FilterAPI struct {
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
 	timeout   time.Duration
+	wal       *filterWAL // optional log persisting the log filters
 }
 
 // NewFilterAPI returns a new FilterAPI instance.
@@ -64,25 +67,45 @@ func NewFilterAPI(system *FilterSystem, lightMode bool) *FilterAPI {
 		filters: make(map[rpc.ID]*filter),
 		timeout: system.cfg.Timeout,
 	}
+	if system.wal != nil {
+		api.wal = system.wal
+
+		records := system.restoredFilters()
+		for _, rec := range records {
+			api.restoreFilter(rec)
+		}
+		log.Info("Restored log filters", "path", system.cfg.WALPath, "count", len(records))
+	}
 	go api.timeoutLoop(system.cfg.Timeout)
 
 	return api
 }
 
 // timeoutLoop runs at the interval set by 'timeout' and deletes filters
-// that have not been recently used. It is started when the API is created.
+// that have not been recently used. It is started when the API is created
+// and runs until the filter system is stopped.
 func (api *FilterAPI) timeoutLoop(timeout time.Duration) {
-	var toUninstall []*Subscription
+	var (
+		toUninstall []*Subscription
+		toForget    []rpc.ID
+	)
 	ticker := time.NewTicker(timeout)
 	defer ticker.Stop()
 	for {
-		<-ticker.C
+		select {
+		case <-ticker.C:
+		case <-api.sys.quit:
+			return
+		}
 		api.filtersMu.Lock()
 		for id, f := range api.filters {
 			select {
 			case <-f.deadline.C:
 				toUninstall = append(toUninstall, f.s)
 				delete(api.filters, id)
+				if api.persistent(f) {
+					toForget = append(toForget, id)
+				}
 			default:
 				continue
 			}
@@ -95,7 +118,12 @@ func (api *FilterAPI) timeoutLoop(timeout time.Duration) {
 		for _, s := range toUninstall {
 			s.Unsubscribe()
 		}
-		toUninstall = nil
+		// The filter log is also written outside the lock, not to stall the
+		// delivery of logs on disk access.
+		for _, id := range toForget {
+			api.wal.remove(id)
+		}
+		toUninstall, toForget = nil, nil
 	}
 }
 
@@ -135,10 +163,41 @@ func (api *FilterAPI) NewPendingTransactionFilter(fullTx *bool) rpc.ID {
 	return pendingTxSub.ID
 }
 
+// PendingTxsArgs are the optional arguments of the pending transaction
+// subscription. For backwards compatibility, a plain boolean is accepted in
+// place of the object and taken as the IncludeTransactions flag.
+type PendingTxsArgs struct {
+	IncludeTransactions bool `json:"includeTransactions"`
+}
+
+// UnmarshalJSON sets *args fields with given data.
+func (args *PendingTxsArgs) UnmarshalJSON(data []byte) error {
+	var fullTx bool
+	if err := json.Unmarshal(data, &fullTx); err == nil {
+		args.IncludeTransactions = fullTx
+		return nil
+	}
+	type input PendingTxsArgs
+	return json.Unmarshal(data, (*input)(args))
+}
+
 // NewPendingTransactions creates a subscription that is triggered each time a
-// transaction enters the transaction pool. If fullTx is true the full tx is
-// sent to the client, otherwise the hash is sent.
-func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
+// transaction enters the transaction pool. If includeTransactions is set the
+// full tx is sent to the client, otherwise the hash is sent.
+func (api *FilterAPI) NewPendingTransactions(ctx context.Context, args *PendingTxsArgs) (*rpc.Subscription, error) {
+	return api.subscribePendingTransactions(ctx, args != nil && args.IncludeTransactions)
+}
+
+// PendingTransactionsFull creates a subscription that is triggered each time a
+// transaction enters the transaction pool, sending the full transaction object
+// in the same format as eth_getTransactionByHash.
+func (api *FilterAPI) PendingTransactionsFull(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribePendingTransactions(ctx, true)
+}
+
+// subscribePendingTransactions creates a subscription that notifies about the
+// transactions entering the pool, either by hash or as full objects.
+func (api *FilterAPI) subscribePendingTransactions(ctx context.Context, fullTx bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -158,7 +217,7 @@ func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool)
 				// TODO(rjl493456442) Send a batch of tx hashes in one notification
 				latest := api.sys.backend.CurrentHeader()
 				for _, tx := range txs {
-					if fullTx != nil && *fullTx {
+					if fullTx {
 						rpcTx := ethapi.NewRPCPendingTransaction(tx, latest, chainConfig)
 						notifier.Notify(rpcSub.ID, rpcTx)
 					} else {
@@ -279,6 +338,114 @@ func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subsc
 	return rpcSub, nil
 }
 
+// streamLogsBatchSize is the number of blocks a log stream scans for every
+// notification it sends.
+const streamLogsBatchSize = 1024
+
+// LogsBatch is a notification of a log stream, carrying the logs found in the
+// last scanned batch of blocks.
+type LogsBatch struct {
+	Logs     []*types.Log      `json:"logs"`
+	Progress LogStreamProgress `json:"progress"`
+}
+
+// LogStreamProgress reports how many blocks of the requested range a log
+// stream has scanned so far.
+type LogStreamProgress struct {
+	Current hexutil.Uint64 `json:"current"`
+	Total   hexutil.Uint64 `json:"total"`
+}
+
+// StreamLogs creates a subscription that scans the given block range for logs
+// matching the filter criteria, like GetLogs, but pushes the matches to the
+// client batch by batch instead of collecting them all first. A notification
+// is sent for every batch of blocks, even if it contained no matches, so the
+// client can track the progress of the scan. The subscription stays silent once
+// the end of the range is reached.
+//
+// Notifications are written to the connection synchronously, so scanning is
+// paused for as long as the client does not keep up with receiving them.
+func (api *FilterAPI) StreamLogs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if crit.BlockHash != nil {
+		return nil, errors.New("single block filters can't be streamed")
+	}
+	begin, err := api.resolveBlockNumber(ctx, crit.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	end, err := api.resolveBlockNumber(ctx, crit.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if begin > end {
+		return nil, errors.New("invalid from and to block combination: from > to")
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		// Abort the scan if the client unsubscribes or the connection drops
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+			case <-notifier.Closed():
+			case <-ctx.Done():
+			}
+			cancel()
+		}()
+		total := end - begin + 1
+		for from := begin; from <= end; from += streamLogsBatchSize {
+			to := from + streamLogsBatchSize - 1
+			if to > end {
+				to = end
+			}
+			logs, err := api.sys.NewRangeFilter(int64(from), int64(to), crit.Addresses, crit.Topics).Logs(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Debug("Log stream failed", "from", from, "to", to, "err", err)
+				}
+				return
+			}
+			batch := &LogsBatch{
+				Logs:     returnLogs(logs),
+				Progress: LogStreamProgress{Current: hexutil.Uint64(to - begin + 1), Total: hexutil.Uint64(total)},
+			}
+			if err := notifier.Notify(rpcSub.ID, batch); err != nil {
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// resolveBlockNumber converts a block number of a filter criteria into an
+// absolute one. Unset and pending numbers refer to the latest block.
+func (api *FilterAPI) resolveBlockNumber(ctx context.Context, number *big.Int) (uint64, error) {
+	n := rpc.LatestBlockNumber
+	if number != nil {
+		n = rpc.BlockNumber(number.Int64())
+	}
+	if n == rpc.PendingBlockNumber {
+		n = rpc.LatestBlockNumber
+	}
+	if n >= 0 {
+		return uint64(n), nil
+	}
+	header, err := api.sys.backend.HeaderByNumber(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("header for block %d not found", n)
+	}
+	return header.Number.Uint64(), nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
@@ -295,14 +462,30 @@ type FilterCriteria ethereum.FilterQuery
 //
 // In case "fromBlock" > "toBlock" an error is returned.
 func (api *FilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
+	// Read the head before subscribing, so that no block imported in between
+	// is skipped should the filter be restored before its first poll.
+	head := api.headNumber()
+
 	logs := make(chan []*types.Log)
 	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
 	if err != nil {
 		return "", err
 	}
+	f := api.installLogFilter(logsSub.ID, crit, logsSub, logs, make([]*types.Log, 0), head)
+	if api.persistent(f) {
+		api.wal.append(newFilterRecord(logsSub.ID, crit, head))
+	}
+	return logsSub.ID, nil
+}
+
+// installLogFilter registers a log filter under the given id, collecting the
+// logs delivered by the subscription on top of the initial ones. The cursor is
+// the block up to which the filter's logs were already delivered.
+func (api *FilterAPI) installLogFilter(id rpc.ID, crit FilterCriteria, logsSub *Subscription, logs chan []*types.Log, initial []*types.Log, cursor uint64) *filter {
+	f := &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(api.timeout), logs: initial, cursor: cursor, s: logsSub}
 
 	api.filtersMu.Lock()
-	api.filters[logsSub.ID] = &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(api.timeout), logs: make([]*types.Log, 0), s: logsSub}
+	api.filters[id] = f
 	api.filtersMu.Unlock()
 
 	go func() {
@@ -310,20 +493,73 @@ func (api *FilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 			select {
 			case l := <-logs:
 				api.filtersMu.Lock()
-				if f, found := api.filters[logsSub.ID]; found {
+				if f, found := api.filters[id]; found {
 					f.logs = append(f.logs, l...)
 				}
 				api.filtersMu.Unlock()
 			case <-logsSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, logsSub.ID)
+				delete(api.filters, id)
 				api.filtersMu.Unlock()
 				return
 			}
 		}
 	}()
+	return f
+}
 
-	return logsSub.ID, nil
+// persistent reports whether the filter is tracked in the filter log. Only the
+// filters of mined logs are, since pending logs are gone after a restart anyway.
+func (api *FilterAPI) persistent(f *filter) bool {
+	return api.wal != nil && f.typ == LogsSubscription && f.s.f.typ == LogsSubscription
+}
+
+// restoreFilter reinstalls a log filter recorded in the filter log, preloading
+// the logs mined since it was last polled.
+func (api *FilterAPI) restoreFilter(rec *filterRecord) {
+	var (
+		crit = rec.criteria()
+		logs = make(chan []*types.Log)
+	)
+	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
+	if err != nil {
+		log.Warn("Failed to restore log filter", "id", rec.ID, "err", err)
+		api.wal.remove(rec.ID)
+		return
+	}
+	missed, err := api.missedLogs(crit, rec.Cursor)
+	if err != nil {
+		log.Warn("Failed to retrieve logs of restored filter", "id", rec.ID, "err", err)
+	}
+	api.installLogFilter(rec.ID, crit, logsSub, logs, missed, rec.Cursor)
+}
+
+// headNumber returns the number of the current head block, or zero if the chain
+// is still empty.
+func (api *FilterAPI) headNumber() uint64 {
+	if head := api.sys.backend.CurrentHeader(); head != nil {
+		return head.Number.Uint64()
+	}
+	return 0
+}
+
+// missedLogs retrieves the logs matching the criteria that were mined after the
+// given block, up to the current head.
+func (api *FilterAPI) missedLogs(crit FilterCriteria, cursor uint64) ([]*types.Log, error) {
+	if crit.BlockHash != nil {
+		return nil, nil
+	}
+	begin, end := int64(cursor)+1, int64(api.headNumber())
+	if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 && crit.FromBlock.Int64() > begin {
+		begin = crit.FromBlock.Int64()
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Int64() < end {
+		end = crit.ToBlock.Int64()
+	}
+	if begin > end {
+		return nil, nil
+	}
+	return api.sys.NewRangeFilter(begin, end, crit.Addresses, crit.Topics).Logs(context.Background())
 }
 
 // GetLogs returns logs matching the given argument that are stored within the state.
@@ -363,6 +599,9 @@ func (api *FilterAPI) UninstallFilter(id rpc.ID) bool {
 	api.filtersMu.Unlock()
 	if found {
 		f.s.Unsubscribe()
+		if api.persistent(f) {
+			api.wal.remove(id)
+		}
 	}
 
 	return found
@@ -410,6 +649,17 @@ func (api *FilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Lo
 // For pending transaction and block filters the result is []common.Hash.
 // (pending)Log filters return []Log.
 func (api *FilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
+	changes, rec, err := api.filterChanges(id)
+	if rec != nil {
+		api.wal.append(rec)
+	}
+	return changes, err
+}
+
+// filterChanges collects the changes of the filter with the given id. For the
+// filters tracked in the filter log it also returns the record to persist, which
+// is written by the caller outside the filter lock.
+func (api *FilterAPI) filterChanges(id rpc.ID) (interface{}, *filterRecord, error) {
 	api.filtersMu.Lock()
 	defer api.filtersMu.Unlock()
 
@@ -428,7 +678,7 @@ func (api *FilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 		case BlocksSubscription:
 			hashes := f.hashes
 			f.hashes = nil
-			return returnHashes(hashes), nil
+			return returnHashes(hashes), nil, nil
 		case PendingTransactionsSubscription:
 			if f.fullTx {
 				txs := make([]*ethapi.RPCTransaction, 0, len(f.txs))
@@ -436,23 +686,35 @@ func (api *FilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 					txs = append(txs, ethapi.NewRPCPendingTransaction(tx, latest, chainConfig))
 				}
 				f.txs = nil
-				return txs, nil
+				return txs, nil, nil
 			} else {
 				hashes := make([]common.Hash, 0, len(f.txs))
 				for _, tx := range f.txs {
 					hashes = append(hashes, tx.Hash())
 				}
 				f.txs = nil
-				return hashes, nil
+				return hashes, nil, nil
 			}
 		case LogsSubscription, MinedAndPendingLogsSubscription:
 			logs := f.logs
 			f.logs = nil
-			return returnLogs(logs), nil
+
+			var rec *filterRecord
+			if api.persistent(f) {
+				// Only advance the cursor past the delivered logs, the logs of
+				// blocks imported up to the current head may still be in flight.
+				for _, l := range logs {
+					if l.BlockNumber > f.cursor {
+						f.cursor = l.BlockNumber
+					}
+				}
+				rec = newFilterRecord(id, f.crit, f.cursor)
+			}
+			return returnLogs(logs), rec, nil
 		}
 	}
 
-	return []interface{}{}, fmt.Errorf("filter not found")
+	return []interface{}{}, nil, fmt.Errorf("filter not found")
 }
 
 // returnHashes is a helper that will return an empty hash array case the given hash array is nil,
@@ -183,3 +183,35 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+func TestUnmarshalJSONPendingTxsArgs(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+		fail  bool
+	}{
+		{input: `true`, want: true},
+		{input: `false`, want: false},
+		{input: `{}`, want: false},
+		{input: `{"includeTransactions": true}`, want: true},
+		{input: `{"includeTransactions": false}`, want: false},
+		{input: `"true"`, fail: true},
+	}
+	for i, test := range tests {
+		var args PendingTxsArgs
+		err := json.Unmarshal([]byte(test.input), &args)
+		if test.fail {
+			if err == nil {
+				t.Errorf("test %d: expected error for %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to unmarshal %s: %v", i, test.input, err)
+			continue
+		}
+		if args.IncludeTransactions != test.want {
+			t.Errorf("test %d: includeTransactions mismatch: have %v, want %v", i, args.IncludeTransactions, test.want)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -237,6 +238,12 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 		if f.begin%10 == 0 && ctx.Err() != nil {
 			return logs, ctx.Err()
 		}
+		// Skip the remainder of the range if its aggregated bloom rules out a match
+		section := uint64(f.begin) / rawdb.BloomRangeSize
+		if bloom, ok := rawdb.ReadBloomRange(f.sys.backend.ChainDb(), section); ok && !bloomFilter(bloom, f.addresses, f.topics) {
+			f.begin = int64((section+1)*rawdb.BloomRangeSize) - 1
+			continue
+		}
 		header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
 			return logs, err
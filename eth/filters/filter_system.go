@@ -43,6 +43,7 @@ import (
 type Config struct {
 	LogCacheSize int           // maximum number of cached blocks (default: 32)
 	Timeout      time.Duration // how long filters stay active (default: 5min)
+	WALPath      string        // file persisting log filters across restarts (default: none)
 }
 
 func (cfg Config) withDefaults() Config {
@@ -81,16 +82,57 @@ type FilterSystem struct {
 	backend   Backend
 	logsCache *lru.Cache[common.Hash, *logCacheElem]
 	cfg       *Config
+
+	wal      *filterWAL      // optional log persisting the log filters
+	restored []*filterRecord // filters recovered from the log, until picked up by the API
+	quit     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewFilterSystem creates a filter system.
 func NewFilterSystem(backend Backend, config Config) *FilterSystem {
 	config = config.withDefaults()
-	return &FilterSystem{
+	sys := &FilterSystem{
 		backend:   backend,
 		logsCache: lru.NewCache[common.Hash, *logCacheElem](config.LogCacheSize),
 		cfg:       &config,
+		quit:      make(chan struct{}),
+	}
+	if path := config.WALPath; path != "" {
+		wal, records, err := openFilterWAL(path, config.Timeout)
+		if err != nil {
+			log.Warn("Failed to open filter log", "path", path, "err", err)
+		} else {
+			sys.wal, sys.restored = wal, records
+		}
 	}
+	return sys
+}
+
+// Start implements node.Lifecycle. The filter system has nothing to start.
+func (sys *FilterSystem) Start() error {
+	return nil
+}
+
+// Stop implements node.Lifecycle, stopping the filter timeouts and closing the
+// filter log.
+func (sys *FilterSystem) Stop() error {
+	var err error
+	sys.stopOnce.Do(func() {
+		close(sys.quit)
+		if sys.wal != nil {
+			err = sys.wal.close()
+		}
+	})
+	return err
+}
+
+// restoredFilters returns the filters recovered from the filter log. They are
+// handed out only once, to the first API created on top of the system.
+func (sys *FilterSystem) restoredFilters() []*filterRecord {
+	records := sys.restored
+	sys.restored = nil
+	return records
 }
 
 type logCacheElem struct {
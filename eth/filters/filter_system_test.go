@@ -24,6 +24,7 @@ import (
 	"math/rand"
 	"reflect"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -50,6 +51,24 @@ type testBackend struct {
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
 	chainFeed       event.Feed
+
+	scannedLock sync.Mutex
+	scanned     map[uint64]bool // Blocks whose header was requested by number, if tracked
+}
+
+// trackScanned starts recording the blocks whose header is requested by number.
+func (b *testBackend) trackScanned() {
+	b.scannedLock.Lock()
+	defer b.scannedLock.Unlock()
+	b.scanned = make(map[uint64]bool)
+}
+
+// wasScanned reports whether the header of the block was requested since the
+// tracking started.
+func (b *testBackend) wasScanned(number uint64) bool {
+	b.scannedLock.Lock()
+	defer b.scannedLock.Unlock()
+	return b.scanned[number]
 }
 
 func (b *testBackend) ChainConfig() *params.ChainConfig {
@@ -90,6 +109,12 @@ func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumbe
 	default:
 		num = uint64(blockNr)
 		hash = rawdb.ReadCanonicalHash(b.db, num)
+
+		b.scannedLock.Lock()
+		if b.scanned != nil {
+			b.scanned[num] = true
+		}
+		b.scannedLock.Unlock()
 	}
 	return rawdb.ReadHeader(b.db, hash, num), nil
 }
@@ -955,3 +980,81 @@ func flattenLogs(pl [][]*types.Log) []*types.Log {
 	}
 	return logs
 }
+
+// TestStreamLogs tests that a log stream delivers the matching logs of the
+// requested range in batches, reporting the progress of the scan.
+func TestStreamLogs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		_, sys = newTestFilterSystem(t, db, Config{})
+		api    = NewFilterAPI(sys, false)
+		addr   = common.BytesToAddress([]byte("jeff"))
+		gspec  = &core.Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.TestChainConfig,
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2500, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 10, 1500, 2400:
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(999, common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	batches := make(chan *LogsBatch)
+	sub, err := client.EthSubscribe(context.Background(), batches, "streamLogs", map[string]interface{}{
+		"fromBlock": "0x1",
+		"address":   []common.Address{addr},
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		logs     []*types.Log
+		progress []uint64
+	)
+	for len(progress) == 0 || progress[len(progress)-1] < 2500 {
+		select {
+		case batch := <-batches:
+			if batch.Progress.Total != 2500 {
+				t.Fatalf("total progress mismatch: have %d, want 2500", batch.Progress.Total)
+			}
+			logs = append(logs, batch.Logs...)
+			progress = append(progress, uint64(batch.Progress.Current))
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for log batches")
+		}
+	}
+	if want := []uint64{1024, 2048, 2500}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress mismatch: have %v, want %v", progress, want)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("log count mismatch: have %d, want 3", len(logs))
+	}
+	for i, number := range []uint64{11, 1501, 2401} {
+		if logs[i].BlockNumber != number {
+			t.Errorf("log %d: block number mismatch: have %d, want %d", i, logs[i].BlockNumber, number)
+		}
+	}
+}
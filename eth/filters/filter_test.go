@@ -100,6 +100,77 @@ func BenchmarkFilters(b *testing.B) {
 	}
 }
 
+// Tests that range blooms let unindexed log queries skip ranges which cannot
+// match, without losing any results.
+func TestFiltersBloomRange(t *testing.T) {
+	var (
+		db, _        = rawdb.NewLevelDBDatabase(t.TempDir(), 0, 0, "", false)
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		addr1        = common.BytesToAddress([]byte("jeff"))
+		addr2        = common.BytesToAddress([]byte("ethereum"))
+
+		gspec = &core.Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.TestChainConfig,
+		}
+	)
+	defer db.Close()
+
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3000, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 1034, 2998:
+			gen.AddUncheckedReceipt(makeReceipt(addr1))
+			gen.AddUncheckedTx(types.NewTransaction(999, common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		case 2003:
+			gen.AddUncheckedReceipt(makeReceipt(addr2))
+			gen.AddUncheckedTx(types.NewTransaction(999, common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	query := func() []*types.Log {
+		backend.trackScanned()
+		logs, err := sys.NewRangeFilter(0, -1, []common.Address{addr2}, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return logs
+	}
+	// No range has been started yet, so the first query walks every header.
+	want := query()
+	if len(want) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(want))
+	}
+	for n := uint64(1); n <= 3000; n++ {
+		if !backend.wasScanned(n) {
+			t.Fatalf("block %d not scanned without range blooms", n)
+		}
+	}
+	for _, block := range chain {
+		rawdb.UpdateBloomRange(db, db, block.NumberU64(), block.Bloom())
+	}
+	have := query()
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("range indexed logs mismatch: have %v, want %v", have, want)
+	}
+	// The range of the genesis was never started, the ones holding only logs
+	// of other addresses must be skipped.
+	for n := uint64(1); n <= 3000; n++ {
+		var (
+			section = n / rawdb.BloomRangeSize
+			want    = section == 0 || section == 2
+		)
+		if have := backend.wasScanned(n); have != want {
+			t.Fatalf("block %d scan mismatch: have %v, want %v", n, have, want)
+		}
+	}
+}
+
 func TestFilters(t *testing.T) {
 	var (
 		db, _   = rawdb.NewLevelDBDatabase(t.TempDir(), 0, 0, "", false)
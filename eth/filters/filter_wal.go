@@ -0,0 +1,263 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// filterRecord is a single entry of the filter write-ahead log, describing the
+// latest state of a log filter.
+type filterRecord struct {
+	ID        rpc.ID           `json:"id"`
+	BlockHash *common.Hash     `json:"blockHash,omitempty"`
+	FromBlock *big.Int         `json:"fromBlock,omitempty"`
+	ToBlock   *big.Int         `json:"toBlock,omitempty"`
+	Addresses []common.Address `json:"address,omitempty"`
+	Topics    [][]common.Hash  `json:"topics,omitempty"`
+	Cursor    uint64           `json:"cursor"`  // block number of the last delivered log
+	Updated   time.Time        `json:"updated"` // time of the last poll
+	Removed   bool             `json:"removed,omitempty"`
+}
+
+// newFilterRecord creates a log filter record delivered up to the given block.
+func newFilterRecord(id rpc.ID, crit FilterCriteria, cursor uint64) *filterRecord {
+	return &filterRecord{
+		ID:        id,
+		BlockHash: crit.BlockHash,
+		FromBlock: crit.FromBlock,
+		ToBlock:   crit.ToBlock,
+		Addresses: crit.Addresses,
+		Topics:    crit.Topics,
+		Cursor:    cursor,
+		Updated:   time.Now(),
+	}
+}
+
+// criteria returns the filter criteria stored in the record.
+func (r *filterRecord) criteria() FilterCriteria {
+	return FilterCriteria{
+		BlockHash: r.BlockHash,
+		FromBlock: r.FromBlock,
+		ToBlock:   r.ToBlock,
+		Addresses: r.Addresses,
+		Topics:    r.Topics,
+	}
+}
+
+// walCompactSlack is the number of superseded entries the filter log may hold
+// on top of twice the number of live filters before it is compacted.
+const walCompactSlack = 1024
+
+// filterWAL is an append-only log of log filter changes, which allows the
+// polled filters to survive a node restart.
+type filterWAL struct {
+	path    string
+	file    *os.File
+	live    map[rpc.ID]*filterRecord // latest record of each live filter
+	entries int                      // number of entries in the log file
+	closed  bool
+	lock    sync.Mutex
+}
+
+// openFilterWAL opens the filter write-ahead log at path, creating it if needed,
+// and returns the filters recorded in it that were polled within ttl. The log is
+// compacted to only contain those filters.
+func openFilterWAL(path string, ttl time.Duration) (*filterWAL, []*filterRecord, error) {
+	records, err := readFilterWAL(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Drop all the filters that would have timed out in the meantime
+	w := &filterWAL{
+		path: path,
+		live: make(map[rpc.ID]*filterRecord),
+	}
+	cutoff := time.Now().Add(-ttl)
+	for _, rec := range records {
+		if rec.Updated.After(cutoff) {
+			w.live[rec.ID] = rec
+		}
+	}
+	if err := w.compact(); err != nil {
+		return nil, nil, err
+	}
+	return w, w.records(), nil
+}
+
+// records returns the live filters ordered by their last poll.
+func (w *filterWAL) records() []*filterRecord {
+	records := make([]*filterRecord, 0, len(w.live))
+	for _, rec := range w.live {
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Updated.Before(records[j].Updated)
+	})
+	return records
+}
+
+// compact rewrites the log with only the latest record of each live filter and
+// reopens it for appending.
+func (w *filterWAL) compact() error {
+	records := w.records()
+
+	tmp := w.path + ".tmp"
+	if err := writeFilterWAL(tmp, records); err != nil {
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.entries = file, len(records)
+	return nil
+}
+
+// readFilterWAL replays the write-ahead log at path, returning the latest state
+// of each filter that wasn't removed. A missing log is treated as empty, and a
+// truncated last entry, e.g. after a crash, is ignored.
+func readFilterWAL(path string) ([]*filterRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		filters = make(map[rpc.ID]*filterRecord)
+		dec     = json.NewDecoder(bufio.NewReader(file))
+	)
+	for {
+		rec := new(filterRecord)
+		if err := dec.Decode(rec); err != nil {
+			if err != io.EOF {
+				log.Warn("Discarding corrupt filter log entries", "path", path, "err", err)
+			}
+			break
+		}
+		if rec.Removed {
+			delete(filters, rec.ID)
+		} else {
+			filters[rec.ID] = rec
+		}
+	}
+	records := make([]*filterRecord, 0, len(filters))
+	for _, rec := range filters {
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// writeFilterWAL creates a fresh write-ahead log at path holding the records.
+func writeFilterWAL(path string, records []*filterRecord) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(file)
+	enc := json.NewEncoder(buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// append adds a record to the log, compacting it once most of its entries are
+// superseded. Failures are logged but otherwise ignored, as they only affect the
+// filters' ability to survive a restart.
+func (w *filterWAL) append(rec *filterRecord) {
+	blob, err := json.Marshal(rec)
+	if err != nil {
+		log.Warn("Failed to encode filter log entry", "id", rec.ID, "err", err)
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed || w.file == nil {
+		return
+	}
+	if rec.Removed {
+		delete(w.live, rec.ID)
+	} else {
+		w.live[rec.ID] = rec
+	}
+	if _, err := w.file.Write(append(blob, '\n')); err != nil {
+		log.Warn("Failed to write filter log entry", "id", rec.ID, "err", err)
+		return
+	}
+	w.entries++
+	if w.entries > 2*len(w.live)+walCompactSlack {
+		if err := w.compact(); err != nil {
+			log.Warn("Failed to compact filter log", "path", w.path, "err", err)
+		}
+	}
+}
+
+// remove records that a filter was uninstalled.
+func (w *filterWAL) remove(id rpc.ID) {
+	w.append(&filterRecord{ID: id, Updated: time.Now(), Removed: true})
+}
+
+// close closes the log file. Later changes are not recorded anymore.
+func (w *filterWAL) close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that log filters survive a restart through the filter log, returning
+// the logs mined while the node was down on the first poll.
+func TestFilterWALRestore(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		path    = filepath.Join(t.TempDir(), "filters.wal")
+		addr    = common.HexToAddress("0x1122")
+		genesis = &core.Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+	)
+	genesis.MustCommit(db)
+
+	// Generate a chain with logs in blocks 2, 5 and 8
+	_, chain, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 8, func(i int, gen *core.BlockGen) {
+		if i == 1 || i == 4 || i == 7 {
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+		}
+	})
+	writeBlocks := func(from, to int) {
+		for i := from; i < to; i++ {
+			rawdb.WriteBlock(db, chain[i])
+			rawdb.WriteCanonicalHash(db, chain[i].Hash(), chain[i].NumberU64())
+			rawdb.WriteHeadBlockHash(db, chain[i].Hash())
+			rawdb.WriteReceipts(db, chain[i].Hash(), chain[i].NumberU64(), receipts[i])
+		}
+	}
+	writeBlocks(0, 3)
+
+	// Install a filter after block 3 and another one that's uninstalled
+	_, sys := newTestFilterSystem(t, db, Config{WALPath: path})
+	api := NewFilterAPI(sys, false)
+
+	id, err := api.NewFilter(FilterCriteria{Addresses: []common.Address{addr}})
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	removed, err := api.NewFilter(FilterCriteria{Addresses: []common.Address{addr}})
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	if !api.UninstallFilter(removed) {
+		t.Fatal("failed to uninstall filter")
+	}
+	// Restart with blocks 4 to 6 mined in the meantime
+	writeBlocks(3, 6)
+	sys.Stop()
+	_, sys = newTestFilterSystem(t, db, Config{WALPath: path})
+	api = NewFilterAPI(sys, false)
+
+	changes, err := api.GetFilterChanges(id)
+	if err != nil {
+		t.Fatalf("restored filter missing: %v", err)
+	}
+	logs := changes.([]*types.Log)
+	if len(logs) != 1 {
+		t.Fatalf("restored log count mismatch: have %d, want 1", len(logs))
+	}
+	if logs[0].BlockNumber != 5 {
+		t.Fatalf("restored log block mismatch: have %d, want 5", logs[0].BlockNumber)
+	}
+	if _, err := api.GetFilterChanges(removed); err == nil {
+		t.Fatal("uninstalled filter restored")
+	}
+	// Import blocks 7 and 8 without their logs reaching the filter yet, a poll
+	// must not move the cursor past logs that weren't delivered
+	writeBlocks(6, 8)
+	changes, err = api.GetFilterChanges(id)
+	if err != nil {
+		t.Fatalf("restored filter missing: %v", err)
+	}
+	if logs := changes.([]*types.Log); len(logs) != 0 {
+		t.Fatalf("undelivered log count mismatch: have %d, want 0", len(logs))
+	}
+	// A restart after the poll should resume from the last delivered log
+	sys.Stop()
+	_, sys = newTestFilterSystem(t, db, Config{WALPath: path})
+	api = NewFilterAPI(sys, false)
+
+	changes, err = api.GetFilterChanges(id)
+	if err != nil {
+		t.Fatalf("restored filter missing: %v", err)
+	}
+	logs = changes.([]*types.Log)
+	if len(logs) != 1 {
+		t.Fatalf("restored log count mismatch: have %d, want 1", len(logs))
+	}
+	if logs[0].BlockNumber != 8 {
+		t.Fatalf("restored log block mismatch: have %d, want 8", logs[0].BlockNumber)
+	}
+	sys.Stop()
+	if _, err := api.GetFilterChanges(id); err != nil {
+		t.Fatalf("filter missing after stop: %v", err)
+	}
+	// Filters not polled within the timeout should be evicted from the log
+	time.Sleep(10 * time.Millisecond)
+	wal, records, err := openFilterWAL(path, time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to open filter log: %v", err)
+	}
+	wal.close()
+	if len(records) != 0 {
+		t.Fatalf("expired filters not evicted: have %d", len(records))
+	}
+	if records, _ := readFilterWAL(path); len(records) != 0 {
+		t.Fatalf("expired filters not compacted: have %d", len(records))
+	}
+}
+
+// Tests that the filter log is compacted while filters are polled, instead of
+// growing with every poll.
+func TestFilterWALCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.wal")
+	wal, _, err := openFilterWAL(path, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open filter log: %v", err)
+	}
+	defer wal.close()
+
+	for i := 0; i < 10*walCompactSlack; i++ {
+		wal.append(newFilterRecord("0x1", FilterCriteria{}, uint64(i)))
+		wal.append(newFilterRecord("0x2", FilterCriteria{}, uint64(i)))
+	}
+	if wal.entries > 2*len(wal.live)+walCompactSlack {
+		t.Fatalf("filter log not compacted: %d entries for %d filters", wal.entries, len(wal.live))
+	}
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read filter log: %v", err)
+	}
+	if lines := bytes.Count(blob, []byte{'\n'}); lines != wal.entries {
+		t.Fatalf("filter log entry count mismatch: have %d, want %d", lines, wal.entries)
+	}
+	records, err := readFilterWAL(path)
+	if err != nil {
+		t.Fatalf("failed to read filter log: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("filter count mismatch: have %d, want 2", len(records))
+	}
+	for _, rec := range records {
+		if rec.Cursor != 10*walCompactSlack-1 {
+			t.Fatalf("filter %s cursor mismatch: have %d, want %d", rec.ID, rec.Cursor, 10*walCompactSlack-1)
+		}
+	}
+}
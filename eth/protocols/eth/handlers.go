@@ -300,7 +300,8 @@ func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []
 				continue
 			}
 		}
-		// If known, encode and queue for response packet
+		// If known, encode and queue for response packet. The stored receipts
+		// can't be sent as is, as their storage encoding lacks the blooms.
 		if encoded, err := rlp.EncodeToBytes(results); err != nil {
 			log.Error("Failed to encode receipt", "err", err)
 		} else {
@@ -941,7 +941,7 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 			return nil, err
 		}
 	}
-	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true})
+	vmenv := vm.NewEVMWithTracer(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{NoBaseFee: true}, tracer)
 
 	// Define a meaningful timeout of a single transaction trace
 	if config.Timeout != nil {
@@ -107,7 +107,7 @@ func flatCallTracerTestRunner(tracerName string, filename string, dirPath string
 	if err != nil {
 		return fmt.Errorf("failed to create call tracer: %v", err)
 	}
-	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Tracer: tracer})
+	evm := vm.NewEVMWithTracer(context, txContext, statedb, test.Genesis.Config, vm.Config{}, tracer)
 
 	msg, err := core.TransactionToMessage(tx, signer, nil)
 	if err != nil {
@@ -222,6 +222,18 @@ func (t *jsTracer) CaptureTxEnd(restGas uint64) {
 	t.ctx["gasUsed"] = t.vm.ToValue(t.gasLimit - restGas)
 }
 
+// CapturePreimage implements the Tracer interface. Preimages are not exposed to
+// JavaScript tracers.
+func (t *jsTracer) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+// CaptureStorageRead implements the Tracer interface. Storage accesses are
+// observable through the step callback already.
+func (t *jsTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+// CaptureStorageWrite implements the Tracer interface. Storage accesses are
+// observable through the step callback already.
+func (t *jsTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {}
+
 // CaptureStart implements the Tracer interface to initialize the tracing operation.
 func (t *jsTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
 	t.env = env
@@ -630,14 +642,14 @@ func (s *stackObj) Peek(idx int) goja.Value {
 
 // peek returns the nth-from-the-top element of the stack.
 func (s *stackObj) peek(idx int) (*big.Int, error) {
-	if len(s.stack.Data()) <= idx || idx < 0 {
-		return nil, fmt.Errorf("tracer accessed out of bound stack: size %d, index %d", len(s.stack.Data()), idx)
+	if s.stack.Len() <= idx || idx < 0 {
+		return nil, fmt.Errorf("tracer accessed out of bound stack: size %d, index %d", s.stack.Len(), idx)
 	}
 	return s.stack.Back(idx).ToBig(), nil
 }
 
 func (s *stackObj) Length() int {
-	return len(s.stack.Data())
+	return s.stack.Len()
 }
 
 func (s *stackObj) setupObject() *goja.Object {
@@ -103,8 +103,9 @@ func (al accessList) accessList() types.AccessList {
 // AccessListTracer is a tracer that accumulates touched accounts and storage
 // slots into an internal set.
 type AccessListTracer struct {
-	excl map[common.Address]struct{} // Set of account to exclude from the list
-	list accessList                  // Set of accounts and storage slots touched
+	excl      map[common.Address]struct{} // Set of account to exclude from the list
+	list      accessList                  // Set of accounts and storage slots touched
+	preimages map[common.Hash][]byte      // Preimages of the hashed state keys written
 }
 
 // NewAccessListTracer creates a new tracer that can generate AccessLists.
@@ -127,8 +128,9 @@ func NewAccessListTracer(acl types.AccessList, from, to common.Address, precompi
 		}
 	}
 	return &AccessListTracer{
-		excl: excl,
-		list: list,
+		excl:      excl,
+		list:      list,
+		preimages: make(map[common.Hash][]byte),
 	}
 }
 
@@ -138,20 +140,19 @@ func (a *AccessListTracer) CaptureStart(env *vm.EVM, from common.Address, to com
 // CaptureState captures all opcodes that touch storage or addresses and adds them to the accesslist.
 func (a *AccessListTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
 	stack := scope.Stack
-	stackData := stack.Data()
-	stackLen := len(stackData)
+	stackLen := stack.Len()
 	if (op == vm.SLOAD || op == vm.SSTORE) && stackLen >= 1 {
-		slot := common.Hash(stackData[stackLen-1].Bytes32())
+		slot := common.Hash(stack.Back(0).Bytes32())
 		a.list.addSlot(scope.Contract.Address(), slot)
 	}
 	if (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT) && stackLen >= 1 {
-		addr := common.Address(stackData[stackLen-1].Bytes20())
+		addr := common.Address(stack.Back(0).Bytes20())
 		if _, ok := a.excl[addr]; !ok {
 			a.list.addAddress(addr)
 		}
 	}
 	if (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE) && stackLen >= 5 {
-		addr := common.Address(stackData[stackLen-2].Bytes20())
+		addr := common.Address(stack.Back(1).Bytes20())
 		if _, ok := a.excl[addr]; !ok {
 			a.list.addAddress(addr)
 		}
@@ -172,6 +173,22 @@ func (*AccessListTracer) CaptureTxStart(gasLimit uint64) {}
 
 func (*AccessListTracer) CaptureTxEnd(restGas uint64) {}
 
+// CapturePreimage records the preimage of a hashed account address or storage key.
+func (a *AccessListTracer) CapturePreimage(hash common.Hash, preimage []byte) {
+	a.preimages[hash] = preimage
+}
+
+func (*AccessListTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*AccessListTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {
+}
+
+// Preimages returns the keccak256 preimages of the account addresses and storage
+// keys written during the traced execution.
+func (a *AccessListTracer) Preimages() map[common.Hash][]byte {
+	return a.preimages
+}
+
 // AccessList returns the current accesslist maintained by the tracer.
 func (a *AccessListTracer) AccessList() types.AccessList {
 	return a.list.accessList()
@@ -0,0 +1,112 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// CoverageCollector is a tracer recording which instructions of the executed
+// contracts were reached, as a bitmask with one bit per code offset. The
+// coverage of all calls into the same contract is combined, so the collector
+// can be reused across the transactions of a test suite. Init code executed by
+// contract creations is not covered.
+type CoverageCollector struct {
+	coverage map[common.Address][]byte
+}
+
+// NewCoverageCollector creates a new tracer collecting code coverage.
+func NewCoverageCollector() *CoverageCollector {
+	return &CoverageCollector{
+		coverage: make(map[common.Address][]byte),
+	}
+}
+
+func (*CoverageCollector) CaptureTxStart(gasLimit uint64) {}
+
+func (*CoverageCollector) CaptureTxEnd(restGas uint64) {}
+
+func (*CoverageCollector) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+func (*CoverageCollector) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+func (*CoverageCollector) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+func (*CoverageCollector) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+// CaptureState marks the instruction about to be executed as covered.
+func (c *CoverageCollector) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	contract := scope.Contract
+	if contract.CodeAddr == nil || pc >= uint64(len(contract.Code)) {
+		return
+	}
+	// Delegated calls run the code of another account, account it there
+	addr := *contract.CodeAddr
+	bits, ok := c.coverage[addr]
+	if !ok || len(bits) < (len(contract.Code)+7)/8 {
+		bits = make([]byte, (len(contract.Code)+7)/8)
+		copy(bits, c.coverage[addr])
+		c.coverage[addr] = bits
+	}
+	bits[pc/8] |= 0x80 >> (pc % 8)
+}
+
+func (*CoverageCollector) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (*CoverageCollector) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+func (*CoverageCollector) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*CoverageCollector) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {
+}
+
+// Coverage returns the coverage bitmask of the code of the contract at addr, the
+// most significant bit of the first byte standing for offset 0. It is nil if the
+// code of the contract was never executed.
+func (c *CoverageCollector) Coverage(addr common.Address) []byte {
+	return c.coverage[addr]
+}
+
+// CoverageReport disassembles the code, marking every instruction with a '+' if
+// it is covered by the bitmask, or with a '-' if it isn't.
+func CoverageReport(code []byte, coverage []byte) string {
+	var (
+		report strings.Builder
+		it     = asm.NewInstructionIterator(code)
+	)
+	for it.Next() {
+		pc, marker := it.PC(), "-"
+		if pc/8 < uint64(len(coverage)) && coverage[pc/8]&(0x80>>(pc%8)) != 0 {
+			marker = "+"
+		}
+		if it.Arg() != nil && 0 < len(it.Arg()) {
+			fmt.Fprintf(&report, "%s %05x: %v %#x\n", marker, pc, it.Op(), it.Arg())
+		} else {
+			fmt.Fprintf(&report, "%s %05x: %v\n", marker, pc, it.Op())
+		}
+	}
+	return report.String()
+}
@@ -27,8 +27,10 @@ func (s StructLog) MarshalJSON() ([]byte, error) {
 		ReturnData    hexutil.Bytes               `json:"returnData,omitempty"`
 		Storage       map[common.Hash]common.Hash `json:"-"`
 		Depth         int                         `json:"depth"`
+		CallDepth     *int                        `json:"callDepth,omitempty"`
 		RefundCounter uint64                      `json:"refund"`
 		Err           error                       `json:"-"`
+		FaultReason   string                      `json:"faultReason,omitempty"`
 		OpName        string                      `json:"opName"`
 		ErrorString   string                      `json:"error,omitempty"`
 	}
@@ -43,8 +45,10 @@ func (s StructLog) MarshalJSON() ([]byte, error) {
 	enc.ReturnData = s.ReturnData
 	enc.Storage = s.Storage
 	enc.Depth = s.Depth
+	enc.CallDepth = s.CallDepth
 	enc.RefundCounter = s.RefundCounter
 	enc.Err = s.Err
+	enc.FaultReason = s.FaultReason
 	enc.OpName = s.OpName()
 	enc.ErrorString = s.ErrorString()
 	return json.Marshal(&enc)
@@ -63,8 +67,10 @@ func (s *StructLog) UnmarshalJSON(input []byte) error {
 		ReturnData    *hexutil.Bytes              `json:"returnData,omitempty"`
 		Storage       map[common.Hash]common.Hash `json:"-"`
 		Depth         *int                        `json:"depth"`
+		CallDepth     *int                        `json:"callDepth,omitempty"`
 		RefundCounter *uint64                     `json:"refund"`
 		Err           error                       `json:"-"`
+		FaultReason   *string                     `json:"faultReason,omitempty"`
 	}
 	var dec StructLog
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -100,11 +106,17 @@ func (s *StructLog) UnmarshalJSON(input []byte) error {
 	if dec.Depth != nil {
 		s.Depth = *dec.Depth
 	}
+	if dec.CallDepth != nil {
+		s.CallDepth = dec.CallDepth
+	}
 	if dec.RefundCounter != nil {
 		s.RefundCounter = *dec.RefundCounter
 	}
 	if dec.Err != nil {
 		s.Err = dec.Err
 	}
+	if dec.FaultReason != nil {
+		s.FaultReason = *dec.FaultReason
+	}
 	return nil
 }
@@ -52,8 +52,15 @@ type Config struct {
 	DisableStack     bool // disable stack capture
 	DisableStorage   bool // disable storage capture
 	EnableReturnData bool // enable return data capture
+	EnableCallDepth  bool // enable zero-based call depth capture in the JSON logger
 	Debug            bool // print output during capture end
 	Limit            int  // maximum length of output, but zero means unlimited
+	// GasHistogram, if non-nil, makes the JSON logger accumulate the gas cost
+	// per opcode into it instead of printing the individual steps. The gas
+	// spent by a sub-call is attributed to the opcodes executed by the callee,
+	// the call opcode only holds its own cost and the gas the callee used
+	// without executing opcodes, e.g. in a precompile.
+	GasHistogram map[vm.OpCode]uint64 `json:"-"`
 	// Chain overrides, can be used to execute a trace using future fork rules
 	Overrides *params.ChainConfig `json:"overrides,omitempty"`
 }
@@ -73,8 +80,10 @@ type StructLog struct {
 	ReturnData    []byte                      `json:"returnData,omitempty"`
 	Storage       map[common.Hash]common.Hash `json:"-"`
 	Depth         int                         `json:"depth"`
+	CallDepth     *int                        `json:"callDepth,omitempty"`
 	RefundCounter uint64                      `json:"refund"`
 	Err           error                       `json:"-"`
+	FaultReason   string                      `json:"faultReason,omitempty"`
 }
 
 // overrides for gencodec
@@ -169,13 +178,13 @@ func (l *StructLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, s
 	// Copy a snapshot of the current stack state to a new buffer
 	var stck []uint256.Int
 	if !l.cfg.DisableStack {
-		stck = make([]uint256.Int, len(stack.Data()))
-		for i, item := range stack.Data() {
-			stck[i] = item
+		items := stack.ToSlice()
+		stck = make([]uint256.Int, len(items))
+		for i, item := range items {
+			stck[i] = *item
 		}
 	}
-	stackData := stack.Data()
-	stackLen := len(stackData)
+	stackLen := stack.Len()
 	// Copy a snapshot of the current storage to a new container
 	var storage Storage
 	if !l.cfg.DisableStorage && (op == vm.SLOAD || op == vm.SSTORE) {
@@ -187,7 +196,7 @@ func (l *StructLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, s
 		// capture SLOAD opcodes and record the read entry in the local storage
 		if op == vm.SLOAD && stackLen >= 1 {
 			var (
-				address = common.Hash(stackData[stackLen-1].Bytes32())
+				address = common.Hash(stack.Back(0).Bytes32())
 				value   = l.env.StateDB.GetState(contract.Address(), address)
 			)
 			l.storage[contract.Address()][address] = value
@@ -195,8 +204,8 @@ func (l *StructLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, s
 		} else if op == vm.SSTORE && stackLen >= 2 {
 			// capture SSTORE opcodes and record the written entry in the local storage.
 			var (
-				value   = common.Hash(stackData[stackLen-2].Bytes32())
-				address = common.Hash(stackData[stackLen-1].Bytes32())
+				value   = common.Hash(stack.Back(1).Bytes32())
+				address = common.Hash(stack.Back(0).Bytes32())
 			)
 			l.storage[contract.Address()][address] = value
 			storage = l.storage[contract.Address()].Copy()
@@ -208,13 +217,37 @@ func (l *StructLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, s
 		copy(rdata, rData)
 	}
 	// create a new snapshot of the EVM.
-	log := StructLog{pc, op, gas, cost, mem, memory.Len(), stck, rdata, storage, depth, l.env.StateDB.GetRefund(), err}
+	log := StructLog{
+		Pc:            pc,
+		Op:            op,
+		Gas:           gas,
+		GasCost:       cost,
+		Memory:        mem,
+		MemorySize:    memory.Len(),
+		Stack:         stck,
+		ReturnData:    rdata,
+		Storage:       storage,
+		Depth:         depth,
+		RefundCounter: l.env.StateDB.GetRefund(),
+		Err:           err,
+	}
+	if err != nil {
+		log.FaultReason = scope.FaultReason
+	}
 	l.logs = append(l.logs, log)
 }
 
 // CaptureFault implements the EVMLogger interface to trace an execution fault
 // while running an opcode.
 func (l *StructLogger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if l.interrupt.Load() || len(l.logs) == 0 {
+		return
+	}
+	// The faulting opcode was already captured before it executed, attach the
+	// reason to that step unless a nested call has logged steps since then.
+	if last := &l.logs[len(l.logs)-1]; last.Pc == pc && last.Depth == depth {
+		last.FaultReason = scope.FaultReason
+	}
 }
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
@@ -269,6 +302,12 @@ func (l *StructLogger) CaptureTxEnd(restGas uint64) {
 	l.usedGas = l.gasLimit - restGas
 }
 
+func (*StructLogger) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+func (*StructLogger) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*StructLogger) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {}
+
 // StructLogs returns the captured log entries.
 func (l *StructLogger) StructLogs() []StructLog { return l.logs }
 
@@ -367,7 +406,7 @@ func (t *mdLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope
 	if !t.cfg.DisableStack {
 		// format stack
 		var a []string
-		for _, elem := range stack.Data() {
+		for _, elem := range stack.ToSlice() {
 			a = append(a, elem.Hex())
 		}
 		b := fmt.Sprintf("[%v]", strings.Join(a, ","))
@@ -398,6 +437,12 @@ func (*mdLogger) CaptureTxStart(gasLimit uint64) {}
 
 func (*mdLogger) CaptureTxEnd(restGas uint64) {}
 
+func (*mdLogger) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+func (*mdLogger) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*mdLogger) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {}
+
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
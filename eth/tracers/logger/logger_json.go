@@ -23,13 +23,32 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
 )
 
 type JSONLogger struct {
 	encoder *json.Encoder
 	cfg     *Config
 	env     *vm.EVM
+
+	gasFrames []gasFrame // Call frames entered, for attributing their gas in the histogram
+}
+
+// gasFrame tracks the gas attributed in the gas histogram while executing a
+// call frame.
+type gasFrame struct {
+	op         vm.OpCode // Opcode that entered the frame
+	attributed uint64    // Gas attributed to the opcodes of the frame and its sub-calls
+}
+
+// attributeGas adds gas spent by op to the gas histogram.
+func (l *JSONLogger) attributeGas(op vm.OpCode, gas uint64) {
+	l.cfg.GasHistogram[op] += gas
+	if n := len(l.gasFrames); n > 0 {
+		l.gasFrames[n-1].attributed += gas
+	}
 }
 
 // NewJSONLogger creates a new EVM tracer that prints execution steps as JSON objects
@@ -47,12 +66,22 @@ func (l *JSONLogger) CaptureStart(env *vm.EVM, from, to common.Address, create b
 }
 
 func (l *JSONLogger) CaptureFault(pc uint64, op vm.OpCode, gas uint64, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if l.cfg.GasHistogram != nil {
+		return
+	}
 	// TODO: Add rData to this interface as well
 	l.CaptureState(pc, op, gas, cost, scope, nil, depth, err)
 }
 
 // CaptureState outputs state information on the logger.
 func (l *JSONLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if l.cfg.GasHistogram != nil {
+		// Failing steps don't have a meaningful cost, leave them out
+		if err == nil {
+			l.attributeGas(op, cost)
+		}
+		return
+	}
 	memory := scope.Memory
 	stack := scope.Stack
 
@@ -70,33 +99,112 @@ func (l *JSONLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, sco
 		log.Memory = memory.Data()
 	}
 	if !l.cfg.DisableStack {
-		log.Stack = stack.Data()
+		items := stack.ToSlice()
+		log.Stack = make([]uint256.Int, len(items))
+		for i, item := range items {
+			log.Stack[i] = *item
+		}
 	}
 	if l.cfg.EnableReturnData {
 		log.ReturnData = rData
 	}
+	if l.cfg.EnableCallDepth {
+		callDepth := scope.CallDepth()
+		log.CallDepth = &callDepth
+	}
+	if err != nil {
+		log.FaultReason = scope.FaultReason
+	}
 	l.encoder.Encode(log)
 }
 
+// GasHistogram returns the gas accumulated per opcode, keyed by opcode name, or
+// nil if the logger isn't configured to collect it.
+func (l *JSONLogger) GasHistogram() map[string]uint64 {
+	if l.cfg.GasHistogram == nil {
+		return nil
+	}
+	histogram := make(map[string]uint64, len(l.cfg.GasHistogram))
+	for op, gas := range l.cfg.GasHistogram {
+		histogram[op.String()] = gas
+	}
+	return histogram
+}
+
 // CaptureEnd is triggered at end of execution.
 func (l *JSONLogger) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	type stepLog struct {
+		Pc      uint64              `json:"pc"`
+		Op      vm.OpCode           `json:"op"`
+		OpName  string              `json:"opName"`
+		Gas     math.HexOrDecimal64 `json:"gas"`
+		GasCost math.HexOrDecimal64 `json:"gasCost"`
+		Depth   int                 `json:"depth"`
+	}
 	type endLog struct {
-		Output  string              `json:"output"`
-		GasUsed math.HexOrDecimal64 `json:"gasUsed"`
-		Err     string              `json:"error,omitempty"`
+		Output    string              `json:"output"`
+		GasUsed   math.HexOrDecimal64 `json:"gasUsed"`
+		Status    string              `json:"status"`
+		Err       string              `json:"error,omitempty"`
+		LastSteps []stepLog           `json:"lastNSteps,omitempty"`
 	}
-	var errMsg string
+	var (
+		errMsg    string
+		status    = types.ReceiptStatus(types.ReceiptStatusSuccessful)
+		lastSteps []stepLog
+	)
 	if err != nil {
 		errMsg = err.Error()
+		status = types.ReceiptStatus(types.ReceiptStatusFailed)
+
+		// Include the instructions leading to the error, if they were retained
+		for _, step := range l.env.LastSteps() {
+			lastSteps = append(lastSteps, stepLog{step.Pc, step.Op, step.Op.String(), math.HexOrDecimal64(step.Gas), math.HexOrDecimal64(step.Cost), step.Depth})
+		}
 	}
-	l.encoder.Encode(endLog{common.Bytes2Hex(output), math.HexOrDecimal64(gasUsed), errMsg})
+	l.encoder.Encode(endLog{common.Bytes2Hex(output), math.HexOrDecimal64(gasUsed), status.String(), errMsg, lastSteps})
 }
 
 func (l *JSONLogger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if l.cfg.GasHistogram == nil {
+		return
+	}
+	// The cost of the call opcodes includes the gas forwarded to the callee,
+	// which is attributed to the callee's opcodes instead. Creations deduct
+	// the forwarded gas outside of their cost.
+	if typ != vm.CREATE && typ != vm.CREATE2 {
+		l.cfg.GasHistogram[typ] -= gas
+		if n := len(l.gasFrames); n > 0 {
+			l.gasFrames[n-1].attributed -= gas
+		}
+	}
+	l.gasFrames = append(l.gasFrames, gasFrame{op: typ})
 }
 
-func (l *JSONLogger) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (l *JSONLogger) CaptureExit(output []byte, gasUsed uint64, err error) {
+	n := len(l.gasFrames)
+	if l.cfg.GasHistogram == nil || n == 0 {
+		return
+	}
+	frame := l.gasFrames[n-1]
+	l.gasFrames = l.gasFrames[:n-1]
+
+	// Gas used by the callee without executing opcodes, e.g. by a precompile
+	// or when aborting with an error, is attributed to the call itself
+	if gasUsed > frame.attributed {
+		l.cfg.GasHistogram[frame.op] += gasUsed - frame.attributed
+	}
+	if n > 1 {
+		l.gasFrames[n-2].attributed += gasUsed
+	}
+}
 
 func (l *JSONLogger) CaptureTxStart(gasLimit uint64) {}
 
 func (l *JSONLogger) CaptureTxEnd(restGas uint64) {}
+
+func (l *JSONLogger) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+func (*JSONLogger) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*JSONLogger) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {}
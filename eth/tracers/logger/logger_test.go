@@ -17,14 +17,19 @@
 package logger
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -55,7 +60,7 @@ func (*dummyStatedb) SetState(_ common.Address, _ common.Hash, _ common.Hash) {}
 func TestStoreCapture(t *testing.T) {
 	var (
 		logger   = NewStructLogger(nil)
-		env      = vm.NewEVM(vm.BlockContext{}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{Tracer: logger})
+		env      = vm.NewEVMWithTracer(vm.BlockContext{}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{}, logger)
 		contract = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
 	)
 	contract.Code = []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.SSTORE)}
@@ -75,6 +80,186 @@ func TestStoreCapture(t *testing.T) {
 	}
 }
 
+// Tests that the struct logger records why the execution faulted, both for
+// errors raised before and after the faulting opcode was captured.
+func TestStructLoggerFaultReason(t *testing.T) {
+	tests := []struct {
+		code []byte
+		want string
+	}{
+		{[]byte{byte(vm.PUSH1), 0x1, byte(vm.SWAP3)}, "stack underflow on SWAP3: need 4 items, have 1"},
+		{[]byte{byte(vm.PUSH1), 0x5, byte(vm.JUMP)}, "invalid jump destination on JUMP"},
+	}
+	for i, tt := range tests {
+		var (
+			logger   = NewStructLogger(nil)
+			env      = vm.NewEVMWithTracer(vm.BlockContext{}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{}, logger)
+			contract = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+		)
+		contract.Code = tt.code
+		logger.CaptureStart(env, common.Address{}, contract.Address(), false, nil, 0, nil)
+		if _, err := env.Interpreter().Run(contract, []byte{}, false); err == nil {
+			t.Fatalf("test %d: expected execution to fail", i)
+		}
+		logs := logger.StructLogs()
+		if have := logs[len(logs)-1].FaultReason; have != tt.want {
+			t.Errorf("test %d: fault reason mismatch: have %q, want %q", i, have, tt.want)
+		}
+		for _, log := range logs[:len(logs)-1] {
+			if log.FaultReason != "" {
+				t.Errorf("test %d: fault reason on successful step %v: %q", i, log.Op, log.FaultReason)
+			}
+		}
+	}
+}
+
+// Tests that the access list tracer collects the preimages of the storage keys
+// and accounts hashed by the state during traced calls.
+func TestAccessListTracerPreimages(t *testing.T) {
+	var (
+		address = common.HexToAddress("0xaa")
+		created = common.HexToAddress("0xbb")
+		later   = common.HexToAddress("0xcc")
+		slot    = common.BigToHash(big.NewInt(7))
+	)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetCode(address, []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x7, byte(vm.SSTORE)})
+
+	var (
+		tracer = NewAccessListTracer(nil, common.Address{}, address, nil)
+		vmctx  = vm.BlockContext{
+			CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		env = vm.NewEVMWithTracer(vmctx, vm.TxContext{}, statedb, params.TestChainConfig, vm.Config{}, tracer)
+	)
+	if _, _, err := env.Call(vm.AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); err != nil {
+		t.Fatal(err)
+	}
+	// Sending value to a missing account creates it
+	if _, _, err := env.Call(vm.AccountRef(common.Address{}), created, nil, 100000, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	// Accounts created outside of a call are not reported, the hook is removed
+	statedb.CreateAccount(later)
+
+	want := map[common.Hash][]byte{
+		crypto.Keccak256Hash(slot[:]):    slot[:],
+		crypto.Keccak256Hash(created[:]): created[:],
+	}
+	if have := tracer.Preimages(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("preimage mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that the storage tracer records SLOAD and SSTORE accesses in order.
+func TestStorageTracer(t *testing.T) {
+	var (
+		address = common.HexToAddress("0xaa")
+		slot    = common.BigToHash(big.NewInt(7))
+		one     = common.BigToHash(big.NewInt(1))
+		two     = common.BigToHash(big.NewInt(2))
+	)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetState(address, slot, one)
+	statedb.SetCode(address, []byte{
+		byte(vm.PUSH1), 0x7, byte(vm.SLOAD),
+		byte(vm.PUSH1), 0x2, byte(vm.PUSH1), 0x7, byte(vm.SSTORE),
+	})
+	var (
+		tracer   = NewStorageTracer()
+		env      = vm.NewEVM(vm.BlockContext{}, vm.TxContext{}, statedb, params.TestChainConfig, vm.Config{StorageTracer: tracer})
+		contract = vm.NewContract(vm.AccountRef(common.Address{}), vm.AccountRef(address), new(big.Int), 100000)
+	)
+	contract.SetCallCode(&address, statedb.GetCodeHash(address), statedb.GetCode(address))
+	if _, err := env.Interpreter().Run(contract, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	want := []StorageAccess{
+		{Address: address, Key: slot, Value: one},
+		{Address: address, Key: slot, Value: two, OldValue: &one},
+	}
+	if have := tracer.Accesses(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("storage access mismatch: have %+v, want %+v", have, want)
+	}
+}
+
+func TestCoverageCollector(t *testing.T) {
+	address := common.HexToAddress("0xaa")
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	// push(0) calldataload push(9) jumpi push(1) stop jumpdest push(2) stop
+	statedb.SetCode(address, common.Hex2Bytes("6000356009576001005b600200"))
+
+	var (
+		collector = NewCoverageCollector()
+		env       = vm.NewEVMWithTracer(vm.BlockContext{}, vm.TxContext{}, statedb, params.TestChainConfig, vm.Config{}, collector)
+	)
+	run := func(input []byte) {
+		contract := vm.NewContract(vm.AccountRef(common.Address{}), vm.AccountRef(address), new(big.Int), 100000)
+		contract.SetCallCode(&address, statedb.GetCodeHash(address), statedb.GetCode(address))
+		if _, err := env.Interpreter().Run(contract, input, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Skip the jump, covering everything up to the first stop
+	run(nil)
+	if have, want := collector.Coverage(address), []byte{0xb6, 0x80}; !bytes.Equal(have, want) {
+		t.Fatalf("coverage mismatch: have %x, want %x", have, want)
+	}
+	if report := CoverageReport(statedb.GetCode(address), collector.Coverage(address)); !strings.Contains(report, "+ 00005: JUMPI\n") || !strings.Contains(report, "- 00009: JUMPDEST\n") {
+		t.Fatalf("unexpected coverage report:\n%s", report)
+	}
+	// Take the jump, the coverage of both runs is combined
+	run(common.LeftPadBytes([]byte{1}, 32))
+	if have, want := collector.Coverage(address), []byte{0xb6, 0xe8}; !bytes.Equal(have, want) {
+		t.Fatalf("coverage mismatch: have %x, want %x", have, want)
+	}
+	if report := CoverageReport(statedb.GetCode(address), collector.Coverage(address)); strings.Contains(report, "- ") {
+		t.Fatalf("unexpected coverage report:\n%s", report)
+	}
+}
+
+func TestJSONLoggerCallDepth(t *testing.T) {
+	var (
+		outer = common.HexToAddress("0xaa")
+		inner = common.HexToAddress("0xbb")
+	)
+	statedb := state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+	// push(0) dup1 dup1 dup1 dup1 push(0xbb) gas call stop
+	statedb.SetCode(outer, common.Hex2Bytes("60008080808060bb5af100"))
+	// push(0) pop stop
+	statedb.SetCode(inner, common.Hex2Bytes("60005000"))
+
+	var (
+		out    bytes.Buffer
+		tracer = NewJSONLogger(&Config{EnableCallDepth: true, DisableStack: true}, &out)
+		vmctx  = vm.BlockContext{
+			CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		env = vm.NewEVMWithTracer(vmctx, vm.TxContext{}, statedb, params.TestChainConfig, vm.Config{}, tracer)
+	)
+	if _, _, err := env.Call(vm.AccountRef(common.Address{}), outer, nil, 100000, new(big.Int)); err != nil {
+		t.Fatal(err)
+	}
+	var depths []int
+	for dec := json.NewDecoder(&out); dec.More(); {
+		var step struct {
+			CallDepth *int `json:"callDepth"`
+		}
+		if err := dec.Decode(&step); err != nil {
+			t.Fatal(err)
+		}
+		if step.CallDepth != nil {
+			depths = append(depths, *step.CallDepth)
+		}
+	}
+	want := []int{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 0}
+	if !reflect.DeepEqual(depths, want) {
+		t.Fatalf("call depth mismatch: have %v, want %v", depths, want)
+	}
+}
+
 // Tests that blank fields don't appear in logs when JSON marshalled, to reduce
 // logs bloat and confusion. See https://github.com/ethereum/go-ethereum/issues/24487
 func TestStructLogMarshalingOmitEmpty(t *testing.T) {
@@ -91,6 +276,8 @@ func TestStructLogMarshalingOmitEmpty(t *testing.T) {
 			`{"pc":0,"op":0,"gas":"0x0","gasCost":"0x0","memory":"0x0000","memSize":2,"stack":null,"depth":0,"refund":0,"opName":"STOP"}`},
 		{"with 0-size mem", &StructLog{Memory: make([]byte, 0)},
 			`{"pc":0,"op":0,"gas":"0x0","gasCost":"0x0","memSize":0,"stack":null,"depth":0,"refund":0,"opName":"STOP"}`},
+		{"with fault reason", &StructLog{Err: fmt.Errorf("this failed"), FaultReason: "this failed on STOP"},
+			`{"pc":0,"op":0,"gas":"0x0","gasCost":"0x0","memSize":0,"stack":null,"depth":0,"refund":0,"faultReason":"this failed on STOP","opName":"STOP","error":"this failed"}`},
 	}
 
 	for _, tt := range tests {
@@ -105,3 +292,161 @@ func TestStructLogMarshalingOmitEmpty(t *testing.T) {
 		})
 	}
 }
+
+// Tests that the JSON logger explains why the execution faulted.
+func TestJSONLoggerFaultReason(t *testing.T) {
+	tests := []struct {
+		code []byte
+		gas  uint64
+		want string
+	}{
+		{[]byte{byte(vm.PUSH1), 0x1, byte(vm.SWAP3)}, 100000, "stack underflow on SWAP3: need 4 items, have 1"},
+		{[]byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.ADD)}, 8, "out of gas on ADD: need 3, have 2 (short by 1)"},
+		{[]byte{0xfe}, 100000, "invalid opcode: INVALID on INVALID"},
+	}
+	for i, tt := range tests {
+		var (
+			out      bytes.Buffer
+			logger   = NewJSONLogger(nil, &out)
+			env      = vm.NewEVMWithTracer(vm.BlockContext{}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{}, logger)
+			contract = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), tt.gas)
+		)
+		contract.Code = tt.code
+		logger.CaptureStart(env, common.Address{}, contract.Address(), false, nil, 0, nil)
+		if _, err := env.Interpreter().Run(contract, []byte{}, false); err == nil {
+			t.Fatalf("test %d: expected execution to fail", i)
+		}
+		var (
+			lines = bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+			last  StructLog
+		)
+		if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil {
+			t.Fatalf("test %d: failed to decode log: %v", i, err)
+		}
+		if last.FaultReason != tt.want {
+			t.Errorf("test %d: fault reason mismatch: have %q, want %q", i, last.FaultReason, tt.want)
+		}
+		for _, line := range lines[:len(lines)-1] {
+			if bytes.Contains(line, []byte("faultReason")) {
+				t.Errorf("test %d: fault reason on successful step: %s", i, line)
+			}
+		}
+	}
+}
+
+// Tests that the JSON logger accumulates the gas per opcode instead of printing
+// the steps if asked to.
+func TestJSONLoggerGasHistogram(t *testing.T) {
+	var (
+		out      bytes.Buffer
+		logger   = NewJSONLogger(&Config{GasHistogram: make(map[vm.OpCode]uint64)}, &out)
+		env      = vm.NewEVMWithTracer(vm.BlockContext{}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{}, logger)
+		contract = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+	)
+	contract.Code = []byte{
+		byte(vm.PUSH1), 0x3, byte(vm.PUSH1), 0x5, byte(vm.ADD), // 3 + 3 + 3
+		byte(vm.PUSH1), 0x0, byte(vm.MSTORE), // 3 + 3 + 3 for one word of memory
+		byte(vm.PUSH1), 0x0, byte(vm.MLOAD), // 3 + 3
+		byte(vm.STOP), // 0
+	}
+	logger.CaptureStart(env, common.Address{}, contract.Address(), false, nil, 0, nil)
+	if _, err := env.Interpreter().Run(contract, []byte{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("unexpected step output: %s", out.String())
+	}
+	want := map[string]uint64{"PUSH1": 12, "ADD": 3, "MSTORE": 6, "MLOAD": 3, "STOP": 0}
+	if have := logger.GasHistogram(); !reflect.DeepEqual(have, want) {
+		t.Errorf("histogram mismatch:\n\thave: %v\n\twant: %v", have, want)
+	}
+}
+
+// Tests that the gas histogram doesn't count the gas forwarded to sub-calls
+// twice, adding up to the gas used by the execution.
+func TestJSONLoggerGasHistogramNested(t *testing.T) {
+	var (
+		statedb = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		caller  = common.HexToAddress("0xca11e4")
+		callee  = common.HexToAddress("0xca11ee")
+		out     bytes.Buffer
+		logger  = NewJSONLogger(&Config{GasHistogram: make(map[vm.OpCode]uint64)}, &out)
+		vmctx   = vm.BlockContext{
+			CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		env = vm.NewEVMWithTracer(vmctx, vm.TxContext{}, statedb, params.TestChainConfig, vm.Config{}, logger)
+	)
+	// Call the callee, then the identity precompile, forwarding 0xffff gas to both
+	var code []byte
+	for _, target := range []common.Address{callee, common.BytesToAddress([]byte{4})} {
+		code = append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20))
+		code = append(code, target.Bytes()...)
+		code = append(code, byte(vm.PUSH2), 0xff, 0xff, byte(vm.CALL), byte(vm.POP))
+	}
+	statedb.SetCode(caller, code)
+	// The callee stores a value, so its execution is not free
+	statedb.SetCode(callee, []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE)})
+
+	const gasLimit = 1_000_000
+	_, leftOver, err := env.Call(vm.AccountRef(common.Address{}), caller, nil, gasLimit, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total uint64
+	for _, gas := range logger.GasHistogram() {
+		total += gas
+	}
+	if total != gasLimit-leftOver {
+		t.Fatalf("histogram total mismatch: have %d, want %d (%v)", total, gasLimit-leftOver, logger.GasHistogram())
+	}
+	if have := logger.GasHistogram()["SSTORE"]; have < params.SstoreSetGasEIP2200 {
+		t.Fatalf("callee gas not attributed: SSTORE %d", have)
+	}
+	if have := logger.GasHistogram()["CALL"]; have >= 2*0xffff {
+		t.Fatalf("forwarded gas attributed to CALL: %d", have)
+	}
+}
+
+// Tests that the JSON logger reports the last instructions retained by the EVM
+// along with an execution error.
+func TestJSONLoggerLastSteps(t *testing.T) {
+	var (
+		out      bytes.Buffer
+		logger   = NewJSONLogger(nil, &out)
+		env      = vm.NewEVMWithTracer(vm.BlockContext{}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{StepBackBuffer: 3}, logger)
+		contract = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+	)
+	// push(1) push(0) add push(0) jump, failing on the invalid jump destination
+	contract.Code = []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.ADD), byte(vm.PUSH1), 0x0, byte(vm.JUMP)}
+	logger.CaptureStart(env, common.Address{}, contract.Address(), false, nil, 0, nil)
+	_, err := env.Interpreter().Run(contract, []byte{}, false)
+	if err == nil {
+		t.Fatal("expected execution to fail")
+	}
+	out.Reset()
+	logger.CaptureEnd(nil, 0, err)
+
+	var end struct {
+		LastSteps []struct {
+			Pc     uint64 `json:"pc"`
+			OpName string `json:"opName"`
+		} `json:"lastNSteps"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &end); err != nil {
+		t.Fatalf("failed to decode end log: %v", err)
+	}
+	var have []string
+	for _, step := range end.LastSteps {
+		have = append(have, fmt.Sprintf("%d:%s", step.Pc, step.OpName))
+	}
+	if want := []string{"4:ADD", "5:PUSH1", "7:JUMP"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("last steps mismatch: have %v, want %v", have, want)
+	}
+	// Successful executions don't report any steps
+	out.Reset()
+	logger.CaptureEnd(nil, 0, nil)
+	if strings.Contains(out.String(), "lastNSteps") {
+		t.Errorf("last steps reported without error: %s", out.String())
+	}
+}
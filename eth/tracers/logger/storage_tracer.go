@@ -0,0 +1,57 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StorageAccess is a single storage slot read or write made by an SLOAD or an
+// SSTORE.
+type StorageAccess struct {
+	Address  common.Address `json:"address"`
+	Key      common.Hash    `json:"key"`
+	Value    common.Hash    `json:"value"`              // Value read or written
+	OldValue *common.Hash   `json:"oldValue,omitempty"` // Value before a write, nil for reads
+}
+
+// StorageTracer is a lightweight tracer that only records the storage accesses
+// of an execution, in the order they happened. It is meant to be installed as
+// vm.Config.StorageTracer, so that the opcodes aren't traced.
+type StorageTracer struct {
+	accesses []StorageAccess
+}
+
+// NewStorageTracer creates a new tracer recording storage accesses.
+func NewStorageTracer() *StorageTracer {
+	return &StorageTracer{}
+}
+
+// CaptureStorageRead records a storage slot loaded by SLOAD.
+func (t *StorageTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {
+	t.accesses = append(t.accesses, StorageAccess{Address: addr, Key: key, Value: value})
+}
+
+// CaptureStorageWrite records a storage slot stored by SSTORE.
+func (t *StorageTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {
+	t.accesses = append(t.accesses, StorageAccess{Address: addr, Key: key, Value: newValue, OldValue: &oldValue})
+}
+
+// Accesses returns the storage accesses recorded so far, in execution order.
+func (t *StorageTracer) Accesses() []StorageAccess {
+	return t.accesses
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// MultiTracer is an EVMLogger running multiple loggers over the same execution,
+// calling each of them in order for every hook.
+//
+// A logger panicking is recovered from and disabled for the remainder of the
+// execution, as its state can't be trusted anymore. The other loggers keep
+// running, and the failure is reported through Err.
+type MultiTracer struct {
+	tracers []vm.EVMLogger
+	failed  []bool
+	err     error
+}
+
+// NewMultiTracer creates a logger fanning out to all the given loggers.
+func NewMultiTracer(tracers ...vm.EVMLogger) *MultiTracer {
+	return &MultiTracer{
+		tracers: tracers,
+		failed:  make([]bool, len(tracers)),
+	}
+}
+
+// Add appends a logger to be called after the existing ones.
+func (t *MultiTracer) Add(tracer vm.EVMLogger) {
+	t.tracers = append(t.tracers, tracer)
+	t.failed = append(t.failed, false)
+}
+
+// Err returns the error of the first logger that panicked, if any.
+func (t *MultiTracer) Err() error {
+	return t.err
+}
+
+// each invokes the hook on all the loggers still running.
+func (t *MultiTracer) each(hook string, fn func(vm.EVMLogger)) {
+	for i, tracer := range t.tracers {
+		if !t.failed[i] {
+			t.call(i, hook, tracer, fn)
+		}
+	}
+}
+
+// call invokes the hook on a single logger, disabling it if it panics.
+func (t *MultiTracer) call(i int, hook string, tracer vm.EVMLogger, fn func(vm.EVMLogger)) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.failed[i] = true
+			if t.err == nil {
+				t.err = fmt.Errorf("tracer %d panicked in %s: %v", i, hook, r)
+			}
+			log.Warn("Disabled panicking tracer", "index", i, "hook", hook, "panic", r)
+		}
+	}()
+	fn(tracer)
+}
+
+func (t *MultiTracer) CaptureTxStart(gasLimit uint64) {
+	t.each("CaptureTxStart", func(tracer vm.EVMLogger) {
+		tracer.CaptureTxStart(gasLimit)
+	})
+}
+
+func (t *MultiTracer) CaptureTxEnd(restGas uint64) {
+	t.each("CaptureTxEnd", func(tracer vm.EVMLogger) {
+		tracer.CaptureTxEnd(restGas)
+	})
+}
+
+func (t *MultiTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.each("CaptureStart", func(tracer vm.EVMLogger) {
+		tracer.CaptureStart(env, from, to, create, input, gas, value)
+	})
+}
+
+func (t *MultiTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.each("CaptureEnd", func(tracer vm.EVMLogger) {
+		tracer.CaptureEnd(output, gasUsed, err)
+	})
+}
+
+func (t *MultiTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.each("CaptureEnter", func(tracer vm.EVMLogger) {
+		tracer.CaptureEnter(typ, from, to, input, gas, value)
+	})
+}
+
+func (t *MultiTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.each("CaptureExit", func(tracer vm.EVMLogger) {
+		tracer.CaptureExit(output, gasUsed, err)
+	})
+}
+
+func (t *MultiTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.each("CaptureState", func(tracer vm.EVMLogger) {
+		tracer.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	})
+}
+
+func (t *MultiTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.each("CaptureFault", func(tracer vm.EVMLogger) {
+		tracer.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	})
+}
+
+func (t *MultiTracer) CapturePreimage(hash common.Hash, preimage []byte) {
+	t.each("CapturePreimage", func(tracer vm.EVMLogger) {
+		tracer.CapturePreimage(hash, preimage)
+	})
+}
+
+func (t *MultiTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {
+	t.each("CaptureStorageRead", func(tracer vm.EVMLogger) {
+		tracer.CaptureStorageRead(addr, key, value)
+	})
+}
+
+func (t *MultiTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {
+	t.each("CaptureStorageWrite", func(tracer vm.EVMLogger) {
+		tracer.CaptureStorageWrite(addr, key, oldValue, newValue)
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+)
+
+// panickingLogger is a struct logger that panics on the n'th opcode.
+type panickingLogger struct {
+	*logger.StructLogger
+	steps, n int
+}
+
+func (l *panickingLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if l.steps++; l.steps == l.n {
+		panic("boom")
+	}
+	l.StructLogger.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+}
+
+func TestMultiTracer(t *testing.T) {
+	var (
+		first  = logger.NewStructLogger(nil)
+		broken = &panickingLogger{StructLogger: logger.NewStructLogger(nil), n: 3}
+		last   = logger.NewStructLogger(nil)
+		multi  = NewMultiTracer(first, broken)
+	)
+	multi.Add(last)
+
+	// push(1) push(2) add push(0) mstore stop
+	code := []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 2, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.STOP)}
+	if _, _, err := runtime.Execute(code, nil, &runtime.Config{EVMConfig: vm.Config{Tracer: multi}}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	// The loggers around the panicking one should see every step
+	if have := len(first.StructLogs()); have != 6 {
+		t.Errorf("first logger step count mismatch: have %d, want 6", have)
+	}
+	if have := len(last.StructLogs()); have != 6 {
+		t.Errorf("last logger step count mismatch: have %d, want 6", have)
+	}
+	// The panicking one should be disabled after the failure
+	if have := broken.steps; have != 3 {
+		t.Errorf("panicking logger called after failure: %d steps", have)
+	}
+	if have := len(broken.StructLogs()); have != 2 {
+		t.Errorf("panicking logger step count mismatch: have %d, want 2", have)
+	}
+	if multi.Err() == nil {
+		t.Error("panic not reported")
+	}
+}
@@ -207,6 +207,13 @@ func (t *flatCallTracer) CaptureTxEnd(restGas uint64) {
 	t.tracer.CaptureTxEnd(restGas)
 }
 
+func (t *flatCallTracer) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+func (*flatCallTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*flatCallTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {
+}
+
 // GetResult returns an empty json object.
 func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
 	if len(t.tracer.callstack) < 1 {
@@ -113,6 +113,24 @@ func (t *muxTracer) CaptureTxEnd(restGas uint64) {
 	}
 }
 
+func (t *muxTracer) CapturePreimage(hash common.Hash, preimage []byte) {
+	for _, t := range t.tracers {
+		t.CapturePreimage(hash, preimage)
+	}
+}
+
+func (t *muxTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {
+	for _, t := range t.tracers {
+		t.CaptureStorageRead(addr, key, value)
+	}
+}
+
+func (t *muxTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {
+	for _, t := range t.tracers {
+		t.CaptureStorageWrite(addr, key, oldValue, newValue)
+	}
+}
+
 // GetResult returns an empty json object.
 func (t *muxTracer) GetResult() (json.RawMessage, error) {
 	resObject := make(map[string]json.RawMessage)
@@ -67,6 +67,12 @@ func (*noopTracer) CaptureTxStart(gasLimit uint64) {}
 
 func (*noopTracer) CaptureTxEnd(restGas uint64) {}
 
+func (*noopTracer) CapturePreimage(hash common.Hash, preimage []byte) {}
+
+func (*noopTracer) CaptureStorageRead(addr common.Address, key, value common.Hash) {}
+
+func (*noopTracer) CaptureStorageWrite(addr common.Address, key, oldValue, newValue common.Hash) {}
+
 // GetResult returns an empty json object.
 func (t *noopTracer) GetResult() (json.RawMessage, error) {
 	return json.RawMessage(`{}`), nil
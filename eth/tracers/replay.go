@@ -0,0 +1,253 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errTraceDiverged is returned by ReplayTrace if consecutive steps of the trace
+// could not have been produced by a single execution.
+var errTraceDiverged = errors.New("trace diverged")
+
+// replayFrame is a call frame reconstructed while replaying a trace.
+type replayFrame struct {
+	last     *logger.StructLog // Last step executed in the frame
+	gas      uint64            // Gas available at the first step of the frame
+	contract *vm.Contract
+	memory   *vm.Memory
+}
+
+// ReplayTrace feeds a recorded struct log trace, e.g. one produced by the
+// StructLogger, to the tracer as if the execution was running live. Each step
+// is checked against the preceding one for the program counter, opcode and
+// gas it implies, returning an error at the first step that diverges.
+//
+// The trace carries no information about the accounts involved, the call
+// inputs and outputs or the state, so the tracer is driven by an EVM running
+// on an empty state, with zero addresses and no call data. The recorded stack,
+// memory and refund counter are reproduced at every step.
+func ReplayTrace(trace []logger.StructLog, tracer vm.EVMLogger) error {
+	if len(trace) == 0 {
+		return nil
+	}
+	if trace[0].Depth != 1 {
+		return fmt.Errorf("%w: trace starts at depth %d", errTraceDiverged, trace[0].Depth)
+	}
+	var (
+		statedb = state.NewEmptyStateDB(state.NewDatabase(rawdb.NewMemoryDatabase()))
+		env     = vm.NewEVM(vm.BlockContext{BlockNumber: new(big.Int)}, vm.TxContext{}, statedb, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer})
+		frames  = []*replayFrame{newReplayFrame(&trace[0])}
+	)
+	tracer.CaptureTxStart(trace[0].Gas)
+	tracer.CaptureStart(env, common.Address{}, common.Address{}, false, nil, trace[0].Gas, new(big.Int))
+
+	for i := range trace {
+		step := &trace[i]
+		if i > 0 {
+			if err := checkStep(frames, step); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+		}
+		switch depth := len(frames); {
+		case step.Depth > depth:
+			caller := frames[depth-1].last
+			tracer.CaptureEnter(caller.Op, common.Address{}, common.Address{}, nil, step.Gas, callValue(caller))
+			frames = append(frames, newReplayFrame(step))
+
+		case step.Depth < depth:
+			for len(frames) > step.Depth {
+				frame := frames[len(frames)-1]
+				tracer.CaptureExit(nil, frame.gasUsed(), frame.err())
+				frames = frames[:len(frames)-1]
+			}
+		}
+		frame := frames[len(frames)-1]
+		frame.last = step
+
+		// Reproduce the execution context of the step
+		frame.contract.Gas = step.Gas
+		if len(step.Memory) > 0 {
+			frame.memory.Resize(uint64(len(step.Memory)))
+			frame.memory.Set(0, uint64(len(step.Memory)), step.Memory)
+		} else {
+			frame.memory.Resize(uint64(step.MemorySize))
+		}
+		if refund := statedb.GetRefund(); refund < step.RefundCounter {
+			statedb.AddRefund(step.RefundCounter - refund)
+		} else if refund > step.RefundCounter {
+			statedb.SubRefund(refund - step.RefundCounter)
+		}
+		scope := &vm.ScopeContext{
+			Memory:      frame.memory,
+			Stack:       vm.NewStack(step.Stack...),
+			Contract:    frame.contract,
+			Depth:       step.Depth,
+			FaultReason: step.FaultReason,
+		}
+		tracer.CaptureState(step.Pc, step.Op, step.Gas, step.GasCost, scope, step.ReturnData, step.Depth, step.Err)
+		if step.Err != nil {
+			tracer.CaptureFault(step.Pc, step.Op, step.Gas, step.GasCost, scope, step.Depth, step.Err)
+		}
+	}
+	for len(frames) > 1 {
+		frame := frames[len(frames)-1]
+		tracer.CaptureExit(nil, frame.gasUsed(), frame.err())
+		frames = frames[:len(frames)-1]
+	}
+	tracer.CaptureEnd(nil, frames[0].gasUsed(), frames[0].err())
+	tracer.CaptureTxEnd(frames[0].gasLeft())
+	return nil
+}
+
+// newReplayFrame creates a call frame starting with the given step.
+func newReplayFrame(first *logger.StructLog) *replayFrame {
+	return &replayFrame{
+		last:     first,
+		gas:      first.Gas,
+		contract: vm.NewContract(vm.AccountRef{}, vm.AccountRef{}, new(big.Int), first.Gas),
+		memory:   vm.NewMemory(),
+	}
+}
+
+// gasLeft returns the gas remaining after the last step of the frame.
+func (f *replayFrame) gasLeft() uint64 {
+	if f.last.Err != nil || f.last.GasCost > f.last.Gas {
+		return 0
+	}
+	return f.last.Gas - f.last.GasCost
+}
+
+// gasUsed returns the gas consumed by the frame.
+func (f *replayFrame) gasUsed() uint64 {
+	return f.gas - f.gasLeft()
+}
+
+// err returns the error the frame ended with.
+func (f *replayFrame) err() error {
+	if f.last.Err != nil {
+		return f.last.Err
+	}
+	if f.last.Op == vm.REVERT {
+		return vm.ErrExecutionReverted
+	}
+	return nil
+}
+
+// isCallOp reports whether the opcode enters a new call frame.
+func isCallOp(op vm.OpCode) bool {
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		return true
+	}
+	return false
+}
+
+// callValue returns the value transferred by the call step, read from its
+// recorded stack. Calls not transferring value report nil, the same as live.
+func callValue(step *logger.StructLog) *big.Int {
+	var pos int
+	switch step.Op {
+	case vm.CALL, vm.CALLCODE:
+		pos = 2
+	case vm.CREATE, vm.CREATE2:
+		pos = 0
+	default:
+		return nil
+	}
+	if pos >= len(step.Stack) {
+		return new(big.Int)
+	}
+	return step.Stack[len(step.Stack)-1-pos].ToBig()
+}
+
+// checkStep verifies that the step can follow the steps executed so far in the
+// given call frames in a single execution.
+func checkStep(frames []*replayFrame, step *logger.StructLog) error {
+	prev := frames[len(frames)-1].last
+	switch {
+	case step.Depth > prev.Depth+1:
+		return fmt.Errorf("%w: depth jumped from %d to %d", errTraceDiverged, prev.Depth, step.Depth)
+
+	case step.Depth == prev.Depth+1:
+		if !isCallOp(prev.Op) {
+			return fmt.Errorf("%w: depth increased after %v", errTraceDiverged, prev.Op)
+		}
+		if step.Pc != 0 {
+			return fmt.Errorf("%w: call entered at pc %d", errTraceDiverged, step.Pc)
+		}
+		return nil
+
+	case step.Depth < prev.Depth:
+		if step.Depth < 1 {
+			return fmt.Errorf("%w: depth dropped to %d", errTraceDiverged, step.Depth)
+		}
+		// Execution resumes right after the call in the frame returned to
+		call := frames[step.Depth-1].last
+		if step.Pc != call.Pc+1 {
+			return fmt.Errorf("%w: returned to pc %d after %v at pc %d", errTraceDiverged, step.Pc, call.Op, call.Pc)
+		}
+		if step.Gas > call.Gas {
+			return fmt.Errorf("%w: gas increased from %d to %d over %v", errTraceDiverged, call.Gas, step.Gas, call.Op)
+		}
+		return nil
+	}
+	if prev.Err != nil {
+		return fmt.Errorf("%w: execution continued after failed %v", errTraceDiverged, prev.Op)
+	}
+	switch prev.Op {
+	case vm.STOP, vm.RETURN, vm.REVERT, vm.SELFDESTRUCT, vm.INVALID:
+		return fmt.Errorf("%w: execution continued after %v", errTraceDiverged, prev.Op)
+
+	case vm.JUMP:
+		if step.Op != vm.JUMPDEST {
+			return fmt.Errorf("%w: jump landed on %v at pc %d", errTraceDiverged, step.Op, step.Pc)
+		}
+	case vm.JUMPI:
+		if step.Pc != prev.Pc+1 && step.Op != vm.JUMPDEST {
+			return fmt.Errorf("%w: jump landed on %v at pc %d", errTraceDiverged, step.Op, step.Pc)
+		}
+	default:
+		next := prev.Pc + 1
+		if prev.Op.IsPush() {
+			next += uint64(prev.Op - vm.PUSH1 + 1)
+		}
+		if step.Pc != next {
+			return fmt.Errorf("%w: pc %d after %v at pc %d, want %d", errTraceDiverged, step.Pc, prev.Op, prev.Pc, next)
+		}
+	}
+	// The gas returned by a call is not recorded, the rest is charged upfront
+	if isCallOp(prev.Op) {
+		if step.Gas > prev.Gas {
+			return fmt.Errorf("%w: gas increased from %d to %d over %v", errTraceDiverged, prev.Gas, step.Gas, prev.Op)
+		}
+		return nil
+	}
+	if want := prev.Gas - prev.GasCost; prev.GasCost > prev.Gas || step.Gas != want {
+		return fmt.Errorf("%w: gas %d after %v, want %d", errTraceDiverged, step.Gas, prev.Op, want)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+)
+
+func TestReplayTrace(t *testing.T) {
+	// push(1) push(2) add push(9) jump invalid invalid invalid jumpdest push(0) mstore stop
+	code := []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 2, byte(vm.ADD), byte(vm.PUSH1), 10, byte(vm.JUMP),
+		byte(vm.INVALID), byte(vm.INVALID), byte(vm.JUMPDEST), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.STOP),
+	}
+	live := logger.NewStructLogger(&logger.Config{EnableMemory: true})
+	if _, _, err := runtime.Execute(code, nil, &runtime.Config{EVMConfig: vm.Config{Tracer: live}}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	trace := live.StructLogs()
+
+	// Replaying the trace should reproduce it step by step
+	replayed := logger.NewStructLogger(&logger.Config{EnableMemory: true})
+	if err := ReplayTrace(trace, replayed); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if !reflect.DeepEqual(replayed.StructLogs(), trace) {
+		t.Fatalf("replayed trace mismatch:\nhave %+v\nwant %+v", replayed.StructLogs(), trace)
+	}
+	// Tampering with any of the program counter, opcode or gas must be detected
+	tamper := []func(step *logger.StructLog){
+		func(step *logger.StructLog) { step.Pc++ },
+		func(step *logger.StructLog) { step.Op = vm.JUMPDEST },
+		func(step *logger.StructLog) { step.Gas-- },
+	}
+	for i, fn := range tamper {
+		broken := append([]logger.StructLog(nil), trace...)
+		fn(&broken[4])
+		if err := ReplayTrace(broken, logger.NewStructLogger(nil)); !errors.Is(err, errTraceDiverged) {
+			t.Errorf("tamper %d: error mismatch: have %v, want %v", i, err, errTraceDiverged)
+		}
+	}
+}
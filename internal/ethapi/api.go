@@ -786,6 +786,67 @@ func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fu
 	return nil, err
 }
 
+// maxBlockRange is the maximum number of blocks returned by GetBlocksByRange.
+const maxBlockRange = 100
+
+// GetBlocksByRange returns the blocks from the first up to and including the
+// last requested one, in ascending order. At most maxBlockRange blocks can be
+// requested at once. Blocks missing from the range are returned as null. When
+// fullTx is true all transactions in the blocks are returned in full detail,
+// otherwise only the transaction hashes are returned.
+func (s *BlockChainAPI) GetBlocksByRange(ctx context.Context, from, to rpc.BlockNumber, fullTx bool) ([]map[string]interface{}, error) {
+	if from == rpc.PendingBlockNumber || to == rpc.PendingBlockNumber {
+		return nil, errors.New("pending block not supported in range")
+	}
+	begin, err := s.resolveBlockNumber(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := s.resolveBlockNumber(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if begin > end {
+		return nil, fmt.Errorf("invalid block range: %d > %d", begin, end)
+	}
+	if end-begin >= maxBlockRange {
+		return nil, fmt.Errorf("block range too large: %d > %d", end-begin+1, maxBlockRange)
+	}
+	blocks := make([]map[string]interface{}, 0, end-begin+1)
+	for number := begin; number <= end; number++ {
+		block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			blocks = append(blocks, nil)
+			continue
+		}
+		response, err := s.rpcMarshalBlock(ctx, block, true, fullTx)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, response)
+	}
+	return blocks, nil
+}
+
+// resolveBlockNumber converts a block number, possibly a tag like latest, into
+// an absolute block number.
+func (s *BlockChainAPI) resolveBlockNumber(ctx context.Context, number rpc.BlockNumber) (uint64, error) {
+	if number >= 0 {
+		return uint64(number), nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %v not found", number)
+	}
+	return header.Number.Uint64(), nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index.
 func (s *BlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
 	block, err := s.b.BlockByNumber(ctx, blockNr)
@@ -1463,6 +1524,34 @@ func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionAr
 	return result, nil
 }
 
+// CreateAccessListWithGas creates an EIP-2930 type AccessList for the given
+// transaction, along with the gas limit the transaction needs when sent with
+// that access list attached. The estimate accounts for both the intrinsic cost
+// of the list and the cheaper warm accesses to the slots it pre-loads.
+//
+// If the transaction fails, the gas used by the failing execution is returned
+// together with the error instead of an estimate.
+func (s *BlockChainAPI) CreateAccessListWithGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
+	if err != nil {
+		return nil, err
+	}
+	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed)}
+	if vmerr != nil {
+		result.Error = vmerr.Error()
+		return result, nil
+	}
+	args.AccessList = &acl
+	if result.GasUsed, err = DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // AccessList creates an access list for the given transaction.
 // If the accesslist creation fails an error is returned.
 // If the transaction itself fails, an vmErr is returned.
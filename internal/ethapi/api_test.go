@@ -282,7 +282,12 @@ func (b testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 func (b testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	panic("implement me")
 }
-func (b testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int { panic("implement me") }
+func (b testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
+	if number := rawdb.ReadHeaderNumber(b.db, hash); number != nil {
+		return b.chain.GetTd(hash, *number)
+	}
+	return nil
+}
 func (b testBackend) GetEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockContext *vm.BlockContext) (*vm.EVM, func() error, error) {
 	vmError := func() error { return nil }
 	if vmConfig == nil {
@@ -425,6 +430,55 @@ func TestEstimateGas(t *testing.T) {
 	}
 }
 
+func TestCreateAccessListWithGas(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		contract = common.HexToAddress("0xc0de")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				// sload(0) + sload(1)
+				contract: {Balance: common.Big0, Code: common.FromHex("600054600154015000")},
+			},
+		}
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {}))
+
+	call := TransactionArgs{
+		From:     &accounts[0].addr,
+		To:       &contract,
+		GasPrice: (*hexutil.Big)(big.NewInt(0)),
+		Nonce:    new(hexutil.Uint64),
+	}
+	result, err := api.CreateAccessListWithGas(context.Background(), call, &latest)
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected execution error: %v", result.Error)
+	}
+	want := types.AccessList{{Address: contract, StorageKeys: []common.Hash{{}, common.BytesToHash([]byte{1})}}}
+	if !reflect.DeepEqual(*result.Accesslist, want) {
+		t.Fatalf("access list mismatch: have %v, want %v", *result.Accesslist, want)
+	}
+	// 21000 intrinsic + 2400 address + 2*1900 slots + 211 execution with warm slots
+	if result.GasUsed != 27411 {
+		t.Fatalf("gas mismatch: have %d, want %d", result.GasUsed, 27411)
+	}
+	// The estimate must match the one of the transaction with the list attached
+	call.AccessList = result.Accesslist
+	estimate, err := api.EstimateGas(context.Background(), call, &latest)
+	if err != nil {
+		t.Fatalf("failed to estimate gas: %v", err)
+	}
+	if estimate != result.GasUsed {
+		t.Fatalf("estimate mismatch: have %d, want %d", result.GasUsed, estimate)
+	}
+}
+
 func TestCall(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -624,3 +678,59 @@ func hex2Bytes(str string) *hexutil.Bytes {
 	rpcBytes := hexutil.Bytes(common.Hex2Bytes(str))
 	return &rpcBytes
 }
+
+func TestGetBlocksByRange(t *testing.T) {
+	t.Parallel()
+
+	var (
+		genesis   = &core.Genesis{Config: params.TestChainConfig}
+		genBlocks = 10
+		backend   = newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {})
+		api       = NewBlockChainAPI(backend)
+	)
+	var testSuite = []struct {
+		from, to  rpc.BlockNumber
+		want      []int64 // block numbers, -1 for missing blocks
+		expectErr bool
+	}{
+		{from: 0, to: 0, want: []int64{0}},
+		{from: 3, to: 6, want: []int64{3, 4, 5, 6}},
+		{from: 8, to: rpc.LatestBlockNumber, want: []int64{8, 9, 10}},
+		{from: 9, to: 12, want: []int64{9, 10, -1, -1}},
+		{from: 6, to: 3, expectErr: true},
+		{from: 0, to: 100, expectErr: true},
+		{from: 0, to: rpc.PendingBlockNumber, expectErr: true},
+	}
+	for i, tt := range testSuite {
+		blocks, err := api.GetBlocksByRange(context.Background(), tt.from, tt.to, false)
+		if tt.expectErr {
+			if err == nil {
+				t.Errorf("test %d: want error, have nothing", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: want no error, have %v", i, err)
+			continue
+		}
+		if len(blocks) != len(tt.want) {
+			t.Errorf("test %d: block count mismatch: have %d, want %d", i, len(blocks), len(tt.want))
+			continue
+		}
+		for j, number := range tt.want {
+			if number < 0 {
+				if blocks[j] != nil {
+					t.Errorf("test %d: block %d should be missing", i, j)
+				}
+				continue
+			}
+			if blocks[j] == nil {
+				t.Errorf("test %d: block %d missing", i, j)
+				continue
+			}
+			if have := blocks[j]["number"].(*hexutil.Big).ToInt().Int64(); have != number {
+				t.Errorf("test %d: block %d number mismatch: have %d, want %d", i, j, have, number)
+			}
+		}
+	}
+}
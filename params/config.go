@@ -531,6 +531,40 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64, time u
 	return lasterr
 }
 
+// ForEachFork calls fn for each fork scheduled in the chain config, oldest first,
+// with its human-readable name and either its activation block or timestamp,
+// the other being nil. Forks that aren't scheduled are skipped.
+func (c *ChainConfig) ForEachFork(fn func(name string, block *big.Int, timestamp *uint64)) {
+	for _, fork := range []struct {
+		name      string
+		block     *big.Int
+		timestamp *uint64
+	}{
+		{name: "Homestead", block: c.HomesteadBlock},
+		{name: "DAO Fork", block: c.DAOForkBlock},
+		{name: "Tangerine Whistle", block: c.EIP150Block},
+		{name: "Spurious Dragon/1", block: c.EIP155Block},
+		{name: "Spurious Dragon/2", block: c.EIP158Block},
+		{name: "Byzantium", block: c.ByzantiumBlock},
+		{name: "Constantinople", block: c.ConstantinopleBlock},
+		{name: "Petersburg", block: c.PetersburgBlock},
+		{name: "Istanbul", block: c.IstanbulBlock},
+		{name: "Muir Glacier", block: c.MuirGlacierBlock},
+		{name: "Berlin", block: c.BerlinBlock},
+		{name: "London", block: c.LondonBlock},
+		{name: "Arrow Glacier", block: c.ArrowGlacierBlock},
+		{name: "Gray Glacier", block: c.GrayGlacierBlock},
+		{name: "Merge Netsplit", block: c.MergeNetsplitBlock},
+		{name: "Shanghai", timestamp: c.ShanghaiTime},
+		{name: "Cancun", timestamp: c.CancunTime},
+		{name: "Prague", timestamp: c.PragueTime},
+	} {
+		if fork.block != nil || fork.timestamp != nil {
+			fn(fork.name, fork.block, fork.timestamp)
+		}
+	}
+}
+
 // CheckConfigForkOrder checks that we don't "skip" any forks, geth isn't pluggable enough
 // to guarantee that forks can be implemented in a different order than on official networks
 func (c *ChainConfig) CheckConfigForkOrder() error {
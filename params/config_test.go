@@ -136,3 +136,53 @@ func TestConfigRules(t *testing.T) {
 		t.Errorf("expected %v to be shanghai", stamp)
 	}
 }
+
+func TestForEachFork(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *ChainConfig
+		forks  []string
+	}{
+		{"mainnet", MainnetChainConfig, []string{
+			"Homestead", "DAO Fork", "Tangerine Whistle", "Spurious Dragon/1", "Spurious Dragon/2",
+			"Byzantium", "Constantinople", "Petersburg", "Istanbul", "Muir Glacier", "Berlin",
+			"London", "Arrow Glacier", "Gray Glacier", "Shanghai",
+		}},
+		{"goerli", GoerliChainConfig, []string{
+			"Homestead", "Tangerine Whistle", "Spurious Dragon/1", "Spurious Dragon/2", "Byzantium",
+			"Constantinople", "Petersburg", "Istanbul", "Berlin", "London", "Shanghai",
+		}},
+		{"sepolia", SepoliaChainConfig, []string{
+			"Homestead", "Tangerine Whistle", "Spurious Dragon/1", "Spurious Dragon/2", "Byzantium",
+			"Constantinople", "Petersburg", "Istanbul", "Muir Glacier", "Berlin", "London",
+			"Merge Netsplit", "Shanghai",
+		}},
+	}
+	for _, tt := range tests {
+		var (
+			forks     []string
+			lastBlock = new(big.Int)
+			lastTime  *uint64
+		)
+		tt.config.ForEachFork(func(name string, block *big.Int, timestamp *uint64) {
+			forks = append(forks, name)
+			switch {
+			case block != nil && timestamp == nil:
+				if lastTime != nil || block.Cmp(lastBlock) < 0 {
+					t.Errorf("%s: fork %s at block %v out of order", tt.name, name, block)
+				}
+				lastBlock = block
+			case block == nil && timestamp != nil:
+				if lastTime != nil && *timestamp < *lastTime {
+					t.Errorf("%s: fork %s at time %d out of order", tt.name, name, *timestamp)
+				}
+				lastTime = timestamp
+			default:
+				t.Errorf("%s: fork %s has block %v and timestamp %v", tt.name, name, block, timestamp)
+			}
+		})
+		if !reflect.DeepEqual(forks, tt.forks) {
+			t.Errorf("%s: forks mismatch:\nhave %v\nwant %v", tt.name, forks, tt.forks)
+		}
+	}
+}
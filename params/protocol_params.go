@@ -163,6 +163,16 @@ const (
 	BlobTxDataGasPerBlob             = 1 << 17 // Gas consumption of a single data blob (== blob byte size)
 	BlobTxMinDataGasprice            = 1       // Minimum gas price for data blobs
 	BlobTxDataGaspriceUpdateFraction = 2225652 // Controls the maximum rate of change for data gas price
+	BlobTxMaxDataGasPerBlock         = 1 << 19 // Maximum consumable data gas for data blobs per block
+	BlobTxHashVersion                = 0x01    // Version byte of the commitment hash
+	BlobTxBytesPerFieldElement       = 32      // Size in bytes of a field element
+	BlobTxFieldElementsPerBlob       = 4096    // Number of field elements stored in a single data blob
+
+	BlobSize = BlobTxBytesPerFieldElement * BlobTxFieldElementsPerBlob // Size in bytes of a single data blob
+
+	MaxBlobsPerBlock = BlobTxMaxDataGasPerBlock / BlobTxDataGasPerBlob // Maximum number of data blobs per block
+
+	BlobRetentionBlocks uint64 = 216000 // Number of recent blocks whose blob sidecars are retained (~30 days)
 )
 
 // Gas discount table for BLS12-381 G1 and G2 multi exponentiation operations
@@ -147,7 +147,7 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 		// Add space in the extradata to put the signature
 		newExtra := make([]byte, len(header.Extra)+65)
 		copy(newExtra, header.Extra)
-		header.Extra = newExtra
+		header = header.WithExtra(newExtra)
 
 		// Get back the rlp data, encoded by us
 		sighash, cliqueRlp, err := cliqueHeaderHashAndRlp(header)
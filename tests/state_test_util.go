@@ -37,7 +37,6 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
-	"github.com/ethereum/go-ethereum/trie"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -146,6 +145,9 @@ func GetChainConfig(forkString string) (baseConfig *params.ChainConfig, eips []i
 			eips = append(eips, eipNum)
 		}
 	}
+	if err := vm.ValidateExtraEips(eips, baseConfig.Rules(new(big.Int), false, 0)); err != nil {
+		return nil, nil, err
+	}
 	return baseConfig, eips, nil
 }
 
@@ -285,7 +287,7 @@ func (t *StateTest) gasLimit(subtest StateSubtest) uint64 {
 }
 
 func MakePreState(db ethdb.Database, accounts core.GenesisAlloc, snapshotter bool) (*snapshot.Tree, *state.StateDB) {
-	sdb := state.NewDatabaseWithConfig(db, &trie.Config{Preimages: true})
+	sdb := state.NewDatabaseWithConfig(db, &state.TrieConfig{Preimages: true})
 	statedb, _ := state.New(common.Hash{}, sdb, nil)
 	for addr, a := range accounts {
 		statedb.SetCode(addr, a.Code)